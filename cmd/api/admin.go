@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+
+	"thesis.lefler.eu/internal/storage"
+	"thesis.lefler.eu/internal/util"
+	"thesis.lefler.eu/migrations"
+)
+
+// storageTimeout bounds the storage report, row counts and redundancy checks scan whole tables.
+// The write timeout of the admin server leaves room to respond after it.
+const storageTimeout = 30 * time.Second
+
+// adminRoutes serves the operator endpoints on their own listener, so they are never reachable
+// through the public API. Every request must carry the admin token as a bearer token.
+func (app *application) adminRoutes() http.Handler {
+	router := httprouter.New()
+
+	router.NotFound = http.HandlerFunc(app.errors.NotFoundResponse)
+	router.MethodNotAllowed = http.HandlerFunc(app.errors.MethodNotAllowedResponse)
+
+	router.HandlerFunc(http.MethodGet, "/admin/usage", app.usageHandler)
+	router.HandlerFunc(http.MethodGet, "/admin/storage", app.storageHandler)
+	router.HandlerFunc(http.MethodGet, "/admin/shadow", app.shadowHandler)
+
+	return app.recoverPanic(app.requireAdminToken(router))
+}
+
+func (app *application) requireAdminToken(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+
+		if !ok || app.config.admin.token == "" || subtle.ConstantTimeCompare([]byte(token), []byte(app.config.admin.token)) != 1 {
+			app.errors.InvalidAuthenticationTokenResponse(w, r)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// usageHandler reports how often each strategy, version and resource has been requested,
+// optionally narrowed down with ?strategy=
+func (app *application) usageHandler(w http.ResponseWriter, r *http.Request) {
+	strategy := util.ReadString(r.URL.Query(), "strategy", "")
+
+	usage := app.usage.Snapshot()
+
+	if strategy != "" {
+		filtered := usage[:0]
+		for _, u := range usage {
+			if u.Strategy == strategy {
+				filtered = append(filtered, u)
+			}
+		}
+		usage = filtered
+	}
+
+	err := util.WriteJSON(w, http.StatusOK, util.Envelope{"usage": usage}, nil)
+	if err != nil {
+		app.errors.ServerErrorResponse(w, r, err)
+	}
+}
+
+// storageHandler compares the disk usage and redundant data of the strategy databases,
+// optionally narrowed down with ?strategy=
+func (app *application) storageHandler(w http.ResponseWriter, r *http.Request) {
+	strategy := util.ReadString(r.URL.Query(), "strategy", "")
+
+	strategies := migrations.Strategies
+	if strategy != "" {
+		if _, ok := app.dbs[strategy]; !ok {
+			app.errors.FailedValidationResponse(w, r, map[string]string{"strategy": "must be one of views, expand_deprecate or branches"})
+			return
+		}
+		strategies = []string{strategy}
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), storageTimeout)
+	defer cancel()
+
+	report, err := storage.Collect(ctx, app.dbs, strategies)
+	if err != nil {
+		app.errors.ServerErrorResponse(w, r, err)
+		return
+	}
+
+	err = util.WriteJSON(w, http.StatusOK, util.Envelope{"storage": report}, nil)
+	if err != nil {
+		app.errors.ServerErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"database/sql"
+	"log/slog"
+	"os"
+	"testing"
+	"time"
+
+	"thesis.lefler.eu/internal/conformance"
+	"thesis.lefler.eu/internal/data"
+	e "thesis.lefler.eu/internal/error"
+	"thesis.lefler.eu/internal/handler"
+	"thesis.lefler.eu/internal/migration"
+	"thesis.lefler.eu/internal/telemetry"
+	"thesis.lefler.eu/migrations"
+)
+
+// dsnEnv names the environment variable of the DSN of each strategy, the tests run against the
+// databases when they are set and against the in-memory reference implementation otherwise
+var dsnEnv = map[string]string{
+	"views":            "VIEWS_DB_DSN",
+	"expand_deprecate": "EXPAND_DEPRECATE_DB_DSN",
+	"branches":         "BRANCHES_DB_DSN",
+}
+
+// newTestApp returns the application on the databases of the DSNs in the environment, or on the
+// memory models at the latest migration of every strategy if none is set. Its clock stands before
+// the first deprecation, so every version serves requests whatever the date.
+func newTestApp(t *testing.T) *application {
+	t.Helper()
+
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	var cfg config
+	cfg.defaultVersion = "v5"
+	cfg.db.maxOpenConns = 25
+	cfg.db.maxIdleConns = 25
+	cfg.db.maxIdleTime = 15 * time.Minute
+
+	dsns := make(map[string]string, len(dsnEnv))
+	for strategy, env := range dsnEnv {
+		if dsn := os.Getenv(env); dsn != "" {
+			dsns[strategy] = dsn
+		}
+	}
+
+	var (
+		models         data.Models
+		dbs            map[string]*sql.DB
+		schemaVersions map[string]int64
+	)
+
+	switch len(dsns) {
+	case 0:
+		models = data.NewMemoryModels()
+		schemaVersions = make(map[string]int64, len(migrations.Strategies))
+
+		for _, strategy := range migrations.Strategies {
+			loaded, err := migration.Load(migrations.FS, migrations.Dirs[strategy])
+			if err != nil {
+				t.Fatal(err)
+			}
+			schemaVersions[strategy] = loaded[len(loaded)-1].Version
+		}
+
+	case len(dsnEnv):
+		dbs = make(map[string]*sql.DB, len(dsns))
+		for strategy, dsn := range dsns {
+			db, err := openDB(cfg, dsn, strategy)
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Cleanup(func() { db.Close() })
+
+			dbs[strategy] = db
+		}
+
+		var err error
+		schemaVersions, err = readSchemaVersions(dbs)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		models = data.NewModels(data.DbConns{Views: dbs["views"], ExpandDeprecate: dbs["expand_deprecate"], Branches: dbs["branches"]})
+
+	default:
+		t.Fatalf("set the DSN of every strategy or none, got %d of %d", len(dsns), len(dsnEnv))
+	}
+
+	errors := e.NewErrors(logger)
+
+	return &application{
+		config:         cfg,
+		logger:         logger,
+		models:         models,
+		dbs:            dbs,
+		errors:         errors,
+		handlers:       handler.NewHandlers(&errors, &models),
+		schemaVersions: schemaVersions,
+		schemas:        data.Schemas(),
+		usage:          telemetry.NewRecorder(),
+		now:            beforeLifecycles,
+	}
+}
+
+// beforeLifecycles is the day before the first version is deprecated
+func beforeLifecycles() time.Time {
+	var first time.Time
+	for _, l := range lifecycles {
+		if first.IsZero() || l.deprecated.Before(first) {
+			first = l.deprecated
+		}
+	}
+	return first.AddDate(0, 0, -1)
+}
+
+// TestConformance writes a movie through every API version and reads it through every other,
+// asserting the declared compatibility matrix. Records created by the checks are deleted again,
+// except people the databases create for directors written through v2 to v4.
+func TestConformance(t *testing.T) {
+	routes := newTestApp(t).routes()
+
+	for _, strategy := range migrations.Strategies {
+		t.Run(strategy, func(t *testing.T) {
+			for _, result := range conformance.Run(strategy, routes) {
+				switch {
+				case result.Passed:
+				case result.Error != "":
+					t.Errorf("%s %s to %s: %s", result.Check, result.Writer, result.Reader, result.Error)
+				default:
+					t.Errorf("%s %s to %s: %s is %v, want %v", result.Check, result.Writer, result.Reader, result.Field, result.Actual, result.Expected)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+
+	"thesis.lefler.eu/internal/schema"
+	"thesis.lefler.eu/internal/util"
+)
+
+// paramRX matches the params of a route, e.g. :person_id
+var paramRX = regexp.MustCompile(`:([a-z_]+)`)
+
+type resourceDescription struct {
+	Name   string            `json:"name"`
+	Fields []schema.Field    `json:"fields"`
+	Links  map[string]string `json:"links"`
+}
+
+type migrationDescription struct {
+	Requires  int64  `json:"requires"`             // migration the version depends on
+	RemovedBy *int64 `json:"removed_by,omitempty"` // cleanup migration that drops its schema
+}
+
+type versionDescription struct {
+	Version    string                `json:"version"`
+	State      lifecycleState        `json:"state"`
+	Available  bool                  `json:"available"` // false if the database is not migrated to support the version
+	Deprecated *string               `json:"deprecated,omitempty"`
+	Sunset     *string               `json:"sunset,omitempty"`
+	Successor  string                `json:"successor,omitempty"`
+	Migration  migrationDescription  `json:"migration"`
+	Resources  []resourceDescription `json:"resources"`
+}
+
+type strategyDescription struct {
+	Strategy      string               `json:"strategy"`
+	SchemaVersion int64                `json:"schema_version"` // currently applied migration
+	Versions      []versionDescription `json:"versions"`
+	Links         map[string]string    `json:"links"`
+}
+
+// routesDiscovery registers /{strategy}/versions for every strategy passed to registerRoutes
+func (app *application) routesDiscovery(router *httprouter.Router) {
+	for _, strategy := range app.strategies() {
+		router.HandlerFunc(http.MethodGet, fmt.Sprintf("/%s/versions", strategy), func(w http.ResponseWriter, r *http.Request) {
+			err := util.WriteJSON(w, http.StatusOK, util.Envelope{"strategy": app.describeStrategy(strategy, app.now())}, nil)
+			if err != nil {
+				app.errors.ServerErrorResponse(w, r, err)
+			}
+		})
+	}
+}
+
+func (app *application) versionsHandler(w http.ResponseWriter, r *http.Request) {
+	now := app.now()
+
+	strategies := []strategyDescription{}
+	for _, strategy := range app.strategies() {
+		strategies = append(strategies, app.describeStrategy(strategy, now))
+	}
+
+	env := util.Envelope{
+		"default_version": app.config.defaultVersion,
+		"strategies":      strategies,
+	}
+
+	err := util.WriteJSON(w, http.StatusOK, env, nil)
+	if err != nil {
+		app.errors.ServerErrorResponse(w, r, err)
+	}
+}
+
+// strategies returns every registered strategy in registration order
+func (app *application) strategies() []string {
+	var strategies []string
+	seen := make(map[string]bool)
+
+	for _, reg := range app.registrations {
+		if !seen[reg.strategy] {
+			seen[reg.strategy] = true
+			strategies = append(strategies, reg.strategy)
+		}
+	}
+
+	return strategies
+}
+
+func (app *application) describeStrategy(strategy string, now time.Time) strategyDescription {
+	description := strategyDescription{
+		Strategy:      strategy,
+		SchemaVersion: app.schemaVersions[strategy],
+		Versions:      []versionDescription{},
+		Links: map[string]string{
+			"self": fmt.Sprintf("/%s/versions", strategy),
+		},
+	}
+
+	index := make(map[string]int)
+
+	for _, reg := range app.registrations {
+		if reg.strategy != strategy {
+			continue
+		}
+
+		i, ok := index[reg.version]
+		if !ok {
+			description.Versions = append(description.Versions, app.describeVersion(strategy, reg.version, now))
+			i = len(description.Versions) - 1
+			index[reg.version] = i
+		}
+
+		description.Versions[i].Resources = append(description.Versions[i].Resources, resourceDescription{
+			Name:   reg.resource,
+			Fields: reg.model.Fields,
+			Links: map[string]string{
+				"collection": fmt.Sprintf("/%s/%s/%s", strategy, reg.version, template(reg.path)),
+				"item":       fmt.Sprintf("/%s/%s/%s/%s", strategy, reg.version, template(reg.path), template(reg.item)),
+			},
+		})
+	}
+
+	return description
+}
+
+func (app *application) describeVersion(strategy string, version string, now time.Time) versionDescription {
+	description := versionDescription{
+		Version:   version,
+		State:     stateActive,
+		Available: app.schemaAvailability(strategy, version) == nil,
+		Resources: []resourceDescription{},
+	}
+
+	if required, ok := schemaRanges[strategy][version]; ok {
+		description.Migration.Requires = required.since
+		if required.until != 0 {
+			description.Migration.RemovedBy = &required.until
+		}
+	}
+
+	if l, ok := lifecycles[version]; ok {
+		description.State = l.state(now)
+		description.Successor = l.successor
+
+		if !l.deprecated.IsZero() {
+			deprecated := l.deprecated.Format(time.DateOnly)
+			description.Deprecated = &deprecated
+		}
+		if !l.sunset.IsZero() {
+			sunset := l.sunset.Format(time.DateOnly)
+			description.Sunset = &sunset
+		}
+	}
+
+	return description
+}
+
+// template turns the params of a route into the placeholders of a link, e.g. :id into {id}
+func template(path string) string {
+	return paramRX.ReplaceAllString(path, "{$1}")
+}
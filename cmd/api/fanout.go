@@ -0,0 +1,605 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+
+	"thesis.lefler.eu/internal/handler"
+	"thesis.lefler.eu/internal/util"
+	"thesis.lefler.eu/migrations"
+)
+
+// references maps body fields holding the id of another record to the resource of that record
+var references = map[string]string{
+	"actor_id":  "actors",
+	"person_id": "people",
+}
+
+// fanoutResult is the reply of a single strategy to a fanned out request
+type fanoutResult struct {
+	Strategy string          `json:"strategy"`
+	Status   int             `json:"status"`
+	Duration float64         `json:"duration_ms"`
+	ID       int64           `json:"id,omitempty"` // of the record created
+	Body     json.RawMessage `json:"body"`
+
+	compared json.RawMessage // the body with the ids through /all, which the strategies are compared by
+}
+
+// divergence is a difference between the replies of the strategies
+type divergence struct {
+	Kind   string         `json:"kind"` // status, id, validation or field
+	Field  string         `json:"field,omitempty"`
+	Values map[string]any `json:"values"`
+}
+
+// fanoutMaxIDs bounds the records created through /all whose ids are kept, the oldest are
+// forgotten first
+const fanoutMaxIDs = 100_000
+
+// fanoutRecord is a record created through /all, by its id there
+type fanoutRecord struct {
+	resource string
+	id       int64
+}
+
+// strategyRecord is the same record in a single strategy, by its id there
+type strategyRecord struct {
+	resource string
+	strategy string
+	id       int64
+}
+
+// fanoutIDs maps the ids of records created through /all, which are the ids of the first
+// strategy, to the ids the other strategies generated for the same record, and back. It is
+// kept in memory only, so records created before the API started, or forgotten since, are
+// unknown and cannot be addressed through /all.
+type fanoutIDs struct {
+	mu      sync.Mutex
+	max     int
+	ids     map[fanoutRecord]map[string]int64 // strategy
+	reverse map[strategyRecord]int64
+	order   []fanoutRecord // in creation order, may still hold records deleted since
+}
+
+func newFanoutIDs(max int) *fanoutIDs {
+	return &fanoutIDs{
+		max:     max,
+		ids:     make(map[fanoutRecord]map[string]int64),
+		reverse: make(map[strategyRecord]int64),
+	}
+}
+
+func (f *fanoutIDs) set(resource string, id int64, strategy string, strategyID int64) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	record := fanoutRecord{resource, id}
+
+	if f.ids[record] == nil {
+		f.ids[record] = make(map[string]int64)
+		f.order = append(f.order, record)
+	}
+
+	f.ids[record][strategy] = strategyID
+	f.reverse[strategyRecord{resource, strategy, strategyID}] = id
+
+	for len(f.ids) > f.max {
+		f.forget(f.order[0])
+		f.order = f.order[1:]
+	}
+}
+
+// get returns the id of the record in strategy, or an error if strategy did not create it
+// through /all or it has been forgotten since
+func (f *fanoutIDs) get(resource string, id int64, strategy string) (int64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	strategyID, ok := f.ids[fanoutRecord{resource, id}][strategy]
+	if !ok {
+		return 0, fmt.Errorf("%s %d is unknown, only records created through /all since the API started can be addressed", resource, id)
+	}
+	return strategyID, nil
+}
+
+// original returns the /all id of the record strategy knows by strategyID
+func (f *fanoutIDs) original(resource string, strategy string, strategyID int64) (int64, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	id, ok := f.reverse[strategyRecord{resource, strategy, strategyID}]
+	return id, ok
+}
+
+// delete forgets a record deleted through /all
+func (f *fanoutIDs) delete(resource string, id int64) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.forget(fanoutRecord{resource, id})
+
+	// the order keeps deleted records until they are the oldest, drop them before they pile up
+	if len(f.order) > 2*len(f.ids)+64 {
+		f.order = slices.DeleteFunc(f.order, func(record fanoutRecord) bool {
+			_, ok := f.ids[record]
+			return !ok
+		})
+	}
+}
+
+func (f *fanoutIDs) forget(record fanoutRecord) {
+	for strategy, strategyID := range f.ids[record] {
+		delete(f.reverse, strategyRecord{record.resource, strategy, strategyID})
+	}
+	delete(f.ids, record)
+}
+
+// routesFanout registers /all/{version}/{resource} routes for every version and resource
+// passed to registerRoutes or registerNestedRoutes, applying each request to every strategy in
+// turn. The reply holds the reply of each strategy and flags where they diverge.
+func (app *application) routesFanout(router *httprouter.Router) {
+	type key struct{ version, resource string }
+
+	strategies := make(map[key]map[string]handler.Handler)
+	var order []registration
+
+	for _, reg := range app.registrations {
+		k := key{reg.version, reg.resource}
+
+		if _, ok := strategies[k]; !ok {
+			strategies[k] = make(map[string]handler.Handler)
+			order = append(order, reg)
+		}
+
+		strategies[k][reg.strategy] = reg.handler
+	}
+
+	ids := newFanoutIDs(fanoutMaxIDs)
+
+	for _, reg := range order {
+		handlers := strategies[key{reg.version, reg.resource}]
+
+		fanout := func(operation func(handler.Handler) http.HandlerFunc) http.HandlerFunc {
+			return func(w http.ResponseWriter, r *http.Request) {
+				app.fanout(w, r, reg, handlers, operation, ids)
+			}
+		}
+
+		collection := fmt.Sprintf("/all/%s/%s", reg.version, reg.path)
+		item := collection + "/" + reg.item
+
+		router.HandlerFunc(http.MethodGet, collection, fanout(func(h handler.Handler) http.HandlerFunc { return h.ListHandler }))
+		router.HandlerFunc(http.MethodPost, collection, fanout(func(h handler.Handler) http.HandlerFunc { return h.CreateHandler }))
+		router.HandlerFunc(http.MethodGet, item, fanout(func(h handler.Handler) http.HandlerFunc { return h.GetHandler }))
+		router.HandlerFunc(http.MethodPatch, item, fanout(func(h handler.Handler) http.HandlerFunc { return h.UpdateHandler }))
+		router.HandlerFunc(http.MethodDelete, item, fanout(func(h handler.Handler) http.HandlerFunc { return h.DeleteHandler }))
+	}
+}
+
+func (app *application) fanout(w http.ResponseWriter, r *http.Request, reg registration, handlers map[string]handler.Handler, operation func(handler.Handler) http.HandlerFunc, ids *fanoutIDs) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, 1_048_576))
+	if err != nil {
+		app.errors.BadRequestResponse(w, r, err)
+		return
+	}
+
+	params := httprouter.ParamsFromContext(r.Context())
+
+	var results []fanoutResult
+	var allID int64
+
+	for _, strategy := range migrations.Strategies {
+		h, ok := handlers[strategy]
+		if !ok {
+			continue
+		}
+
+		translatedParams, err := translateParams(params, reg.root(), strategy, ids)
+		if err != nil {
+			results = append(results, unknownID(strategy, http.StatusNotFound, err.Error()))
+			continue
+		}
+
+		translatedBody, unknown := translateReferences(body, strategy, ids)
+		if unknown != nil {
+			results = append(results, unknownID(strategy, http.StatusUnprocessableEntity, unknown))
+			continue
+		}
+
+		req := r.Clone(context.WithValue(r.Context(), httprouter.ParamsKey, translatedParams))
+		req.Body = io.NopCloser(bytes.NewReader(translatedBody))
+
+		rec := &fanoutRecorder{header: make(http.Header), status: http.StatusOK}
+
+		start := time.Now()
+		operation(h)(rec, req)
+		duration := time.Since(start)
+
+		result := fanoutResult{
+			Strategy: strategy,
+			Status:   rec.status,
+			Duration: float64(duration.Microseconds()) / 1000,
+			Body:     json.RawMessage(bytes.TrimSpace(rec.body.Bytes())),
+		}
+		if !json.Valid(result.Body) {
+			result.Body, _ = json.Marshal(rec.body.String())
+		}
+
+		if r.Method == http.MethodPost && rec.status == http.StatusCreated {
+			result.ID = createdID(rec.body.Bytes())
+
+			// the record is known by the id of the first strategy that created it
+			if allID == 0 {
+				allID = result.ID
+			}
+			if result.ID != 0 {
+				ids.set(reg.resource, allID, strategy, result.ID)
+			}
+		}
+		result.compared = translateReplyIDs(result.Body, reg.root(), strategy, ids)
+
+		results = append(results, result)
+	}
+
+	divergences := diverge(results)
+
+	// a status all strategies agree on is passed through, a split outcome is a multi-status
+	status := results[0].Status
+	for _, result := range results {
+		if result.Status != status {
+			status = http.StatusMultiStatus
+			break
+		}
+	}
+
+	// a record deleted from every strategy is no longer addressed through /all
+	if r.Method == http.MethodDelete && !reg.nested() && status == http.StatusOK {
+		if id, err := strconv.ParseInt(params.ByName("id"), 10, 64); err == nil {
+			ids.delete(reg.resource, id)
+		}
+	}
+
+	env := util.Envelope{
+		"consistent":  len(divergences) == 0,
+		"results":     results,
+		"divergences": divergences,
+	}
+	if allID != 0 {
+		env["id"] = allID
+	}
+
+	if len(divergences) > 0 {
+		app.logger.Warn("strategies diverged", "method", r.Method, "uri", r.URL.RequestURI(), "divergences", len(divergences))
+	}
+
+	err = util.WriteJSON(w, status, util.Envelope{"fanout": env}, nil)
+	if err != nil {
+		app.errors.ServerErrorResponse(w, r, err)
+	}
+}
+
+// translateParams replaces the ids in the params of a route with the ids of strategy: the id
+// of the root resource, e.g. the movie of /movies/:id/crew, and references like :person_id
+func translateParams(params httprouter.Params, root string, strategy string, ids *fanoutIDs) (httprouter.Params, error) {
+	translated := make(httprouter.Params, len(params))
+
+	for i, param := range params {
+		translated[i] = param
+
+		resource, ok := references[param.Key]
+		if param.Key == "id" {
+			resource, ok = root, true
+		}
+
+		id, err := strconv.ParseInt(param.Value, 10, 64)
+		if !ok || err != nil {
+			continue // left for the handlers to reject
+		}
+
+		strategyID, err := ids.get(resource, id, strategy)
+		if err != nil {
+			return nil, err
+		}
+		translated[i].Value = strconv.FormatInt(strategyID, 10)
+	}
+
+	return translated, nil
+}
+
+// translateReferences replaces the ids referencing records created through /all, like the
+// person_id of a crew member, with the ids of strategy. Bodies that are not JSON are passed
+// on as they are, for the handlers to reject. The errors are those of the references that are
+// unknown, by field.
+func translateReferences(body []byte, strategy string, ids *fanoutIDs) ([]byte, map[string]string) {
+	value, ok := decodeNumbers(body)
+	if !ok {
+		return body, nil
+	}
+
+	errors := make(map[string]string)
+
+	walkReferences(value, "", func(field string, resource string, id int64) (int64, bool) {
+		strategyID, err := ids.get(resource, id, strategy)
+		if err != nil {
+			errors[field] = err.Error()
+			return 0, false
+		}
+		return strategyID, true
+	})
+
+	if len(errors) > 0 {
+		return nil, errors
+	}
+
+	translated, err := json.Marshal(value)
+	if err != nil {
+		return body, nil
+	}
+	return translated, nil
+}
+
+// translateReplyIDs replaces the ids in the reply of strategy with the ids of the records
+// through /all, so replies of strategies that generated different ids compare equal: the ids of
+// the records in the envelope, e.g. movie.id or movies[0].id, and the references anywhere
+// in them, e.g. movie.crew[0].person_id. Ids that are unknown are left as they are.
+func translateReplyIDs(body []byte, root string, strategy string, ids *fanoutIDs) json.RawMessage {
+	value, ok := decodeNumbers(body)
+	if !ok {
+		return body
+	}
+
+	original := func(field string, resource string, id int64) (int64, bool) {
+		return ids.original(resource, strategy, id)
+	}
+
+	if env, ok := value.(map[string]any); ok {
+		for _, record := range env {
+			records, isList := record.([]any)
+			if !isList {
+				records = []any{record}
+			}
+
+			for _, record := range records {
+				if fields, ok := record.(map[string]any); ok {
+					translateNumber(fields, "id", root, original)
+				}
+			}
+		}
+	}
+
+	walkReferences(value, "", original)
+
+	translated, err := json.Marshal(value)
+	if err != nil {
+		return body
+	}
+	return translated
+}
+
+// walkReferences calls translate for every field of value referencing another record, like
+// crew[0].person_id, and replaces the id with the one it returns if it is ok
+func walkReferences(value any, path string, translate func(field string, resource string, id int64) (int64, bool)) {
+	switch v := value.(type) {
+	case map[string]any:
+		for field, child := range v {
+			if resource, ok := references[field]; ok {
+				translateNumber(v, field, resource, func(_ string, resource string, id int64) (int64, bool) {
+					return translate(join(path, field), resource, id)
+				})
+				continue
+			}
+			walkReferences(child, join(path, field), translate)
+		}
+	case []any:
+		for i, child := range v {
+			walkReferences(child, fmt.Sprintf("%s[%d]", path, i), translate)
+		}
+	}
+}
+
+// translateNumber replaces the id in fields[field] with the one translate returns if it is ok
+func translateNumber(fields map[string]any, field string, resource string, translate func(field string, resource string, id int64) (int64, bool)) {
+	n, ok := fields[field].(json.Number)
+	if !ok {
+		return
+	}
+
+	id, err := n.Int64()
+	if err != nil {
+		return
+	}
+
+	if translated, ok := translate(field, resource, id); ok {
+		fields[field] = translated
+	}
+}
+
+// decodeNumbers decodes a JSON body keeping its numbers as they are
+func decodeNumbers(body []byte) (any, bool) {
+	if len(body) == 0 {
+		return nil, false
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+
+	var value any
+	if dec.Decode(&value) != nil {
+		return nil, false
+	}
+	return value, true
+}
+
+func join(path string, field string) string {
+	if path == "" {
+		return field
+	}
+	return path + "." + field
+}
+
+// unknownID is the result of a strategy the request was not applied to, as it addresses a
+// record unknown to the strategy
+func unknownID(strategy string, status int, message any) fanoutResult {
+	body, _ := json.Marshal(util.Envelope{"error": message})
+
+	return fanoutResult{
+		Strategy: strategy,
+		Status:   status,
+		Body:     body,
+		compared: body,
+	}
+}
+
+// createdID returns the id of the record in a response like {"movie": {"id": 1, ...}}
+func createdID(body []byte) int64 {
+	var env map[string]struct {
+		ID int64 `json:"id"`
+	}
+	if json.Unmarshal(body, &env) != nil || len(env) != 1 {
+		return 0
+	}
+
+	for _, record := range env {
+		return record.ID
+	}
+	return 0
+}
+
+// diverge compares the replies of the strategies: their statuses, the ids they generated,
+// their validation errors and every other field of the bodies
+func diverge(results []fanoutResult) []divergence {
+	divergences := []divergence{}
+
+	statuses := make(map[string]any)
+	fields := make(map[string]map[string]any)
+
+	for _, result := range results {
+		statuses[result.Strategy] = result.Status
+
+		var body any
+		if json.Unmarshal(result.compared, &body) != nil {
+			continue
+		}
+
+		flat := make(map[string]any)
+		flatten("", body, flat)
+
+		for field, value := range flat {
+			if fields[field] == nil {
+				fields[field] = make(map[string]any)
+			}
+			fields[field][result.Strategy] = value
+		}
+	}
+
+	if !equal(statuses, len(results)) {
+		divergences = append(divergences, divergence{Kind: "status", Values: statuses})
+	}
+
+	for _, field := range slices.Sorted(maps.Keys(fields)) {
+		values := fields[field]
+		if equal(values, len(results)) {
+			continue
+		}
+
+		kind := "field"
+		switch {
+		case field == "error" || strings.HasPrefix(field, "error."):
+			kind = "validation"
+		case isID(field):
+			kind = "id"
+		}
+
+		divergences = append(divergences, divergence{Kind: kind, Field: field, Values: values})
+	}
+
+	return divergences
+}
+
+// isID reports whether a flattened field is an id, of a record in an envelope or referenced by
+// it, e.g. movie.id, movies[0].id or movie.crew[0].person_id
+func isID(field string) bool {
+	name := field[strings.LastIndex(field, ".")+1:]
+	_, reference := references[name]
+	return name == "id" && name != field || reference
+}
+
+// flatten collects the leaves of a JSON value by path, e.g. movie.crew[0].person_id
+func flatten(path string, value any, flat map[string]any) {
+	switch v := value.(type) {
+	case map[string]any:
+		for key, child := range v {
+			if path == "" {
+				flatten(key, child, flat)
+			} else {
+				flatten(path+"."+key, child, flat)
+			}
+		}
+	case []any:
+		for i, child := range v {
+			flatten(fmt.Sprintf("%s[%d]", path, i), child, flat)
+		}
+		if len(v) == 0 {
+			flat[path] = v
+		}
+	default:
+		flat[path] = v
+	}
+}
+
+// equal reports whether all n strategies have the same value
+func equal(values map[string]any, n int) bool {
+	if len(values) != n {
+		return false
+	}
+
+	var first []byte
+	for _, value := range values {
+		encoded, _ := json.Marshal(value)
+		if first == nil {
+			first = encoded
+			continue
+		}
+		if !bytes.Equal(first, encoded) {
+			return false
+		}
+	}
+	return true
+}
+
+// fanoutRecorder buffers the reply of a strategy
+type fanoutRecorder struct {
+	header      http.Header
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func (rec *fanoutRecorder) Header() http.Header {
+	return rec.header
+}
+
+func (rec *fanoutRecorder) WriteHeader(status int) {
+	if !rec.wroteHeader {
+		rec.status = status
+		rec.wroteHeader = true
+	}
+}
+
+func (rec *fanoutRecorder) Write(b []byte) (int, error) {
+	rec.wroteHeader = true
+	return rec.body.Write(b)
+}
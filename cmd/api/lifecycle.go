@@ -0,0 +1,92 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+
+	"thesis.lefler.eu/internal/util"
+)
+
+type lifecycleState string
+
+const (
+	stateActive     lifecycleState = "active"
+	stateDeprecated lifecycleState = "deprecated"
+	stateSunset     lifecycleState = "sunset"
+)
+
+// lifecycle describes when an API version is retired and what replaces it
+type lifecycle struct {
+	deprecated time.Time // from this date responses carry the Deprecation and Sunset headers
+	sunset     time.Time // from this date the version answers with 410 Gone
+	successor  string    // version clients should migrate to
+}
+
+// lifecycles lists every version that is being retired, versions not listed are active
+var lifecycles = map[string]lifecycle{
+	"v1": {
+		deprecated: time.Date(2026, time.October, 1, 0, 0, 0, 0, time.UTC),
+		sunset:     time.Date(2027, time.January, 1, 0, 0, 0, 0, time.UTC),
+		successor:  "v5",
+	},
+	"v2": {
+		deprecated: time.Date(2026, time.October, 1, 0, 0, 0, 0, time.UTC),
+		sunset:     time.Date(2027, time.April, 1, 0, 0, 0, 0, time.UTC),
+		successor:  "v5",
+	},
+}
+
+func (l lifecycle) state(now time.Time) lifecycleState {
+	switch {
+	case !l.sunset.IsZero() && !now.Before(l.sunset):
+		return stateSunset
+	case !l.deprecated.IsZero() && !now.Before(l.deprecated):
+		return stateDeprecated
+	default:
+		return stateActive
+	}
+}
+
+// successorURL points to the same resource, and item if the route has one, in the successor version
+func (l lifecycle) successorURL(r *http.Request, strategy string, resource string) string {
+	url := fmt.Sprintf("/%s/%s/%s", strategy, l.successor, resource)
+
+	if id := httprouter.ParamsFromContext(r.Context()).ByName("id"); id != "" {
+		url = fmt.Sprintf("%s/%s", url, id)
+	}
+
+	return url
+}
+
+// lifecycleHeaders announces the deprecation of a version with the Deprecation (RFC 9745),
+// Sunset (RFC 8594) and successor-version Link headers, and rejects requests once it is sunset
+func (app *application) lifecycleHeaders(strategy string, version string, resource string) func(http.HandlerFunc) http.HandlerFunc {
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		l, ok := lifecycles[version]
+		if !ok {
+			return next
+		}
+
+		return func(w http.ResponseWriter, r *http.Request) {
+			switch l.state(app.now()) {
+			case stateSunset:
+				app.errors.GoneResponse(w, r, util.Envelope{
+					"message":   fmt.Sprintf("API version %s was sunset on %s, please use %s instead", version, l.sunset.Format(time.DateOnly), l.successor),
+					"successor": l.successorURL(r, strategy, resource),
+				})
+				return
+			case stateDeprecated:
+				w.Header().Set("Deprecation", fmt.Sprintf("@%d", l.deprecated.Unix()))
+				if !l.sunset.IsZero() {
+					w.Header().Set("Sunset", l.sunset.UTC().Format(http.TimeFormat))
+				}
+				w.Header().Add("Link", fmt.Sprintf(`<%s>; rel="successor-version"`, l.successorURL(r, strategy, resource)))
+			}
+
+			next(w, r)
+		}
+	}
+}
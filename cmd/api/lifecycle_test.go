@@ -0,0 +1,73 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLifecycleHeaders(t *testing.T) {
+	app := newTestApp(t)
+	routes := app.routes()
+
+	v1, v2 := lifecycles["v1"], lifecycles["v2"]
+
+	tests := []struct {
+		name       string
+		now        time.Time
+		target     string
+		status     int
+		deprecated bool
+		successor  string
+	}{
+		{"active", v1.deprecated.Add(-time.Second), "/views/v1/movies", http.StatusOK, false, ""},
+		{"deprecated", v1.deprecated, "/views/v1/movies", http.StatusOK, true, "/views/v5/movies"},
+		{"deprecated item", v1.deprecated, "/branches/v1/movies/1", http.StatusNotFound, true, "/branches/v5/movies/1"},
+		{"sunset", v1.sunset, "/views/v1/movies", http.StatusGone, false, "/views/v5/movies"},
+		{"sunset item", v1.sunset, "/expand_deprecate/v1/movies/1", http.StatusGone, false, "/expand_deprecate/v5/movies/1"},
+		{"other version still deprecated", v1.sunset, "/views/v2/movies", http.StatusOK, true, "/views/v5/movies"},
+		{"other version sunset", v2.sunset, "/views/v2/movies", http.StatusGone, false, "/views/v5/movies"},
+		{"successor never deprecated", v2.sunset, "/views/v5/movies", http.StatusOK, false, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app.now = func() time.Time { return tt.now }
+
+			w := httptest.NewRecorder()
+			routes.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.target, nil))
+
+			if w.Code != tt.status {
+				t.Fatalf("got status %d, want %d: %s", w.Code, tt.status, w.Body)
+			}
+
+			if got := w.Header().Get("Deprecation") != ""; got != tt.deprecated {
+				t.Errorf("got Deprecation %q, want it set %t", w.Header().Get("Deprecation"), tt.deprecated)
+			}
+			if tt.deprecated && w.Header().Get("Sunset") == "" {
+				t.Error("got no Sunset header for a deprecated version")
+			}
+
+			successor := strings.TrimSuffix(strings.TrimPrefix(w.Header().Get("Link"), "<"), `>; rel="successor-version"`)
+			if tt.status == http.StatusGone {
+				var body struct {
+					Error struct {
+						Successor string `json:"successor"`
+					} `json:"error"`
+				}
+				err := json.Unmarshal(w.Body.Bytes(), &body)
+				if err != nil {
+					t.Fatal(err)
+				}
+				successor = body.Error.Successor
+			}
+
+			if successor != tt.successor {
+				t.Errorf("got successor %q, want %q", successor, tt.successor)
+			}
+		})
+	}
+}
@@ -8,21 +8,29 @@ import (
 	"log/slog"
 	"net/http"
 	"os"
+	"regexp"
+	"strings"
 	"time"
 
 	"thesis.lefler.eu/internal/data"
 	e "thesis.lefler.eu/internal/error"
 	"thesis.lefler.eu/internal/handler"
+	"thesis.lefler.eu/internal/schema"
+	"thesis.lefler.eu/internal/telemetry"
 
 	_ "github.com/lib/pq"
 )
 
 const version = "1.0.0"
 
+var defaultVersionRX = regexp.MustCompile(`^v[0-9]+$`)
+
 type config struct {
-	port int
-	env  string
-	db   struct {
+	port           int
+	env            string
+	defaultVersion string
+	fanout         bool
+	db             struct {
 		dsn struct {
 			views           string
 			expandDeprecate string
@@ -32,14 +40,35 @@ type config struct {
 		maxIdleConns int
 		maxIdleTime  time.Duration
 	}
+	shadow struct {
+		rules   string
+		ignore  string
+		workers int
+		queue   int
+	}
+	telemetry struct {
+		persist       bool
+		flushInterval time.Duration
+	}
+	admin struct {
+		port  int
+		token string
+	}
 }
 
 type application struct {
-	config   config
-	handlers handler.Handlers
-	logger   *slog.Logger
-	errors   e.Errors
-	models   data.Models
+	config         config
+	handlers       handler.Handlers
+	logger         *slog.Logger
+	errors         e.Errors
+	models         data.Models
+	dbs            map[string]*sql.DB
+	schemaVersions map[string]int64
+	schemas        map[string]schema.Versions
+	registrations  []registration
+	usage          *telemetry.Recorder
+	shadow         *shadowReader
+	now            func() time.Time // clock the lifecycle of versions is read at
 }
 
 func main() {
@@ -47,6 +76,14 @@ func main() {
 
 	flag.IntVar(&cfg.port, "port", 4000, "API server port")
 	flag.StringVar(&cfg.env, "env", "development", "Environment (development|staging|production)")
+	flag.StringVar(&cfg.defaultVersion, "default-version", "v5", "API version served when a request does not select one")
+
+	flag.BoolVar(&cfg.fanout, "fanout", false, "Serve /all/{version}/{resource}, applying every request to each strategy")
+
+	flag.StringVar(&cfg.shadow.rules, "shadow", "", "Shadow sampled GETs to the other strategies, e.g. v5/movies=0.1,v4/*=0.05,*/*=0.01")
+	flag.StringVar(&cfg.shadow.ignore, "shadow-ignore", "", "Comma separated fields not compared by shadow reads, e.g. movie.crew.person_id")
+	flag.IntVar(&cfg.shadow.workers, "shadow-workers", 4, "Number of concurrent shadow reads")
+	flag.IntVar(&cfg.shadow.queue, "shadow-queue", 100, "Shadow reads waiting for a worker before further reads are dropped")
 
 	flag.StringVar(&cfg.db.dsn.views, "db-dsn-views", os.Getenv("VIEWS_DB_DSN"), "PostgreSQL DSN for Views method")
 	flag.StringVar(&cfg.db.dsn.expandDeprecate, "db-dsn-expand-deprecate", os.Getenv("EXPAND_DEPRECATE_DB_DSN"), "PostgreSQL DSN for Expand & Deprecate method")
@@ -56,16 +93,31 @@ func main() {
 	flag.IntVar(&cfg.db.maxIdleConns, "db-max-idle-conns", 25, "PostgreSQL max idle connections")
 	flag.DurationVar(&cfg.db.maxIdleTime, "db-max-idle-time", 15*time.Minute, "PostgreSQL max connection idle time")
 
+	flag.BoolVar(&cfg.telemetry.persist, "telemetry-persist", false, "Persist per version usage to the api_usage tables of each database")
+	flag.DurationVar(&cfg.telemetry.flushInterval, "telemetry-flush-interval", time.Minute, "Interval between writes of usage to the database")
+
+	flag.IntVar(&cfg.admin.port, "admin-port", 4001, "Admin server port, serving /admin/usage, /admin/storage and /admin/shadow")
+	flag.StringVar(&cfg.admin.token, "admin-token", os.Getenv("ADMIN_TOKEN"), "Bearer token required by the admin server, which is not started without one")
+
 	flag.Parse()
 
 	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}))
 
+	if !defaultVersionRX.MatchString(cfg.defaultVersion) {
+		logger.Error("invalid default version, must look like v5", "default-version", cfg.defaultVersion)
+		os.Exit(1)
+	}
+
+	shadowRules, err := parseShadowRules(cfg.shadow.rules)
+	if err != nil {
+		logger.Error(err.Error())
+		os.Exit(1)
+	}
+
 	dbConns := data.DbConns{
 		Views: nil,
 	}
 
-	var err error
-
 	dbConns.Views, err = openDB(cfg, cfg.db.dsn.views, "views")
 	if err != nil {
 		logger.Error(err.Error())
@@ -90,15 +142,56 @@ func main() {
 
 	logger.Info("database connection pool established")
 
+	dbs := map[string]*sql.DB{
+		"views":            dbConns.Views,
+		"expand_deprecate": dbConns.ExpandDeprecate,
+		"branches":         dbConns.Branches,
+	}
+
+	schemaVersions, err := readSchemaVersions(dbs)
+	if err != nil {
+		logger.Error(err.Error())
+		os.Exit(1)
+	}
+
+	logger.Info("database schema versions read", "views", schemaVersions["views"], "expand_deprecate", schemaVersions["expand_deprecate"], "branches", schemaVersions["branches"])
+
 	models := data.NewModels(dbConns)
 	errors := e.NewErrors(logger)
 
 	app := &application{
-		config:   cfg,
-		logger:   logger,
-		models:   models,
-		errors:   errors,
-		handlers: handler.NewHandlers(&errors, &models),
+		config:         cfg,
+		logger:         logger,
+		models:         models,
+		dbs:            dbs,
+		errors:         errors,
+		handlers:       handler.NewHandlers(&errors, &models),
+		schemaVersions: schemaVersions,
+		schemas:        data.Schemas(),
+		usage:          telemetry.NewRecorder(),
+		now:            time.Now,
+	}
+
+	if len(shadowRules) > 0 {
+		app.shadow = newShadowReader(shadowRules, strings.Split(cfg.shadow.ignore, ","), cfg.shadow.workers, cfg.shadow.queue, logger)
+	}
+
+	if cfg.telemetry.persist {
+		store := telemetry.Store{DBs: dbs}
+
+		err = store.Load(app.usage)
+		if err != nil {
+			logger.Error(err.Error())
+			os.Exit(1)
+		}
+
+		go func() {
+			for range time.Tick(cfg.telemetry.flushInterval) {
+				if err := store.Flush(app.usage); err != nil {
+					logger.Error("flushing usage telemetry", "error", err.Error())
+				}
+			}
+		}()
 	}
 
 	srv := &http.Server{
@@ -110,6 +203,27 @@ func main() {
 		ErrorLog:     slog.NewLogLogger(logger.Handler(), slog.LevelError),
 	}
 
+	if cfg.admin.token != "" {
+		adminSrv := &http.Server{
+			Addr:         fmt.Sprintf("%s:%d", "localhost", cfg.admin.port),
+			Handler:      app.adminRoutes(),
+			IdleTimeout:  time.Minute,
+			ReadTimeout:  5 * time.Second,
+			WriteTimeout: storageTimeout + 10*time.Second,
+			ErrorLog:     slog.NewLogLogger(logger.Handler(), slog.LevelError),
+		}
+
+		go func() {
+			logger.Info("starting admin server", "addr", adminSrv.Addr)
+
+			err := adminSrv.ListenAndServe()
+			logger.Error(err.Error())
+			os.Exit(1)
+		}()
+	} else {
+		logger.Warn("admin server not started, no admin token set")
+	}
+
 	logger.Info("starting server", "addr", srv.Addr, "env", cfg.env)
 
 	err = srv.ListenAndServe()
@@ -3,6 +3,8 @@ package main
 import (
 	"fmt"
 	"net/http"
+
+	"thesis.lefler.eu/internal/handler"
 )
 
 func (app *application) recoverPanic(next http.Handler) http.Handler {
@@ -17,3 +19,33 @@ func (app *application) recoverPanic(next http.Handler) http.Handler {
 		next.ServeHTTP(w, r)
 	})
 }
+
+// wrappedHandler applies the same middleware to every operation of a resource handler
+type wrappedHandler struct {
+	next handler.Handler
+	wrap func(http.HandlerFunc) http.HandlerFunc
+}
+
+func wrapHandler(next handler.Handler, wrap func(http.HandlerFunc) http.HandlerFunc) handler.Handler {
+	return wrappedHandler{next: next, wrap: wrap}
+}
+
+func (h wrappedHandler) CreateHandler(w http.ResponseWriter, r *http.Request) {
+	h.wrap(h.next.CreateHandler)(w, r)
+}
+
+func (h wrappedHandler) GetHandler(w http.ResponseWriter, r *http.Request) {
+	h.wrap(h.next.GetHandler)(w, r)
+}
+
+func (h wrappedHandler) UpdateHandler(w http.ResponseWriter, r *http.Request) {
+	h.wrap(h.next.UpdateHandler)(w, r)
+}
+
+func (h wrappedHandler) DeleteHandler(w http.ResponseWriter, r *http.Request) {
+	h.wrap(h.next.DeleteHandler)(w, r)
+}
+
+func (h wrappedHandler) ListHandler(w http.ResponseWriter, r *http.Request) {
+	h.wrap(h.next.ListHandler)(w, r)
+}
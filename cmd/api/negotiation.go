@@ -0,0 +1,154 @@
+package main
+
+import (
+	"fmt"
+	"mime"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/julienschmidt/httprouter"
+
+	"thesis.lefler.eu/internal/handler"
+	"thesis.lefler.eu/internal/schema"
+)
+
+// vendorMediaTypeRX matches vendor media types like application/vnd.movies.v4+json
+var vendorMediaTypeRX = regexp.MustCompile(`^application/vnd\.[a-z0-9_.-]+\.(v[0-9]+)\+json$`)
+
+// registration is a single strategy, version and resource as passed to registerRoutes or
+// registerNestedRoutes
+type registration struct {
+	strategy string
+	version  string
+	resource string // e.g. movies, or movies/crew for the crew nested in the movies
+	path     string // collection below the version, e.g. movies or movies/:id/crew
+	item     string // params addressing an item below the collection, e.g. :id or :person_id/:crew_type
+	envelope string // key of an item in replies, if it is not the lower case model name
+	handler  handler.Handler
+	base     handler.Handler // handler without middleware, nil if the schema does not support the version
+	model    schema.Type
+}
+
+// root is the top level resource of the registration, the parent of a nested resource
+func (reg registration) root() string {
+	root, _, _ := strings.Cut(reg.resource, "/")
+	return root
+}
+
+// nested reports whether the resource is nested in the items of another
+func (reg registration) nested() bool {
+	return strings.Contains(reg.resource, "/")
+}
+
+// routesNegotiated registers /{strategy}/{resource} routes for every resource passed to
+// registerRoutes or registerNestedRoutes, dispatching to the version selected by the request headers
+func (app *application) routesNegotiated(router *httprouter.Router) {
+	type key struct{ strategy, resource string }
+
+	versions := make(map[key]map[string]handler.Handler)
+	var order []registration
+
+	for _, reg := range app.registrations {
+		k := key{reg.strategy, reg.resource}
+
+		if _, ok := versions[k]; !ok {
+			versions[k] = make(map[string]handler.Handler)
+			order = append(order, reg)
+		}
+
+		versions[k][reg.version] = reg.handler
+	}
+
+	for _, reg := range order {
+		handlers := versions[key{reg.strategy, reg.resource}]
+
+		dispatch := func(operation func(handler.Handler) http.HandlerFunc) http.HandlerFunc {
+			return func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Add("Vary", "Accept")
+				w.Header().Add("Vary", "Accept-Version")
+
+				version, err := app.negotiateVersion(r)
+				if err != nil {
+					app.errors.NotAcceptableResponse(w, r, err.Error())
+					return
+				}
+
+				h, ok := handlers[version]
+				if !ok {
+					app.errors.VersionNotAvailableResponse(w, r, fmt.Sprintf("the %s resource is not available in API version %s", reg.resource, version))
+					return
+				}
+
+				operation(h)(w, r)
+			}
+		}
+
+		collection := fmt.Sprintf("/%s/%s", reg.strategy, reg.path)
+		item := collection + "/" + reg.item
+
+		router.HandlerFunc(http.MethodGet, collection, dispatch(func(h handler.Handler) http.HandlerFunc { return h.ListHandler }))
+		router.HandlerFunc(http.MethodPost, collection, dispatch(func(h handler.Handler) http.HandlerFunc { return h.CreateHandler }))
+		router.HandlerFunc(http.MethodGet, item, dispatch(func(h handler.Handler) http.HandlerFunc { return h.GetHandler }))
+		router.HandlerFunc(http.MethodPatch, item, dispatch(func(h handler.Handler) http.HandlerFunc { return h.UpdateHandler }))
+		router.HandlerFunc(http.MethodDelete, item, dispatch(func(h handler.Handler) http.HandlerFunc { return h.DeleteHandler }))
+	}
+}
+
+// negotiateVersion resolves the requested API version from the Accept-Version header,
+// then from a vendor media type in the Accept header, and falls back to the configured default
+func (app *application) negotiateVersion(r *http.Request) (string, error) {
+	if value := strings.TrimSpace(r.Header.Get("Accept-Version")); value != "" {
+		version := strings.ToLower(value)
+		if !strings.HasPrefix(version, "v") {
+			version = "v" + version
+		}
+
+		if !app.knownVersion(version) {
+			return "", fmt.Errorf("the requested API version %q is not supported", value)
+		}
+
+		return version, nil
+	}
+
+	for _, accept := range r.Header.Values("Accept") {
+		for _, part := range strings.Split(accept, ",") {
+			mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(part))
+			if err != nil {
+				continue
+			}
+
+			matches := vendorMediaTypeRX.FindStringSubmatch(mediaType)
+			if matches == nil {
+				continue
+			}
+
+			if !app.knownVersion(matches[1]) {
+				return "", fmt.Errorf("the requested media type %q is not supported", mediaType)
+			}
+
+			return matches[1], nil
+		}
+	}
+
+	return app.config.defaultVersion, nil
+}
+
+func (app *application) knownVersion(version string) bool {
+	for _, reg := range app.registrations {
+		if reg.version == version {
+			return true
+		}
+	}
+	return false
+}
+
+// contentVersion echoes the version that served the request in the Content-Version header
+func contentVersion(version string) func(http.HandlerFunc) http.HandlerFunc {
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Version", version)
+			next(w, r)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/julienschmidt/httprouter"
+
+	"thesis.lefler.eu/internal/openapi"
+)
+
+// routesOpenAPI registers the OpenAPI documents of everything passed to registerRoutes and
+// registerNestedRoutes:
+// /{strategy}/{version}/openapi.json per version, /{strategy}/openapi.json per strategy
+// and /openapi.json combining all of them
+func (app *application) routesOpenAPI(router *httprouter.Router) {
+	seen := make(map[string]bool)
+
+	for _, reg := range app.registrations {
+		if !seen[reg.strategy] {
+			seen[reg.strategy] = true
+			router.HandlerFunc(http.MethodGet, fmt.Sprintf("/%s/openapi.json", reg.strategy), app.openAPIHandler(reg.strategy, ""))
+		}
+
+		if path := fmt.Sprintf("/%s/%s/openapi.json", reg.strategy, reg.version); !seen[path] {
+			seen[path] = true
+			router.HandlerFunc(http.MethodGet, path, app.openAPIHandler(reg.strategy, reg.version))
+		}
+	}
+
+	router.HandlerFunc(http.MethodGet, "/openapi.json", app.openAPIHandler("", ""))
+}
+
+// openAPIHandler serves the document of the given strategy and version, empty values match all
+func (app *application) openAPIHandler(strategy string, version string) http.HandlerFunc {
+	title := "Movies API"
+	switch {
+	case version != "":
+		title = fmt.Sprintf("Movies API (%s %s)", strategy, version)
+	case strategy != "":
+		title = fmt.Sprintf("Movies API (%s)", strategy)
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		now := app.now()
+
+		var resources []openapi.Resource
+		for _, reg := range app.registrations {
+			if strategy != "" && reg.strategy != strategy || version != "" && reg.version != version {
+				continue
+			}
+
+			l, ok := lifecycles[reg.version]
+
+			resource := openapi.Resource{
+				Strategy:   reg.strategy,
+				Version:    reg.version,
+				Name:       reg.resource,
+				Envelope:   reg.envelope,
+				Deprecated: ok && l.state(now) != stateActive,
+				Model:      reg.model,
+			}
+
+			if reg.nested() {
+				resource.Parent, resource.Name, _ = strings.Cut(reg.resource, "/")
+
+				for _, param := range strings.Split(reg.item, "/") {
+					resource.Item = append(resource.Item, strings.TrimPrefix(param, ":"))
+				}
+			}
+
+			resources = append(resources, resource)
+		}
+
+		// the document is the response itself, not wrapped in an envelope like other responses
+		js, err := json.MarshalIndent(openapi.New(title, resources...), "", "  ")
+		if err != nil {
+			app.errors.ServerErrorResponse(w, r, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(append(js, '\n'))
+	}
+}
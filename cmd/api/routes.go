@@ -16,18 +16,80 @@ func (app *application) routes() http.Handler {
 	router.MethodNotAllowed = http.HandlerFunc(app.errors.MethodNotAllowedResponse)
 
 	router.HandlerFunc(http.MethodGet, "/healthcheck", app.healthcheckHandler)
+	router.HandlerFunc(http.MethodGet, "/versions", app.versionsHandler)
 
 	app.routesViews(router)           // views routes
 	app.routesExpandDeprecate(router) // expand_deprecate routes
 	app.routesBranches(router)        // branches routes
 
+	app.routesNegotiated(router) // header negotiated routes for everything registered above
+	app.routesDiscovery(router)  // /{strategy}/versions for everything registered above
+	app.routesOpenAPI(router)    // OpenAPI documents for everything registered above
+
+	if app.config.fanout {
+		app.routesFanout(router) // /all/{version}/{resource} for everything registered above
+	}
+
+	if app.shadow != nil {
+		app.shadow.start(app.registrations) // shadow reads between everything registered above
+	}
+
 	return app.recoverPanic(router)
 }
 
-func registerRoutes(router *httprouter.Router, prefix string, version string, resource string, handler handler.Handler) {
-	router.HandlerFunc(http.MethodGet, fmt.Sprintf("%s/%s/%s", prefix, version, resource), handler.ListHandler)
-	router.HandlerFunc(http.MethodPost, fmt.Sprintf("%s/%s/%s", prefix, version, resource), handler.CreateHandler)
-	router.HandlerFunc(http.MethodGet, fmt.Sprintf("%s/%s/%s/:id", prefix, version, resource), handler.GetHandler)
-	router.HandlerFunc(http.MethodPatch, fmt.Sprintf("%s/%s/%s/:id", prefix, version, resource), handler.UpdateHandler)
-	router.HandlerFunc(http.MethodDelete, fmt.Sprintf("%s/%s/%s/:id", prefix, version, resource), handler.DeleteHandler)
+// registerRoutes registers the CRUD routes of a resource, or, if the database of the strategy
+// is not migrated to a schema that supports the version, routes explaining why it is unavailable.
+func (app *application) registerRoutes(router *httprouter.Router, prefix string, version string, resource string, handler handler.Handler) {
+	app.register(router, registration{
+		strategy: prefix,
+		version:  version,
+		resource: resource,
+		path:     resource,
+		item:     ":id",
+	}, handler)
+}
+
+// registerNestedRoutes registers the CRUD routes of a resource nested in the items of parent, at
+// /{prefix}/{version}/{parent}/:id/{resource}, its items are addressed by the params in item and
+// replied in envelope. It is registered as parent/resource, e.g. movies/crew, and deprecations of
+// the version point to the parent in its successor.
+func (app *application) registerNestedRoutes(router *httprouter.Router, prefix string, version string, parent string, resource string, item string, envelope string, handler handler.Handler) {
+	app.register(router, registration{
+		strategy: prefix,
+		version:  version,
+		resource: parent + "/" + resource,
+		path:     fmt.Sprintf("%s/:id/%s", parent, resource),
+		item:     item,
+		envelope: envelope,
+	}, handler)
+}
+
+// register wraps the handler of reg in the middleware of every resource and registers its routes
+func (app *application) register(router *httprouter.Router, reg registration, handler handler.Handler) {
+	reg.base = handler
+
+	if unavailable := app.schemaAvailability(reg.strategy, reg.version); unavailable != nil {
+		app.logger.Warn("api version not supported by database schema", "strategy", reg.strategy, "version", reg.version, "resource", reg.resource)
+		handler = unavailableHandler{respond: unavailable}
+		reg.base = nil
+	}
+
+	handler = wrapHandler(handler, app.shadowReads(reg))
+	handler = wrapHandler(handler, app.lifecycleHeaders(reg.strategy, reg.version, reg.root()))
+	handler = wrapHandler(handler, contentVersion(reg.version))
+	handler = wrapHandler(handler, app.usage.Middleware(reg.strategy, reg.version, reg.resource))
+
+	reg.handler = handler
+	reg.model = app.schemas[reg.strategy][reg.version][reg.resource]
+
+	app.registrations = append(app.registrations, reg)
+
+	collection := fmt.Sprintf("/%s/%s/%s", reg.strategy, reg.version, reg.path)
+	item := collection + "/" + reg.item
+
+	router.HandlerFunc(http.MethodGet, collection, handler.ListHandler)
+	router.HandlerFunc(http.MethodPost, collection, handler.CreateHandler)
+	router.HandlerFunc(http.MethodGet, item, handler.GetHandler)
+	router.HandlerFunc(http.MethodPatch, item, handler.UpdateHandler)
+	router.HandlerFunc(http.MethodDelete, item, handler.DeleteHandler)
 }
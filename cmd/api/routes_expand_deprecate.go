@@ -6,19 +6,21 @@ import (
 
 func (app *application) routesExpandDeprecate(router *httprouter.Router) {
 	// v1/movies routes
-	registerRoutes(router, "expand_deprecate", "v1", "movies", &app.handlers.ExpandDeprecate.V1.Movies)
+	app.registerRoutes(router, "expand_deprecate", "v1", "movies", &app.handlers.ExpandDeprecate.V1.Movies)
 
 	// v2/movies routes
-	registerRoutes(router, "expand_deprecate", "v2", "movies", &app.handlers.ExpandDeprecate.V2.Movies)
+	app.registerRoutes(router, "expand_deprecate", "v2", "movies", &app.handlers.ExpandDeprecate.V2.Movies)
 
 	// v3/movies routes
-	registerRoutes(router, "expand_deprecate", "v3", "movies", &app.handlers.ExpandDeprecate.V3.Movies)
+	app.registerRoutes(router, "expand_deprecate", "v3", "movies", &app.handlers.ExpandDeprecate.V3.Movies)
 
-	// v4/movies and actors routes
-	registerRoutes(router, "expand_deprecate", "v4", "movies", &app.handlers.ExpandDeprecate.V4.Movies)
-	registerRoutes(router, "expand_deprecate", "v4", "actors", &app.handlers.ExpandDeprecate.V4.Actors)
+	// v4/movies, actors and movies/:id/actors routes
+	app.registerRoutes(router, "expand_deprecate", "v4", "movies", &app.handlers.ExpandDeprecate.V4.Movies)
+	app.registerRoutes(router, "expand_deprecate", "v4", "actors", &app.handlers.ExpandDeprecate.V4.Actors)
+	app.registerNestedRoutes(router, "expand_deprecate", "v4", "movies", "actors", ":actor_id", "actor", &app.handlers.ExpandDeprecate.V4.MovieActors)
 
-	// v5/movies and people routes
-	registerRoutes(router, "expand_deprecate", "v5", "movies", &app.handlers.ExpandDeprecate.V5.Movies)
-	registerRoutes(router, "expand_deprecate", "v5", "people", &app.handlers.ExpandDeprecate.V5.People)
+	// v5/movies, people and movies/:id/crew routes
+	app.registerRoutes(router, "expand_deprecate", "v5", "movies", &app.handlers.ExpandDeprecate.V5.Movies)
+	app.registerRoutes(router, "expand_deprecate", "v5", "people", &app.handlers.ExpandDeprecate.V5.People)
+	app.registerNestedRoutes(router, "expand_deprecate", "v5", "movies", "crew", ":person_id/:crew_type", "crew_member", &app.handlers.ExpandDeprecate.V5.Crew)
 }
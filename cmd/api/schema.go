@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"time"
+
+	"thesis.lefler.eu/internal/migration"
+)
+
+// schemaRange is the span of migrations in which the backing schema of an API version exists
+type schemaRange struct {
+	since int64 // migration that creates the tables or views the version reads and writes
+	until int64 // migration that removes them again, 0 while no cleanup migration does
+}
+
+// schemaRanges maps each strategy and API version to the migrations it depends on
+var schemaRanges = map[string]map[string]schemaRange{
+	"views": {
+		"v1": {since: 12}, // 00012_v1_views.sql
+		"v2": {since: 22}, // 00022_v2_views.sql
+		"v3": {since: 32}, // 00032_v3_views.sql
+		"v4": {since: 42}, // 00042_v4_views.sql
+		"v5": {since: 52}, // 00052_v5_views.sql
+	},
+	"expand_deprecate": {
+		"v1": {since: 10}, // 00010_v1_schema.sql
+		"v2": {since: 20}, // 00020_v2_schema.sql
+		"v3": {since: 30}, // 00030_v3_schema.sql
+		"v4": {since: 40}, // 00040_v4_schema.sql
+		"v5": {since: 50}, // 00050_v5_schema.sql
+	},
+	"branches": {
+		"v1": {since: 10}, // 00010_v1_schema.sql
+		"v2": {since: 20}, // 00020_v2_schema.sql
+		"v3": {since: 30}, // 00030_v3_schema.sql
+		"v4": {since: 40}, // 00040_v4_schema.sql
+		"v5": {since: 50}, // 00050_v5_schema.sql
+	},
+}
+
+// readSchemaVersions reads the applied migration version of every strategy database
+func readSchemaVersions(dbs map[string]*sql.DB) (map[string]int64, error) {
+	versions := make(map[string]int64, len(dbs))
+
+	for strategy, db := range dbs {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		version, err := migration.CurrentVersion(ctx, db)
+		cancel()
+
+		if err != nil {
+			return nil, fmt.Errorf("reading %s schema version: %w", strategy, err)
+		}
+
+		versions[strategy] = version
+	}
+
+	return versions, nil
+}
+
+// schemaAvailability returns nil if the schema backing version is in place, otherwise
+// a handler explaining why the version cannot be served on this database
+func (app *application) schemaAvailability(strategy string, version string) http.HandlerFunc {
+	current := app.schemaVersions[strategy]
+	required, ok := schemaRanges[strategy][version]
+
+	switch {
+	case !ok:
+		return nil
+	case current < required.since:
+		message := fmt.Sprintf("API version %s is not available yet on the %s database, it requires migration %d but the database is at migration %d",
+			version, strategy, required.since, current)
+
+		return func(w http.ResponseWriter, r *http.Request) {
+			app.errors.VersionNotAvailableResponse(w, r, message)
+		}
+	case required.until != 0 && current >= required.until:
+		message := fmt.Sprintf("API version %s is no longer available on the %s database, its schema was removed by migration %d",
+			version, strategy, required.until)
+
+		return func(w http.ResponseWriter, r *http.Request) {
+			app.errors.GoneResponse(w, r, message)
+		}
+	}
+
+	return nil
+}
+
+// unavailableHandler answers every operation of a resource with the same response
+type unavailableHandler struct {
+	respond http.HandlerFunc
+}
+
+func (h unavailableHandler) CreateHandler(w http.ResponseWriter, r *http.Request) { h.respond(w, r) }
+func (h unavailableHandler) GetHandler(w http.ResponseWriter, r *http.Request)    { h.respond(w, r) }
+func (h unavailableHandler) UpdateHandler(w http.ResponseWriter, r *http.Request) { h.respond(w, r) }
+func (h unavailableHandler) DeleteHandler(w http.ResponseWriter, r *http.Request) { h.respond(w, r) }
+func (h unavailableHandler) ListHandler(w http.ResponseWriter, r *http.Request)   { h.respond(w, r) }
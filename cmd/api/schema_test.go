@@ -0,0 +1,40 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSchemaAvailability(t *testing.T) {
+	app := newTestApp(t)
+
+	// no cleanup migration removes the schema of a version yet, so v1 of views gets one here
+	views := schemaRanges["views"]["v1"]
+	schemaRanges["views"]["v1"] = schemaRange{since: views.since, until: 34}
+	t.Cleanup(func() { schemaRanges["views"]["v1"] = views })
+
+	tests := []struct {
+		name    string
+		current int64
+		status  int
+	}{
+		{"before the schema", views.since - 1, http.StatusNotFound},
+		{"with the schema", views.since, http.StatusOK},
+		{"before the cleanup", 33, http.StatusOK},
+		{"after the cleanup", 34, http.StatusGone},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app.schemaVersions = map[string]int64{"views": tt.current}
+
+			w := httptest.NewRecorder()
+			app.routes().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/views/v1/movies", nil))
+
+			if w.Code != tt.status {
+				t.Errorf("got status %d, want %d: %s", w.Code, tt.status, w.Body)
+			}
+		})
+	}
+}
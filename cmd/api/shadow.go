@@ -0,0 +1,488 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"maps"
+	"math/rand"
+	"net/http"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+
+	"thesis.lefler.eu/internal/handler"
+	"thesis.lefler.eu/internal/latency"
+	"thesis.lefler.eu/internal/util"
+	"thesis.lefler.eu/migrations"
+)
+
+const (
+	shadowTimeout = 10 * time.Second
+	shadowMaxBody = 1 << 20 // larger primary replies are not shadowed
+	shadowDeltas  = 1000    // latency deltas kept per comparison
+	shadowFields  = 10      // mismatching fields logged per read
+)
+
+// indexRX matches the array indexes of a flattened field, e.g. [0] in movie.crew[0].role
+var indexRX = regexp.MustCompile(`\[[0-9]+\]`)
+
+// shadowRule samples the GETs of a version and resource, either may be * to match all
+type shadowRule struct {
+	Version  string  `json:"version"`
+	Resource string  `json:"resource"`
+	Rate     float64 `json:"rate"`
+}
+
+// parseShadowRules parses rules like "v5/movies=0.1,v4/*=0.05,*/*=0.01"
+func parseShadowRules(spec string) ([]shadowRule, error) {
+	var rules []shadowRule
+
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		target, rate, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, fmt.Errorf("shadow rule %q must look like version/resource=rate", part)
+		}
+
+		version, resource, ok := strings.Cut(target, "/")
+		if !ok || version == "" || resource == "" {
+			return nil, fmt.Errorf("shadow rule %q must look like version/resource=rate", part)
+		}
+
+		r, err := strconv.ParseFloat(rate, 64)
+		if err != nil || r < 0 || r > 1 {
+			return nil, fmt.Errorf("shadow rule %q must have a rate between 0 and 1", part)
+		}
+
+		rules = append(rules, shadowRule{Version: version, Resource: resource, Rate: r})
+	}
+
+	return rules, nil
+}
+
+type shadowKey struct {
+	primary  string
+	shadow   string
+	version  string
+	resource string
+}
+
+type shadowStats struct {
+	requests   int64
+	mismatches int64
+	errors     int64
+	fields     map[string]int64 // mismatches per field, without array indexes
+	deltas     []time.Duration  // shadow minus primary latency, the last shadowDeltas
+	next       int
+}
+
+// shadowJob is a sampled primary read to repeat against the other strategies
+type shadowJob struct {
+	strategy string
+	version  string
+	resource string
+	item     bool // read of an item rather than of the collection
+	request  *http.Request
+	status   int
+	body     []byte
+	duration time.Duration
+}
+
+// shadowReader repeats a sample of the GETs served by one strategy against the other two in
+// the background, and compares their replies to the primary reply. Reads are queued and
+// dropped when the queue is full, so shadowing never delays or changes the primary response.
+type shadowReader struct {
+	rules   []shadowRule
+	ignored map[string]bool
+	jobs    chan shadowJob
+	workers int
+	logger  *slog.Logger
+
+	// handlers of every strategy, version and resource, without middleware so shadow reads
+	// are neither counted as usage nor shadowed again
+	targets map[shadowKey]handler.Handler
+
+	mu      sync.Mutex
+	stats   map[shadowKey]*shadowStats
+	dropped int64
+}
+
+// newShadowReader returns a shadow reader for the rules, ignoring the fields listed by their
+// flattened name without array indexes, e.g. movie.crew.person_id
+func newShadowReader(rules []shadowRule, ignored []string, workers int, queue int, logger *slog.Logger) *shadowReader {
+	s := &shadowReader{
+		rules:   rules,
+		ignored: make(map[string]bool),
+		jobs:    make(chan shadowJob, queue),
+		workers: workers,
+		logger:  logger,
+		targets: make(map[shadowKey]handler.Handler),
+		stats:   make(map[shadowKey]*shadowStats),
+	}
+
+	for _, field := range ignored {
+		if field = strings.TrimSpace(field); field != "" {
+			s.ignored[field] = true
+		}
+	}
+
+	return s
+}
+
+// rate returns the sampling rate of the first rule matching version and resource
+func (s *shadowReader) rate(version string, resource string) float64 {
+	for _, rule := range s.rules {
+		if (rule.Version == "*" || rule.Version == version) && (rule.Resource == "*" || rule.Resource == resource) {
+			return rule.Rate
+		}
+	}
+	return 0
+}
+
+// start shadows reads to the handlers of every registration and starts the workers
+func (s *shadowReader) start(registrations []registration) {
+	for _, reg := range registrations {
+		if reg.base == nil {
+			continue
+		}
+		s.targets[shadowKey{shadow: reg.strategy, version: reg.version, resource: reg.resource}] = reg.base
+	}
+
+	for range s.workers {
+		go func() {
+			for job := range s.jobs {
+				s.shadow(job)
+			}
+		}()
+	}
+}
+
+// shadowReads samples the GETs of a registration at the rate of the first matching rule,
+// capturing the primary reply while it is written and queueing a shadow read
+func (app *application) shadowReads(reg registration) func(http.HandlerFunc) http.HandlerFunc {
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		if app.shadow == nil {
+			return next
+		}
+
+		rate := app.shadow.rate(reg.version, reg.resource)
+		if rate == 0 {
+			return next
+		}
+
+		return func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodGet || rand.Float64() >= rate {
+				next(w, r)
+				return
+			}
+
+			tee := &teeWriter{ResponseWriter: w, status: http.StatusOK}
+
+			start := time.Now()
+			next(tee, r)
+			duration := time.Since(start)
+
+			if tee.truncated {
+				return
+			}
+
+			// the request outlives the primary, so it gets its own context carrying the ids
+			params := httprouter.ParamsFromContext(r.Context())
+			ctx := context.WithValue(context.Background(), httprouter.ParamsKey, params)
+
+			req := r.Clone(ctx)
+			req.Body = http.NoBody
+
+			app.shadow.enqueue(shadowJob{
+				strategy: reg.strategy,
+				version:  reg.version,
+				resource: reg.resource,
+				item:     len(params) > strings.Count(reg.path, ":"),
+				request:  req,
+				status:   tee.status,
+				body:     tee.body.Bytes(),
+				duration: duration,
+			})
+		}
+	}
+}
+
+func (s *shadowReader) enqueue(job shadowJob) {
+	select {
+	case s.jobs <- job:
+	default:
+		s.mu.Lock()
+		s.dropped++
+		s.mu.Unlock()
+	}
+}
+
+// shadow repeats a primary read against every other strategy serving the version and resource
+func (s *shadowReader) shadow(job shadowJob) {
+	for _, strategy := range migrations.Strategies {
+		if strategy == job.strategy {
+			continue
+		}
+
+		h, ok := s.targets[shadowKey{shadow: strategy, version: job.version, resource: job.resource}]
+		if !ok {
+			continue
+		}
+
+		rec, duration, err := s.read(h, job.request, job.item)
+
+		key := shadowKey{primary: job.strategy, shadow: strategy, version: job.version, resource: job.resource}
+		log := s.logger.With("primary", job.strategy, "shadow", strategy, "version", job.version, "resource", job.resource, "uri", job.request.URL.RequestURI())
+
+		if err != nil {
+			s.failed(key)
+			log.Error("shadow read failed", "error", err.Error())
+			continue
+		}
+
+		fields := s.compare(job.body, rec.body.Bytes())
+		if job.status != rec.status {
+			fields = append([]string{"status"}, fields...)
+		}
+
+		delta := duration - job.duration
+		s.record(key, fields, delta, rec.status >= http.StatusInternalServerError)
+
+		if len(fields) > 0 {
+			log.Warn("shadow read mismatch",
+				"status", job.status, "shadow_status", rec.status,
+				"mismatches", len(fields), "fields", strings.Join(fields[:min(len(fields), shadowFields)], ","),
+				"delta_ms", latency.Milliseconds(delta))
+		}
+	}
+}
+
+// read calls a shadow handler, recovering from its panics so they cannot take down the API
+func (s *shadowReader) read(h handler.Handler, r *http.Request, item bool) (rec *fanoutRecorder, duration time.Duration, err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("%s", p)
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(r.Context(), shadowTimeout)
+	defer cancel()
+
+	operation := h.ListHandler
+	if item {
+		operation = h.GetHandler
+	}
+
+	rec = &fanoutRecorder{header: make(http.Header), status: http.StatusOK}
+
+	start := time.Now()
+	operation(rec, r.WithContext(ctx))
+
+	return rec, time.Since(start), nil
+}
+
+// compare normalises both JSON bodies into their flattened fields and returns the fields
+// that differ, sorted. Bodies that are not JSON are compared byte for byte.
+func (s *shadowReader) compare(primary []byte, shadow []byte) []string {
+	a, errA := normalise(primary)
+	b, errB := normalise(shadow)
+	if errA != nil || errB != nil {
+		if bytes.Equal(bytes.TrimSpace(primary), bytes.TrimSpace(shadow)) {
+			return nil
+		}
+		return []string{"body"}
+	}
+
+	union := maps.Clone(a)
+	maps.Copy(union, b)
+
+	var fields []string
+
+	for _, field := range slices.Sorted(maps.Keys(union)) {
+		if s.ignored[indexRX.ReplaceAllString(field, "")] {
+			continue
+		}
+
+		va, okA := a[field]
+		vb, okB := b[field]
+		if !okA || !okB || !equal(map[string]any{"primary": va, "shadow": vb}, 2) {
+			fields = append(fields, field)
+		}
+	}
+
+	return fields
+}
+
+// normalise decodes a JSON body into its flattened fields, so key order does not matter
+func normalise(body []byte) (map[string]any, error) {
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+
+	var value any
+	if err := dec.Decode(&value); err != nil {
+		return nil, err
+	}
+
+	flat := make(map[string]any)
+	flatten("", value, flat)
+
+	return flat, nil
+}
+
+// record counts a shadow read, failed if the shadow answered with a server error
+func (s *shadowReader) record(key shadowKey, fields []string, delta time.Duration, failed bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stats := s.statsFor(key)
+	stats.requests++
+
+	if failed {
+		stats.errors++
+	}
+
+	if len(fields) > 0 {
+		stats.mismatches++
+		for _, field := range fields {
+			stats.fields[indexRX.ReplaceAllString(field, "")]++
+		}
+	}
+
+	if len(stats.deltas) < shadowDeltas {
+		stats.deltas = append(stats.deltas, delta)
+	} else {
+		stats.deltas[stats.next] = delta
+		stats.next = (stats.next + 1) % shadowDeltas
+	}
+}
+
+// failed counts a shadow read that did not produce a reply
+func (s *shadowReader) failed(key shadowKey) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stats := s.statsFor(key)
+	stats.requests++
+	stats.errors++
+}
+
+func (s *shadowReader) statsFor(key shadowKey) *shadowStats {
+	stats, ok := s.stats[key]
+	if !ok {
+		stats = &shadowStats{fields: make(map[string]int64)}
+		s.stats[key] = stats
+	}
+	return stats
+}
+
+// shadowComparison summarises the shadow reads of one primary strategy against another
+type shadowComparison struct {
+	Primary      string           `json:"primary"`
+	Shadow       string           `json:"shadow"`
+	Version      string           `json:"version"`
+	Resource     string           `json:"resource"`
+	Requests     int64            `json:"requests"`
+	Mismatches   int64            `json:"mismatches"`
+	MismatchRate float64          `json:"mismatch_rate"`
+	Errors       int64            `json:"errors"`
+	Fields       map[string]int64 `json:"fields"`
+	LatencyDelta latency.Summary  `json:"latency_delta"` // shadow minus primary, negative if faster
+}
+
+// snapshot returns the comparisons ordered by version, resource, primary and shadow strategy
+func (s *shadowReader) snapshot() (comparisons []shadowComparison, dropped int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	comparisons = []shadowComparison{}
+
+	for key, stats := range s.stats {
+		c := shadowComparison{
+			Primary:      key.primary,
+			Shadow:       key.shadow,
+			Version:      key.version,
+			Resource:     key.resource,
+			Requests:     stats.requests,
+			Mismatches:   stats.mismatches,
+			Errors:       stats.errors,
+			Fields:       maps.Clone(stats.fields),
+			LatencyDelta: latency.Summarize(stats.deltas),
+		}
+		if stats.requests > 0 {
+			c.MismatchRate = float64(stats.mismatches) / float64(stats.requests)
+		}
+
+		comparisons = append(comparisons, c)
+	}
+
+	slices.SortFunc(comparisons, func(a, b shadowComparison) int {
+		return strings.Compare(
+			strings.Join([]string{a.Version, a.Resource, a.Primary, a.Shadow}, "/"),
+			strings.Join([]string{b.Version, b.Resource, b.Primary, b.Shadow}, "/"),
+		)
+	})
+
+	return comparisons, s.dropped
+}
+
+// shadowHandler reports the mismatches and latency deltas of the shadow reads so far
+func (app *application) shadowHandler(w http.ResponseWriter, r *http.Request) {
+	env := util.Envelope{"enabled": app.shadow != nil}
+
+	if app.shadow != nil {
+		comparisons, dropped := app.shadow.snapshot()
+
+		env["rules"] = app.shadow.rules
+		env["dropped"] = dropped
+		env["comparisons"] = comparisons
+	}
+
+	err := util.WriteJSON(w, http.StatusOK, util.Envelope{"shadow": env}, nil)
+	if err != nil {
+		app.errors.ServerErrorResponse(w, r, err)
+	}
+}
+
+// teeWriter passes the primary reply through while keeping a copy of its status and body
+type teeWriter struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+	truncated   bool
+}
+
+func (t *teeWriter) WriteHeader(status int) {
+	if !t.wroteHeader {
+		t.status = status
+		t.wroteHeader = true
+	}
+	t.ResponseWriter.WriteHeader(status)
+}
+
+func (t *teeWriter) Write(b []byte) (int, error) {
+	t.wroteHeader = true
+
+	if !t.truncated {
+		if t.body.Len()+len(b) > shadowMaxBody {
+			t.truncated = true
+			t.body.Reset()
+		} else {
+			t.body.Write(b)
+		}
+	}
+
+	return t.ResponseWriter.Write(b)
+}
@@ -0,0 +1,338 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/rand"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"thesis.lefler.eu/internal/latency"
+	"thesis.lefler.eu/internal/workload"
+	"thesis.lefler.eu/migrations"
+)
+
+const usage = `Usage: bench [flags]
+
+Benchmarks the Create, Get, List, Update and Delete endpoints of every resource of every
+version against a running API, once per strategy. Run the API against local PostgreSQL
+databases migrated to the latest version, e.g.
+
+  go run ./cmd/migrate up
+  go run ./cmd/api &
+  go run ./cmd/bench -requests 500 -concurrency 4
+
+Writes report.json, report.csv and summary.md to the output directory.
+
+Flags:
+`
+
+type config struct {
+	url         string
+	strategy    string
+	versions    string
+	requests    int
+	concurrency int
+	warmup      int
+	seed        int64
+	timeout     time.Duration
+	output      string
+}
+
+// operations in the order they are run, the records created are used by the later ones
+var operations = []string{"create", "get", "list", "update", "delete"}
+
+type bench struct {
+	cfg    config
+	client *http.Client
+	logger *slog.Logger
+	rand   *rand.Rand
+	mu     sync.Mutex // guards rand
+}
+
+func main() {
+	var cfg config
+
+	flag.StringVar(&cfg.url, "url", "http://localhost:4000", "Base URL of the API")
+	flag.StringVar(&cfg.strategy, "strategy", "all", "Strategy to benchmark (views|expand_deprecate|branches|all)")
+	flag.StringVar(&cfg.versions, "versions", "v1,v2,v3,v4,v5", "Comma separated versions to benchmark")
+	flag.IntVar(&cfg.requests, "requests", 100, "Requests per operation and endpoint")
+	flag.IntVar(&cfg.concurrency, "concurrency", 1, "Number of requests in flight at once")
+	flag.IntVar(&cfg.warmup, "warmup", 10, "Unmeasured list requests before each endpoint")
+	flag.Int64Var(&cfg.seed, "seed", 1, "Seed of the request bodies")
+	flag.DurationVar(&cfg.timeout, "timeout", 10*time.Second, "Timeout of a single request")
+	flag.StringVar(&cfg.output, "o", "bench", "Directory the reports are written to")
+
+	flag.Usage = func() {
+		fmt.Fprint(flag.CommandLine.Output(), usage)
+		flag.PrintDefaults()
+	}
+
+	flag.Parse()
+
+	logger := slog.New(slog.NewTextHandler(os.Stderr, nil))
+
+	if cfg.requests < 1 || cfg.concurrency < 1 || cfg.warmup < 0 {
+		logger.Error("-requests and -concurrency must be at least 1, -warmup must not be negative")
+		os.Exit(2)
+	}
+
+	strategies := migrations.Strategies
+	if cfg.strategy != "all" {
+		if _, ok := migrations.Dirs[cfg.strategy]; !ok {
+			logger.Error("unknown strategy", "strategy", cfg.strategy)
+			os.Exit(2)
+		}
+		strategies = []string{cfg.strategy}
+	}
+
+	var versions []string
+	for _, version := range strings.Split(cfg.versions, ",") {
+		version = strings.TrimSpace(version)
+		if _, ok := workload.Resources[version]; !ok {
+			logger.Error("unknown version", "version", version)
+			os.Exit(2)
+		}
+		versions = append(versions, version)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	b := &bench{
+		cfg:    cfg,
+		client: &http.Client{Timeout: cfg.timeout},
+		logger: logger,
+		rand:   rand.New(rand.NewSource(cfg.seed)),
+	}
+
+	r := report{
+		URL:         cfg.url,
+		Requests:    cfg.requests,
+		Concurrency: cfg.concurrency,
+		Seed:        cfg.seed,
+		Started:     time.Now().UTC(),
+		Strategies:  strategies,
+		Rows:        []row{},
+	}
+
+	for _, strategy := range strategies {
+		for _, version := range versions {
+			for _, resource := range workload.Resources[version] {
+				if ctx.Err() != nil {
+					break
+				}
+
+				rows := b.endpoint(ctx, strategy, version, resource)
+				r.Rows = append(r.Rows, rows...)
+			}
+		}
+	}
+
+	if ctx.Err() != nil {
+		logger.Warn("interrupted, reporting the endpoints benchmarked so far")
+	}
+
+	err := r.write(cfg.output)
+	if err != nil {
+		logger.Error(err.Error())
+		os.Exit(1)
+	}
+
+	logger.Info("wrote reports", "dir", cfg.output, "files", strings.Join([]string{
+		filepath.Join(cfg.output, "report.json"),
+		filepath.Join(cfg.output, "report.csv"),
+		filepath.Join(cfg.output, "summary.md"),
+	}, ", "))
+}
+
+// endpoint benchmarks every operation of a resource, the records it creates are deleted again
+func (b *bench) endpoint(ctx context.Context, strategy string, version string, resource string) []row {
+	base := strings.TrimSuffix(b.cfg.url, "/") + fmt.Sprintf("/%s/%s/%s", strategy, version, resource)
+
+	for i := 0; i < b.cfg.warmup; i++ {
+		b.send(ctx, http.MethodGet, base, nil)
+	}
+
+	var rows []row
+	var ids []string
+	var idsMu sync.Mutex
+
+	for _, operation := range operations {
+		measure := func(i int) (result, error) {
+			idsMu.Lock()
+			var id string
+			if len(ids) > 0 {
+				id = ids[i%len(ids)]
+			}
+			idsMu.Unlock()
+
+			switch operation {
+			case "create":
+				return b.send(ctx, http.MethodPost, base, b.body(version, resource))
+			case "list":
+				return b.send(ctx, http.MethodGet, base, nil)
+			}
+
+			if id == "" {
+				return result{}, fmt.Errorf("no %s created to %s", resource, operation)
+			}
+
+			switch operation {
+			case "get":
+				return b.send(ctx, http.MethodGet, base+"/"+id, nil)
+			case "update":
+				return b.send(ctx, http.MethodPatch, base+"/"+id, b.body(version, resource))
+			default:
+				return b.send(ctx, http.MethodDelete, base+"/"+id, nil)
+			}
+		}
+
+		expected := http.StatusOK
+		if operation == "create" {
+			expected = http.StatusCreated
+		}
+
+		requests := b.cfg.requests
+		if operation == "delete" && len(ids) > 0 {
+			// every record is deleted once
+			requests = len(ids)
+		}
+
+		onCreated := func(body []byte) {
+			if id := workload.CreatedID(body); id != "" {
+				idsMu.Lock()
+				ids = append(ids, id)
+				idsMu.Unlock()
+			}
+		}
+		if operation != "create" {
+			onCreated = nil
+		}
+
+		rw := b.run(requests, expected, measure, onCreated)
+		rw.Strategy, rw.Version, rw.Resource, rw.Operation = strategy, version, resource, operation
+		rows = append(rows, rw)
+
+		b.logger.Info("benchmarked", "strategy", strategy, "version", version, "resource", resource, "operation", operation,
+			"p50_ms", rw.Latency.P50, "p99_ms", rw.Latency.P99, "throughput_rps", rw.Throughput, "errors", rw.Errors)
+	}
+
+	return rows
+}
+
+// run sends n requests with up to cfg.concurrency in flight, a request is an error if it
+// fails or its status is not expected
+func (b *bench) run(n int, expected int, request func(i int) (result, error), onSuccess func(body []byte)) row {
+	var (
+		mu        sync.Mutex
+		durations []time.Duration
+		errors    int
+	)
+
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+
+	start := time.Now()
+
+	for w := 0; w < b.cfg.concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				res, err := request(i)
+				ok := err == nil && res.status == expected
+
+				mu.Lock()
+				if ok {
+					durations = append(durations, res.latency)
+				} else {
+					errors++
+				}
+				mu.Unlock()
+
+				if ok && onSuccess != nil {
+					onSuccess(res.body)
+				}
+			}
+		}()
+	}
+
+	for i := 0; i < n; i++ {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	wall := time.Since(start)
+
+	rw := row{
+		Requests: n,
+		Errors:   errors,
+		Latency:  latency.Summarize(durations),
+	}
+	rw.ErrorRate = float64(errors) / float64(n)
+	if wall > 0 {
+		rw.Throughput = float64(len(durations)) / wall.Seconds()
+	}
+
+	return rw
+}
+
+// result of a single request
+type result struct {
+	status  int
+	body    []byte
+	latency time.Duration
+}
+
+func (b *bench) send(ctx context.Context, method string, url string, body []byte) (result, error) {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, url, reader)
+	if err != nil {
+		return result{}, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	start := time.Now()
+
+	res, err := b.client.Do(req)
+	if err != nil {
+		return result{}, err
+	}
+	defer res.Body.Close()
+
+	// the body is read in full, so the latency includes writing the response
+	data, err := io.ReadAll(res.Body)
+
+	return result{status: res.StatusCode, body: data, latency: time.Since(start)}, err
+}
+
+// body returns a valid body for creating or updating a record, movies are created without a cast
+func (b *bench) body(version string, resource string) []byte {
+	b.mu.Lock()
+	fields := workload.Fields(b.rand, version, resource)
+	b.mu.Unlock()
+
+	body, err := json.Marshal(fields)
+	if err != nil {
+		panic(err)
+	}
+	return body
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"thesis.lefler.eu/internal/latency"
+)
+
+// row holds the results of one operation on one endpoint of a strategy
+type row struct {
+	Strategy   string          `json:"strategy"`
+	Version    string          `json:"version"`
+	Resource   string          `json:"resource"`
+	Operation  string          `json:"operation"`
+	Requests   int             `json:"requests"`
+	Errors     int             `json:"errors"`
+	ErrorRate  float64         `json:"error_rate"`
+	Throughput float64         `json:"throughput_rps"` // successful requests per second
+	Latency    latency.Summary `json:"latency"`        // of successful requests
+}
+
+type report struct {
+	URL         string    `json:"url"`
+	Requests    int       `json:"requests"` // per operation and endpoint
+	Concurrency int       `json:"concurrency"`
+	Seed        int64     `json:"seed"`
+	Started     time.Time `json:"started"`
+	Strategies  []string  `json:"strategies"`
+	Rows        []row     `json:"rows"`
+}
+
+func (r report) write(dir string) error {
+	err := os.MkdirAll(dir, 0o755)
+	if err != nil {
+		return err
+	}
+
+	for name, write := range map[string]func(f *os.File) error{
+		"report.json": r.writeJSON,
+		"report.csv":  r.writeCSV,
+		"summary.md":  r.writeMarkdown,
+	} {
+		f, err := os.Create(filepath.Join(dir, name))
+		if err != nil {
+			return err
+		}
+
+		err = write(f)
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+	}
+
+	return nil
+}
+
+func (r report) writeJSON(f *os.File) error {
+	enc := json.NewEncoder(f)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+func (r report) writeCSV(f *os.File) error {
+	w := csv.NewWriter(f)
+
+	err := w.Write([]string{"strategy", "version", "resource", "operation", "requests", "errors", "error_rate", "throughput_rps", "mean_ms", "p50_ms", "p95_ms", "p99_ms", "max_ms"})
+	if err != nil {
+		return err
+	}
+
+	for _, rw := range r.Rows {
+		err = w.Write([]string{
+			rw.Strategy,
+			rw.Version,
+			rw.Resource,
+			rw.Operation,
+			strconv.Itoa(rw.Requests),
+			strconv.Itoa(rw.Errors),
+			formatFloat(rw.ErrorRate),
+			formatFloat(rw.Throughput),
+			formatFloat(rw.Latency.Mean),
+			formatFloat(rw.Latency.P50),
+			formatFloat(rw.Latency.P95),
+			formatFloat(rw.Latency.P99),
+			formatFloat(rw.Latency.Max),
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	w.Flush()
+	return w.Error()
+}
+
+// writeMarkdown writes a table per metric, with a row per endpoint and operation and a
+// column per strategy
+func (r report) writeMarkdown(f *os.File) error {
+	var sb strings.Builder
+
+	fmt.Fprintf(&sb, "# Strategy benchmark\n\n")
+	fmt.Fprintf(&sb, "%d requests per operation and endpoint, concurrency %d, seed %d, against %s on %s.\n",
+		r.Requests, r.Concurrency, r.Seed, r.URL, r.Started.Format(time.RFC3339))
+
+	type key struct {
+		version, resource, operation string
+	}
+
+	var keys []key
+	rows := make(map[key]map[string]row) // by strategy
+	for _, rw := range r.Rows {
+		k := key{rw.Version, rw.Resource, rw.Operation}
+		if _, ok := rows[k]; !ok {
+			keys = append(keys, k)
+			rows[k] = make(map[string]row)
+		}
+		rows[k][rw.Strategy] = rw
+	}
+
+	table := func(title string, cell func(rw row) string) {
+		fmt.Fprintf(&sb, "\n## %s\n\n", title)
+		fmt.Fprintf(&sb, "| Version | Resource | Operation | %s |\n", strings.Join(r.Strategies, " | "))
+		fmt.Fprintf(&sb, "|---|---|---|%s\n", strings.Repeat("---:|", len(r.Strategies)))
+
+		for _, k := range keys {
+			fmt.Fprintf(&sb, "| %s | %s | %s |", k.version, k.resource, k.operation)
+			for _, strategy := range r.Strategies {
+				rw, ok := rows[k][strategy]
+				if !ok {
+					fmt.Fprint(&sb, " - |")
+					continue
+				}
+				fmt.Fprintf(&sb, " %s |", cell(rw))
+			}
+			fmt.Fprintln(&sb)
+		}
+	}
+
+	table("Latency p50 / p95 / p99 (ms)", func(rw row) string {
+		return fmt.Sprintf("%.2f / %.2f / %.2f", rw.Latency.P50, rw.Latency.P95, rw.Latency.P99)
+	})
+	table("Throughput (requests/s)", func(rw row) string {
+		return fmt.Sprintf("%.1f", rw.Throughput)
+	})
+	table("Error rate", func(rw row) string {
+		return fmt.Sprintf("%.1f%%", rw.ErrorRate*100)
+	})
+
+	_, err := f.WriteString(sb.String())
+	return err
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', 3, 64)
+}
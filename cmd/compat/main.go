@@ -0,0 +1,136 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"thesis.lefler.eu/internal/compat"
+	"thesis.lefler.eu/internal/data"
+	"thesis.lefler.eu/migrations"
+)
+
+const usage = `Usage: compat [flags]
+
+Compares the resource schemas of consecutive API versions, or of -from and -to, and writes
+a JSON compatibility report to stdout. Exits with status 1 if a breaking change is not
+declared in the -declared file.
+
+Flags:
+`
+
+type config struct {
+	strategy string
+	from     string
+	to       string
+	declared string
+}
+
+type report struct {
+	Compatible  bool                `json:"compatible"` // false if a breaking change is undeclared
+	Breaking    int                 `json:"breaking"`
+	Undeclared  int                 `json:"undeclared"`
+	Comparisons []compat.Comparison `json:"comparisons"`
+}
+
+func main() {
+	var cfg config
+
+	flag.StringVar(&cfg.strategy, "strategy", "all", "Strategy to compare (views|expand_deprecate|branches|all)")
+	flag.StringVar(&cfg.from, "from", "", "Older version, e.g. v4 (default every pair of consecutive versions)")
+	flag.StringVar(&cfg.to, "to", "", "Newer version, e.g. v5")
+	flag.StringVar(&cfg.declared, "declared", "breaking_changes.json", "JSON file of intended breaking changes")
+
+	flag.Usage = func() {
+		fmt.Fprint(flag.CommandLine.Output(), usage)
+		flag.PrintDefaults()
+	}
+
+	flag.Parse()
+
+	logger := slog.New(slog.NewTextHandler(os.Stderr, nil))
+
+	if (cfg.from == "") != (cfg.to == "") {
+		logger.Error("-from and -to must be set together")
+		os.Exit(2)
+	}
+
+	schemas := data.Schemas()
+
+	strategies := migrations.Strategies
+	if cfg.strategy != "all" {
+		if _, ok := schemas[cfg.strategy]; !ok {
+			logger.Error("unknown strategy", "strategy", cfg.strategy)
+			os.Exit(2)
+		}
+		strategies = []string{cfg.strategy}
+	}
+
+	declarations, err := compat.LoadDeclarations(cfg.declared, isFlagSet("declared"))
+	if err != nil {
+		logger.Error(err.Error(), "declared", cfg.declared)
+		os.Exit(2)
+	}
+
+	r := report{Comparisons: []compat.Comparison{}}
+
+	for _, strategy := range strategies {
+		versions := schemas[strategy]
+
+		pairs := compat.Consecutive(versions)
+		if cfg.from != "" {
+			for _, version := range []string{cfg.from, cfg.to} {
+				if _, ok := versions[version]; !ok {
+					logger.Error("unknown version", "strategy", strategy, "version", version)
+					os.Exit(2)
+				}
+			}
+			pairs = [][2]string{{cfg.from, cfg.to}}
+		}
+
+		for _, pair := range pairs {
+			r.Comparisons = append(r.Comparisons, compat.Compare(strategy, pair[0], pair[1], versions)...)
+		}
+	}
+
+	r.Undeclared = compat.Declare(r.Comparisons, declarations)
+	r.Compatible = r.Undeclared == 0
+
+	for _, c := range r.Comparisons {
+		for _, change := range c.Changes {
+			if !change.Breaking {
+				continue
+			}
+
+			r.Breaking++
+			if !change.Declared {
+				logger.Warn("undeclared breaking change", "strategy", c.Strategy, "from", c.From, "to", c.To, "resource", c.Resource, "kind", change.Kind, "path", change.Path)
+			}
+		}
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+
+	err = enc.Encode(r)
+	if err != nil {
+		logger.Error(err.Error())
+		os.Exit(2)
+	}
+
+	if !r.Compatible {
+		os.Exit(1)
+	}
+}
+
+func isFlagSet(name string) bool {
+	set := false
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	return set
+}
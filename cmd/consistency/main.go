@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	"thesis.lefler.eu/internal/consistency"
+	"thesis.lefler.eu/internal/data"
+	"thesis.lefler.eu/internal/repository"
+	"thesis.lefler.eu/migrations"
+
+	_ "github.com/lib/pq"
+)
+
+const usage = `Usage: consistency [flags]
+
+Reads every movie, with its crew, and every person through the v5 models of each strategy,
+normalises them into one canonical form and compares them between the strategies. Movies
+are matched by title and year, people by name and birthdate, as ids of people created by
+migrations differ between strategies.
+
+Writes a JSON report of every record missing in a strategy and every field that differs to
+stdout, and exits with status 1 if there is a difference.
+
+Flags:
+`
+
+type config struct {
+	db struct {
+		dsn struct {
+			views           string
+			expandDeprecate string
+			branches        string
+		}
+	}
+}
+
+func main() {
+	var cfg config
+
+	flag.StringVar(&cfg.db.dsn.views, "db-dsn-views", os.Getenv("VIEWS_DB_DSN"), "PostgreSQL DSN for Views method")
+	flag.StringVar(&cfg.db.dsn.expandDeprecate, "db-dsn-expand-deprecate", os.Getenv("EXPAND_DEPRECATE_DB_DSN"), "PostgreSQL DSN for Expand & Deprecate method")
+	flag.StringVar(&cfg.db.dsn.branches, "db-dsn-branches", os.Getenv("BRANCHES_DB_DSN"), "PostgreSQL DSN for Branches method")
+
+	flag.Usage = func() {
+		fmt.Fprint(flag.CommandLine.Output(), usage)
+		flag.PrintDefaults()
+	}
+
+	flag.Parse()
+
+	logger := slog.New(slog.NewTextHandler(os.Stderr, nil))
+
+	dsns := map[string]string{
+		"views":            cfg.db.dsn.views,
+		"expand_deprecate": cfg.db.dsn.expandDeprecate,
+		"branches":         cfg.db.dsn.branches,
+	}
+
+	var conns data.DbConns
+	for _, strategy := range migrations.Strategies {
+		db, err := openDB(dsns[strategy], strategy)
+		if err != nil {
+			logger.Error(err.Error(), "strategy", strategy)
+			os.Exit(2)
+		}
+		defer db.Close()
+
+		switch strategy {
+		case "views":
+			conns.Views = db
+		case "expand_deprecate":
+			conns.ExpandDeprecate = db
+		case "branches":
+			conns.Branches = db
+		}
+	}
+
+	models := data.NewModels(conns)
+
+	repos := map[string]repository.Repositories{
+		"views":            models.Views,
+		"expand_deprecate": models.ExpandDeprecate,
+		"branches":         models.Branches,
+	}
+
+	snapshots := make(map[string]consistency.Snapshot)
+	for _, strategy := range migrations.Strategies {
+		snapshot, err := consistency.Read(repos[strategy].V5)
+		if err != nil {
+			logger.Error(err.Error(), "strategy", strategy)
+			os.Exit(2)
+		}
+		snapshots[strategy] = snapshot
+	}
+
+	r := consistency.Compare(snapshots, migrations.Strategies)
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+
+	err := enc.Encode(r)
+	if err != nil {
+		logger.Error(err.Error())
+		os.Exit(2)
+	}
+
+	if !r.Consistent {
+		logger.Warn("strategies differ", "differences", len(r.Differences), "by_field", r.Summary())
+		os.Exit(1)
+	}
+}
+
+func openDB(dsn string, method string) (*sql.DB, error) {
+	if dsn == "" {
+		return nil, fmt.Errorf("missing %s DSN", method)
+	}
+
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	err = db.PingContext(ctx)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return db, nil
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"text/tabwriter"
+
+	"thesis.lefler.eu/internal/migration"
+)
+
+// costReport compares the recorded costs of the up migrations between strategies
+type costReport struct {
+	Strategies []string                    `json:"strategies"`
+	Migrations map[string][]migration.Cost `json:"migrations"` // latest successful measurement per file
+	Versions   []versionCost               `json:"versions"`
+}
+
+// versionCost sums the costs of the migrations introducing an API version, per strategy
+type versionCost struct {
+	Version    string               `json:"version"`
+	Strategies map[string]phaseCost `json:"strategies"`
+}
+
+type phaseCost struct {
+	Migrations   int     `json:"migrations"`
+	Duration     float64 `json:"duration_ms"`
+	RowsAffected int64   `json:"rows_affected"`
+	Exclusive    float64 `json:"exclusive_lock_ms"`
+	MaxBlocked   int     `json:"max_blocked"`
+	SizeBefore   int64   `json:"size_before"`
+	SizeAfter    int64   `json:"size_after"`
+}
+
+// loadCosts reads the costs recorded in the database of strategy
+func loadCosts(ctx context.Context, strategy string, dsn string) ([]migration.Cost, error) {
+	db, err := openDB(dsn, strategy)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	return migration.LoadCosts(ctx, db)
+}
+
+func newCostReport(costs map[string][]migration.Cost, strategies []string) costReport {
+	r := costReport{
+		Strategies: strategies,
+		Migrations: make(map[string][]migration.Cost),
+		Versions:   []versionCost{},
+	}
+
+	byVersion := make(map[string]map[string]phaseCost)
+
+	for _, strategy := range strategies {
+		latest := make(map[int64]migration.Cost)
+		for _, cost := range costs[strategy] {
+			if cost.Direction == "up" && cost.Error == "" {
+				latest[cost.Version] = cost
+			}
+		}
+
+		measured := []migration.Cost{}
+		for _, cost := range latest {
+			measured = append(measured, cost)
+		}
+		slices.SortFunc(measured, func(a, b migration.Cost) int { return cmp.Compare(a.Version, b.Version) })
+
+		r.Migrations[strategy] = measured
+
+		for _, cost := range measured {
+			// the files are numbered by the API version they introduce, e.g. 00031_v3_migration.sql
+			version := fmt.Sprintf("v%d", cost.Version/10)
+
+			if byVersion[version] == nil {
+				byVersion[version] = make(map[string]phaseCost)
+			}
+
+			phase, ok := byVersion[version][strategy]
+			if !ok {
+				phase.SizeBefore = cost.SizeBefore
+			}
+			phase.Migrations++
+			phase.Duration += cost.Duration
+			phase.RowsAffected += cost.RowsAffected
+			phase.Exclusive += cost.Exclusive
+			phase.MaxBlocked = max(phase.MaxBlocked, cost.MaxBlocked)
+			phase.SizeAfter = cost.SizeAfter
+
+			byVersion[version][strategy] = phase
+		}
+	}
+
+	for version, phases := range byVersion {
+		r.Versions = append(r.Versions, versionCost{Version: version, Strategies: phases})
+	}
+	slices.SortFunc(r.Versions, func(a, b versionCost) int { return cmp.Compare(a.Version, b.Version) })
+
+	return r
+}
+
+func (r costReport) writeJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+func (r costReport) writeText(w io.Writer) error {
+	for _, strategy := range r.Strategies {
+		fmt.Fprintf(w, "%s\n", strategy)
+
+		if len(r.Migrations[strategy]) == 0 {
+			fmt.Fprintf(w, "    no measured migrations, run migrate -measure up\n\n")
+			continue
+		}
+
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "    Migration\tDuration\tRows\tExclusive Lock\tMax Blocked\tSize Before\tSize After")
+
+		for _, cost := range r.Migrations[strategy] {
+			fmt.Fprintf(tw, "    %s\t%.1f ms\t%d\t%.1f ms\t%d\t%s\t%s\n",
+				cost.Name, cost.Duration, cost.RowsAffected, cost.Exclusive, cost.MaxBlocked, bytes(cost.SizeBefore), bytes(cost.SizeAfter))
+		}
+
+		err := tw.Flush()
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(w)
+	}
+
+	fmt.Fprintf(w, "per version\n")
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "    Version\tStrategy\tMigrations\tDuration\tRows\tExclusive Lock\tMax Blocked\tSize Change")
+
+	for _, version := range r.Versions {
+		for _, strategy := range r.Strategies {
+			phase, ok := version.Strategies[strategy]
+			if !ok {
+				continue
+			}
+
+			fmt.Fprintf(tw, "    %s\t%s\t%d\t%.1f ms\t%d\t%.1f ms\t%d\t%s\n",
+				version.Version, strategy, phase.Migrations, phase.Duration, phase.RowsAffected, phase.Exclusive, phase.MaxBlocked, change(phase.SizeAfter-phase.SizeBefore))
+		}
+	}
+
+	return tw.Flush()
+}
+
+func printCosts(r costReport, asJSON bool) error {
+	if asJSON {
+		return r.writeJSON(os.Stdout)
+	}
+	return r.writeText(os.Stdout)
+}
+
+// bytes formats a size the way pg_size_pretty does
+func bytes(n int64) string {
+	units := []string{"bytes", "kB", "MB", "GB", "TB"}
+
+	size := float64(n)
+	unit := 0
+	for (size >= 10240 || size <= -10240) && unit < len(units)-1 {
+		size /= 1024
+		unit++
+	}
+
+	if unit == 0 {
+		return fmt.Sprintf("%d %s", n, units[0])
+	}
+	return fmt.Sprintf("%.0f %s", size, units[unit])
+}
+
+func change(n int64) string {
+	if n > 0 {
+		return "+" + bytes(n)
+	}
+	return bytes(n)
+}
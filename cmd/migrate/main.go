@@ -0,0 +1,262 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"thesis.lefler.eu/internal/migration"
+	"thesis.lefler.eu/migrations"
+
+	_ "github.com/lib/pq"
+)
+
+const usage = `Usage: migrate [flags] <command> [version]
+
+Commands:
+  up           apply all pending migrations
+  up-to N      apply all pending migrations up to and including version N
+  down         roll back the most recently applied migration
+  redo         roll back the most recently applied migration and apply it again
+  status       list all migrations and whether they have been applied
+  version      print the currently applied version
+  costs        compare the measured costs of the up migrations between strategies
+
+With -measure, up, up-to, down and redo record the wall-clock time, rows affected,
+locks sampled from pg_locks and table sizes before and after every migration in
+the migration_costs table of the migrated database.
+
+Flags:
+`
+
+type config struct {
+	strategy string
+	measure  bool
+	interval time.Duration
+	json     bool
+	db       struct {
+		dsn struct {
+			views           string
+			expandDeprecate string
+			branches        string
+		}
+	}
+}
+
+func main() {
+	var cfg config
+
+	flag.StringVar(&cfg.strategy, "strategy", "all", "Strategy to migrate (views|expand_deprecate|branches|all)")
+
+	flag.BoolVar(&cfg.measure, "measure", false, "Measure the cost of every migration run")
+	flag.DurationVar(&cfg.interval, "sample-interval", migration.DefaultSampleInterval, "Interval between two samples of pg_locks while measuring")
+	flag.BoolVar(&cfg.json, "json", false, "Print the costs report as JSON")
+
+	flag.StringVar(&cfg.db.dsn.views, "db-dsn-views", os.Getenv("VIEWS_DB_DSN"), "PostgreSQL DSN for Views method")
+	flag.StringVar(&cfg.db.dsn.expandDeprecate, "db-dsn-expand-deprecate", os.Getenv("EXPAND_DEPRECATE_DB_DSN"), "PostgreSQL DSN for Expand & Deprecate method")
+	flag.StringVar(&cfg.db.dsn.branches, "db-dsn-branches", os.Getenv("BRANCHES_DB_DSN"), "PostgreSQL DSN for Branches method")
+
+	flag.Usage = func() {
+		fmt.Fprint(flag.CommandLine.Output(), usage)
+		flag.PrintDefaults()
+	}
+
+	flag.Parse()
+
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	if flag.NArg() < 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	command := flag.Arg(0)
+
+	switch command {
+	case "up", "up-to", "down", "redo", "status", "version", "costs":
+	default:
+		logger.Error("unknown command", "command", command)
+		os.Exit(2)
+	}
+
+	var target int64
+	if command == "up-to" {
+		if flag.NArg() != 2 {
+			flag.Usage()
+			os.Exit(2)
+		}
+
+		var err error
+		target, err = strconv.ParseInt(flag.Arg(1), 10, 64)
+		if err != nil {
+			logger.Error("invalid version", "version", flag.Arg(1))
+			os.Exit(2)
+		}
+	}
+
+	strategies := migrations.Strategies
+	if cfg.strategy != "all" {
+		if _, ok := migrations.Dirs[cfg.strategy]; !ok {
+			logger.Error("unknown strategy", "strategy", cfg.strategy)
+			os.Exit(2)
+		}
+		strategies = []string{cfg.strategy}
+	}
+
+	dsns := map[string]string{
+		"views":            cfg.db.dsn.views,
+		"expand_deprecate": cfg.db.dsn.expandDeprecate,
+		"branches":         cfg.db.dsn.branches,
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	if command == "costs" {
+		costs := make(map[string][]migration.Cost)
+		for _, strategy := range strategies {
+			var err error
+			costs[strategy], err = loadCosts(ctx, strategy, dsns[strategy])
+			if err != nil {
+				logger.Error(err.Error(), "strategy", strategy, "command", command)
+				os.Exit(1)
+			}
+		}
+
+		err := printCosts(newCostReport(costs, strategies), cfg.json)
+		if err != nil {
+			logger.Error(err.Error())
+			os.Exit(1)
+		}
+		return
+	}
+
+	for _, strategy := range strategies {
+		err := run(ctx, logger, cfg, strategy, dsns[strategy], command, target)
+		if err != nil {
+			logger.Error(err.Error(), "strategy", strategy, "command", command)
+			os.Exit(1)
+		}
+	}
+}
+
+func run(ctx context.Context, logger *slog.Logger, cfg config, strategy string, dsn string, command string, target int64) error {
+	files, err := migration.Load(migrations.FS, migrations.Dirs[strategy])
+	if err != nil {
+		return err
+	}
+
+	db, err := openDB(dsn, strategy)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	migrator := migration.New(db, files)
+	migrator.Measure = cfg.measure
+	migrator.SampleInterval = cfg.interval
+
+	// the costs are logged also if a migration fails, they are recorded for failed ones too
+	defer func() {
+		for _, cost := range migrator.Costs {
+			logger.Info("measured migration", "strategy", strategy, "name", cost.Name, "direction", cost.Direction,
+				"duration_ms", cost.Duration, "rows_affected", cost.RowsAffected, "exclusive_lock_ms", cost.Exclusive,
+				"max_blocked", cost.MaxBlocked, "size_before", cost.SizeBefore, "size_after", cost.SizeAfter)
+		}
+	}()
+
+	switch command {
+	case "up", "up-to":
+		var applied []*migration.Migration
+		if command == "up" {
+			applied, err = migrator.Up(ctx)
+		} else {
+			applied, err = migrator.UpTo(ctx, target)
+		}
+
+		for _, m := range applied {
+			logger.Info("applied migration", "strategy", strategy, "version", m.Version, "name", m.Name)
+		}
+		if err != nil {
+			return err
+		}
+
+		if len(applied) == 0 {
+			logger.Info("no migrations to apply", "strategy", strategy)
+		}
+
+	case "down", "redo":
+		var m *migration.Migration
+		if command == "down" {
+			m, err = migrator.Down(ctx)
+		} else {
+			m, err = migrator.Redo(ctx)
+		}
+		if err != nil {
+			return err
+		}
+
+		logger.Info(fmt.Sprintf("%s migration", command), "strategy", strategy, "version", m.Version, "name", m.Name)
+
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("%s\n", strategy)
+
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "    Applied At\tMigration")
+
+		for _, status := range statuses {
+			appliedAt := "Pending"
+			if status.Applied {
+				appliedAt = status.AppliedAt.Format(time.DateTime)
+			}
+			fmt.Fprintf(tw, "    %s\t%s\n", appliedAt, status.Migration.Name)
+		}
+
+		tw.Flush()
+		fmt.Println()
+
+	case "version":
+		version, err := migrator.Version(ctx)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("%s\t%d\n", strategy, version)
+	}
+
+	return nil
+}
+
+func openDB(dsn string, method string) (*sql.DB, error) {
+	if dsn == "" {
+		return nil, fmt.Errorf("missing %s DSN", method)
+	}
+
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	err = db.PingContext(ctx)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return db, nil
+}
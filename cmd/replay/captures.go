@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sync"
+)
+
+// captures holds the ids of the records created by entries with capture set, so later
+// entries can reference them. Ids are kept per repetition of the workload.
+type captures struct {
+	mu    sync.Mutex
+	ids   map[string]string
+	ready map[string]chan struct{}
+}
+
+func newCaptures() *captures {
+	return &captures{
+		ids:   make(map[string]string),
+		ready: make(map[string]chan struct{}),
+	}
+}
+
+func captureKey(name string, iteration int) string {
+	return fmt.Sprintf("%s#%d", name, iteration)
+}
+
+func (c *captures) channel(key string) chan struct{} {
+	ch, ok := c.ready[key]
+	if !ok {
+		ch = make(chan struct{})
+		c.ready[key] = ch
+	}
+	return ch
+}
+
+// set records the id of a capture, an empty id marks the capture as failed
+func (c *captures) set(name string, iteration int, id string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key := captureKey(name, iteration)
+	if _, done := c.ids[key]; done {
+		return
+	}
+
+	c.ids[key] = id
+	close(c.channel(key))
+}
+
+// get waits until the entry capturing name has been replayed and returns the captured id
+func (c *captures) get(ctx context.Context, name string, iteration int) (string, error) {
+	key := captureKey(name, iteration)
+
+	c.mu.Lock()
+	ch := c.channel(key)
+	c.mu.Unlock()
+
+	select {
+	case <-ch:
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.ids[key] == "" {
+		return "", fmt.Errorf("unresolved reference {{%s}}, the request creating it failed", name)
+	}
+
+	return c.ids[key], nil
+}
+
+// resolve replaces the references in s matched by pattern with the captured ids
+func (c *captures) resolve(ctx context.Context, pattern *regexp.Regexp, s string, iteration int) (string, error) {
+	var err error
+
+	resolved := pattern.ReplaceAllStringFunc(s, func(match string) string {
+		if err != nil {
+			return match
+		}
+
+		var id string
+		id, err = c.get(ctx, pattern.FindStringSubmatch(match)[1], iteration)
+		return id
+	})
+
+	return resolved, err
+}
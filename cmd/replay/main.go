@@ -0,0 +1,405 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"time"
+
+	"thesis.lefler.eu/internal/workload"
+	"thesis.lefler.eu/migrations"
+)
+
+const usage = `Usage: replay [flags]
+
+Replays a JSONL workload against a running API, once per strategy, and writes a JSON summary
+comparing the strategies to stdout. Every line of the workload is a request:
+
+  {"method": "POST", "path": "/v5/people", "body": {...}, "status": 201, "capture": "p1"}
+  {"method": "GET", "path": "/v5/people/{{p1}}", "status": 200}
+  {"method": "POST", "path": "/v5/movies", "body": {"crew": [{"person_id": "{{p1}}", ...}]}}
+
+The path is relative to the strategy prefix, status is the expected status and optional.
+A line with "strategy" set is only replayed against that strategy. A line with "capture" set
+records the id of the record it creates. Later lines reference it as {{name}} in the path or
+"{{name}}" in the body, which is replaced by the bare id, and wait until it is created.
+
+With -migrate, the next migration step of each strategy is applied while the workload is
+replayed, e.g. from v2 to v3 on a database migrated with
+
+  go run ./cmd/migrate -strategy views up-to 22
+
+The report then breaks down the failures, failed writes and latency outliers of every
+version into before, during and after the migration, with a timeline to spot error spikes.
+The API keeps serving the versions available when it started, so the workload should call
+those, and it should run long enough to span the migration, e.g. with -repeat.
+
+Exits with status 1 if a response has an unexpected status or a request fails.
+
+Flags:
+`
+
+type config struct {
+	url         string
+	workload    string
+	strategy    string
+	concurrency int
+	rate        float64
+	repeat      int
+	timeout     time.Duration
+	migrate     bool
+	after       time.Duration
+	bucket      time.Duration
+	db          struct {
+		dsn struct {
+			views           string
+			expandDeprecate string
+			branches        string
+		}
+	}
+}
+
+type report struct {
+	Workload   string               `json:"workload"`
+	Strategies []strategySummary    `json:"strategies"`
+	Comparison []endpointComparison `json:"comparison"`
+	Mismatches []mismatch           `json:"mismatches"`
+	Online     []onlineSummary      `json:"online,omitempty"`
+}
+
+func main() {
+	var cfg config
+
+	flag.StringVar(&cfg.url, "url", "http://localhost:4000", "Base URL of the API")
+	flag.StringVar(&cfg.workload, "workload", "", "JSONL workload to replay, e.g. one written by go run ./cmd/workload -o workload.jsonl (required)")
+	flag.StringVar(&cfg.strategy, "strategy", "all", "Strategy to replay against (views|expand_deprecate|branches|all)")
+	flag.IntVar(&cfg.concurrency, "concurrency", 1, "Number of requests in flight at once")
+	flag.Float64Var(&cfg.rate, "rate", 0, "Maximum requests per second per strategy (0 = unlimited)")
+	flag.IntVar(&cfg.repeat, "repeat", 1, "Number of times the workload is replayed per strategy")
+	flag.DurationVar(&cfg.timeout, "timeout", 10*time.Second, "Timeout of a single request")
+	flag.BoolVar(&cfg.migrate, "migrate", false, "Apply the next migration step of each strategy during the replay")
+	flag.DurationVar(&cfg.after, "migrate-after", 5*time.Second, "Time into the replay at which the migration step starts")
+	flag.DurationVar(&cfg.bucket, "bucket", time.Second, "Width of the buckets of the timeline of an online migration")
+
+	flag.StringVar(&cfg.db.dsn.views, "db-dsn-views", os.Getenv("VIEWS_DB_DSN"), "PostgreSQL DSN for Views method, with -migrate")
+	flag.StringVar(&cfg.db.dsn.expandDeprecate, "db-dsn-expand-deprecate", os.Getenv("EXPAND_DEPRECATE_DB_DSN"), "PostgreSQL DSN for Expand & Deprecate method, with -migrate")
+	flag.StringVar(&cfg.db.dsn.branches, "db-dsn-branches", os.Getenv("BRANCHES_DB_DSN"), "PostgreSQL DSN for Branches method, with -migrate")
+
+	flag.Usage = func() {
+		fmt.Fprint(flag.CommandLine.Output(), usage)
+		flag.PrintDefaults()
+	}
+
+	flag.Parse()
+
+	logger := slog.New(slog.NewTextHandler(os.Stderr, nil))
+
+	if cfg.workload == "" {
+		logger.Error("-workload is required, generate one with go run ./cmd/workload -o workload.jsonl")
+		os.Exit(2)
+	}
+
+	if cfg.concurrency < 1 || cfg.repeat < 1 || cfg.rate < 0 {
+		logger.Error("-concurrency and -repeat must be at least 1, -rate must not be negative")
+		os.Exit(2)
+	}
+
+	if cfg.after < 0 || cfg.bucket <= 0 {
+		logger.Error("-migrate-after must not be negative, -bucket must be positive")
+		os.Exit(2)
+	}
+
+	strategies := migrations.Strategies
+	if cfg.strategy != "all" {
+		if _, ok := migrations.Dirs[cfg.strategy]; !ok {
+			logger.Error("unknown strategy", "strategy", cfg.strategy)
+			os.Exit(2)
+		}
+		strategies = []string{cfg.strategy}
+	}
+
+	entries, err := workload.Load(cfg.workload)
+	if err != nil {
+		logger.Error(err.Error(), "workload", cfg.workload)
+		os.Exit(2)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	client := &http.Client{Timeout: cfg.timeout}
+
+	r := report{
+		Workload:   cfg.workload,
+		Strategies: []strategySummary{},
+		Mismatches: []mismatch{},
+	}
+
+	dsns := map[string]string{
+		"views":            cfg.db.dsn.views,
+		"expand_deprecate": cfg.db.dsn.expandDeprecate,
+		"branches":         cfg.db.dsn.branches,
+	}
+
+	for _, strategy := range strategies {
+		var (
+			db   *sql.DB
+			step *onlineMigration
+		)
+
+		if cfg.migrate {
+			db, err = openDB(dsns[strategy], strategy)
+			if err != nil {
+				logger.Error(err.Error(), "strategy", strategy)
+				os.Exit(2)
+			}
+
+			step, err = nextStep(ctx, db, strategy)
+			if err != nil {
+				db.Close()
+				logger.Error(err.Error(), "strategy", strategy)
+				os.Exit(2)
+			}
+
+			logger.Info("migrating during replay", "strategy", strategy, "from", step.from, "to", step.migrations[len(step.migrations)-1].Version, "after", cfg.after)
+		}
+
+		logger.Info("replaying workload", "strategy", strategy, "requests", len(entries)*cfg.repeat)
+
+		start := time.Now()
+
+		replayed := make(chan struct{})
+		migrated := make(chan struct{})
+		if step != nil {
+			go func() {
+				defer close(migrated)
+				step.run(ctx, db, strategy, cfg.after, replayed)
+			}()
+		} else {
+			close(migrated)
+		}
+
+		results := replay(ctx, client, cfg, strategy, entries)
+		elapsed := time.Since(start)
+		close(replayed)
+
+		// a migration outlasting the workload still has to finish before it is reported
+		<-migrated
+
+		if step != nil {
+			db.Close()
+
+			online := summarizeOnline(strategy, step, entries, results, start, cfg.bucket)
+			r.Online = append(r.Online, online)
+
+			if step.err != nil {
+				logger.Error("migration step failed", "strategy", strategy, "error", step.err)
+			} else if step.started.IsZero() {
+				logger.Warn("the replay finished before the migration step started, increase -repeat or decrease -migrate-after", "strategy", strategy)
+			} else {
+				logger.Info("migrated during replay", "strategy", strategy, "duration_ms", online.Duration)
+			}
+		}
+
+		summary, mismatches := summarize(strategy, entries, results, elapsed)
+		r.Strategies = append(r.Strategies, summary)
+		r.Mismatches = append(r.Mismatches, mismatches...)
+
+		logger.Info("replayed workload", "strategy", strategy, "requests", summary.Requests, "mismatches", summary.Mismatches, "errors", summary.Errors, "p50_ms", summary.Latency.P50)
+
+		if ctx.Err() != nil {
+			logger.Warn("interrupted, summarizing the requests replayed so far")
+			break
+		}
+	}
+
+	r.Comparison = compare(r.Strategies)
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+
+	err = enc.Encode(r)
+	if err != nil {
+		logger.Error(err.Error())
+		os.Exit(2)
+	}
+
+	if len(r.Mismatches) > 0 {
+		os.Exit(1)
+	}
+}
+
+// job is an entry of the workload in one repetition
+type job struct {
+	index     int
+	iteration int
+}
+
+// replay sends the entries of the workload for strategy, in order but with up to
+// cfg.concurrency in flight, and returns the results of the requests that were sent
+func replay(ctx context.Context, client *http.Client, cfg config, strategy string, entries []workload.Entry) []result {
+	captures := newCaptures()
+
+	var jobs []job
+	for i := 0; i < cfg.repeat; i++ {
+		for j, e := range entries {
+			if e.Strategy != "" && e.Strategy != strategy {
+				// entries referencing the capture fail instead of waiting for it
+				if e.Capture != "" {
+					captures.set(e.Capture, i, "")
+				}
+				continue
+			}
+			jobs = append(jobs, job{index: j, iteration: i})
+		}
+	}
+
+	var limiter *time.Ticker
+	if cfg.rate > 0 {
+		limiter = time.NewTicker(time.Duration(float64(time.Second) / cfg.rate))
+		defer limiter.Stop()
+	}
+
+	queue := make(chan job)
+	results := make(chan result)
+
+	var wg sync.WaitGroup
+	for i := 0; i < cfg.concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range queue {
+				results <- send(ctx, client, cfg.url, strategy, j, entries[j.index], captures)
+			}
+		}()
+	}
+
+	go func() {
+		defer close(queue)
+		for _, j := range jobs {
+			if limiter != nil {
+				select {
+				case <-limiter.C:
+				case <-ctx.Done():
+					return
+				}
+			}
+
+			select {
+			case queue <- j:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	var replayed []result
+	for r := range results {
+		replayed = append(replayed, r)
+	}
+
+	return replayed
+}
+
+func openDB(dsn string, method string) (*sql.DB, error) {
+	if dsn == "" {
+		return nil, fmt.Errorf("missing %s DSN", method)
+	}
+
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	err = db.PingContext(ctx)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return db, nil
+}
+
+func send(ctx context.Context, client *http.Client, baseURL string, strategy string, j job, e workload.Entry, captures *captures) (r result) {
+	r = result{entry: j.index, sent: time.Now()}
+
+	if e.Capture != "" {
+		// an empty id marks the capture as failed, so entries referencing it do not wait forever
+		defer func() {
+			id := ""
+			if r.err == nil && r.status >= 200 && r.status < 300 {
+				id = r.capturedID
+			}
+			captures.set(e.Capture, j.iteration, id)
+		}()
+	}
+
+	path, err := captures.resolve(ctx, workload.Reference, e.Path, j.iteration)
+	if err != nil {
+		r.err = err
+		return r
+	}
+
+	var body io.Reader
+	if len(e.Body) > 0 {
+		resolved, err := captures.resolve(ctx, workload.QuotedReference, string(e.Body), j.iteration)
+		if err != nil {
+			r.err = err
+			return r
+		}
+		body = strings.NewReader(resolved)
+	}
+
+	url := strings.TrimSuffix(baseURL, "/") + "/" + strategy + path
+
+	req, err := http.NewRequestWithContext(ctx, e.Method, url, body)
+	if err != nil {
+		r.err = err
+		return r
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	start := time.Now()
+	r.sent = start
+
+	res, err := client.Do(req)
+	if err != nil {
+		r.err = err
+		return r
+	}
+	defer res.Body.Close()
+
+	// the latency includes reading the body, which the API writes in one go
+	if e.Capture != "" {
+		var buf bytes.Buffer
+		_, err = io.Copy(&buf, res.Body)
+		r.capturedID = workload.CreatedID(buf.Bytes())
+	} else {
+		_, err = io.Copy(io.Discard, res.Body)
+	}
+	r.latency = time.Since(start)
+	r.status = res.StatusCode
+	r.err = err
+
+	return r
+}
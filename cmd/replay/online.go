@@ -0,0 +1,296 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"fmt"
+	"maps"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"thesis.lefler.eu/internal/latency"
+	"thesis.lefler.eu/internal/migration"
+	"thesis.lefler.eu/internal/workload"
+	"thesis.lefler.eu/migrations"
+
+	_ "github.com/lib/pq"
+)
+
+// phases of a replay relative to the migration step applied during it
+const (
+	phaseBefore = "before"
+	phaseDuring = "during"
+	phaseAfter  = "after"
+)
+
+// onlineMigration is the next migration step of a strategy, applied while the workload is replayed
+type onlineMigration struct {
+	from       int64
+	migrations []*migration.Migration
+
+	// set once the step has run
+	started  time.Time
+	finished time.Time
+	costs    []migration.Cost
+	err      error
+}
+
+// nextStep returns the pending migrations introducing the next API version, e.g.
+// 00030_v3_schema.sql to 00034_v3_cleanup.sql on a views database migrated to v2
+func nextStep(ctx context.Context, db *sql.DB, strategy string) (*onlineMigration, error) {
+	files, err := migration.Load(migrations.FS, migrations.Dirs[strategy])
+	if err != nil {
+		return nil, err
+	}
+
+	statuses, err := migration.New(db, files).Status(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	step := &onlineMigration{}
+
+	for _, status := range statuses {
+		if status.Applied {
+			step.from = max(step.from, status.Migration.Version)
+			continue
+		}
+
+		// the files are numbered by the API version they introduce, e.g. 00031_v3_migration.sql,
+		// the bookkeeping tables below 00010 are applied with the first step
+		if len(step.migrations) > 0 && apiVersion(status.Migration) != apiVersion(step.migrations[0]) {
+			break
+		}
+		step.migrations = append(step.migrations, status.Migration)
+	}
+
+	if len(step.migrations) == 0 {
+		return nil, fmt.Errorf("%s database is fully migrated, there is no next step to apply", strategy)
+	}
+
+	return step, nil
+}
+
+// apiVersion is the API version a migration introduces by its number, 1 for the bookkeeping
+// tables applied before the schema of v1
+func apiVersion(m *migration.Migration) int64 {
+	return max(m.Version/10, 1)
+}
+
+// run applies the step after delay, measuring the cost of every migration. It is skipped
+// if the replay is done before, a step that has started always runs to completion.
+func (step *onlineMigration) run(ctx context.Context, db *sql.DB, strategy string, delay time.Duration, replayed <-chan struct{}) {
+	select {
+	case <-time.After(delay):
+	case <-replayed:
+		return
+	case <-ctx.Done():
+		step.err = ctx.Err()
+		return
+	}
+
+	files, err := migration.Load(migrations.FS, migrations.Dirs[strategy])
+	if err != nil {
+		step.err = err
+		return
+	}
+
+	migrator := migration.New(db, files)
+	migrator.Measure = true
+
+	step.started = time.Now()
+	_, step.err = migrator.UpTo(ctx, step.migrations[len(step.migrations)-1].Version)
+	step.finished = time.Now()
+
+	step.costs = migrator.Costs
+}
+
+// onlineSummary reports what clients of each version experienced before, during and after
+// a strategy applied a migration step
+type onlineSummary struct {
+	Strategy   string           `json:"strategy"`
+	From       int64            `json:"from"` // migration the database was at
+	Migrations []string         `json:"migrations"`
+	Started    float64          `json:"started_ms"` // since the replay started
+	Duration   float64          `json:"duration_ms"`
+	Error      string           `json:"error,omitempty"`
+	Costs      []migration.Cost `json:"costs"`
+	Versions   []versionOnline  `json:"versions"`
+	Timeline   []bucket         `json:"timeline"`
+}
+
+type versionOnline struct {
+	Version string                  `json:"version"`
+	Phases  map[string]phaseSummary `json:"phases"`
+}
+
+type phaseSummary struct {
+	Requests     int             `json:"requests"`
+	Failures     int             `json:"failures"` // requests without a response or with an unexpected status
+	FailedWrites int             `json:"failed_writes"`
+	ErrorRate    float64         `json:"error_rate"`
+	PeakErrors   float64         `json:"peak_error_rate"` // highest error rate of a bucket of the timeline
+	Outliers     int             `json:"outliers"`        // slower than the p99 of the version before the migration
+	Latency      latency.Summary `json:"latency"`
+}
+
+// bucket of the timeline of a version, to spot error spikes and latency outliers over time
+type bucket struct {
+	Offset   float64 `json:"offset_ms"` // start of the bucket since the replay started
+	Version  string  `json:"version"`
+	Requests int     `json:"requests"`
+	Failures int     `json:"failures"`
+	Max      float64 `json:"max_ms"`
+}
+
+// phase of a request: before if it completed before the migration started, after if it was
+// sent once the migration finished, during if it overlapped with it
+func (step *onlineMigration) phase(r result) string {
+	switch {
+	case step.started.IsZero() || r.sent.Add(r.latency).Before(step.started):
+		return phaseBefore
+	case step.finished.IsZero() || r.sent.Before(step.finished):
+		return phaseDuring
+	default:
+		return phaseAfter
+	}
+}
+
+func summarizeOnline(strategy string, step *onlineMigration, entries []workload.Entry, results []result, start time.Time, width time.Duration) onlineSummary {
+	s := onlineSummary{
+		Strategy: strategy,
+		From:     step.from,
+		Costs:    step.costs,
+		Versions: []versionOnline{},
+		Timeline: []bucket{},
+	}
+	for _, m := range step.migrations {
+		s.Migrations = append(s.Migrations, m.Name)
+	}
+	if !step.started.IsZero() {
+		s.Started = latency.Milliseconds(step.started.Sub(start))
+		s.Duration = latency.Milliseconds(step.finished.Sub(step.started))
+	}
+	if step.err != nil {
+		s.Error = step.err.Error()
+	}
+	if s.Costs == nil {
+		s.Costs = []migration.Cost{}
+	}
+
+	type key struct {
+		version string
+		phase   string
+	}
+
+	durations := make(map[key][]time.Duration)
+	phases := make(map[key]phaseSummary)
+	buckets := make(map[string]map[int64]*bucket)
+	bucketPhase := make(map[string]map[int64]string)
+
+	for _, r := range results {
+		e := entries[r.entry]
+		version := clientVersion(e.Path)
+		k := key{version, step.phase(r)}
+
+		failed := r.err != nil || (e.Status != 0 && r.status != e.Status)
+
+		p := phases[k]
+		p.Requests++
+		if failed {
+			p.Failures++
+			if e.Method != http.MethodGet {
+				p.FailedWrites++
+			}
+		}
+		phases[k] = p
+
+		if r.err == nil {
+			durations[k] = append(durations[k], r.latency)
+		}
+
+		index := int64(r.sent.Sub(start) / width)
+		if buckets[version] == nil {
+			buckets[version] = make(map[int64]*bucket)
+			bucketPhase[version] = make(map[int64]string)
+		}
+		b, ok := buckets[version][index]
+		if !ok {
+			b = &bucket{Offset: latency.Milliseconds(time.Duration(index) * width), Version: version}
+			buckets[version][index] = b
+			bucketPhase[version][index] = k.phase
+		}
+		b.Requests++
+		if failed {
+			b.Failures++
+		}
+		b.Max = max(b.Max, latency.Milliseconds(r.latency))
+		// a bucket overlapping the migration counts towards it
+		if k.phase == phaseDuring {
+			bucketPhase[version][index] = phaseDuring
+		}
+	}
+
+	versions := make(map[string]bool)
+	for k := range phases {
+		versions[k.version] = true
+	}
+
+	for _, version := range slices.Sorted(maps.Keys(versions)) {
+		v := versionOnline{Version: version, Phases: make(map[string]phaseSummary)}
+
+		// outliers are measured against the latency of the version without a migration running
+		var threshold time.Duration
+		if before := slices.Sorted(slices.Values(durations[key{version, phaseBefore}])); len(before) > 0 {
+			threshold = latency.Percentile(before, 99)
+		}
+
+		for _, phase := range []string{phaseBefore, phaseDuring, phaseAfter} {
+			k := key{version, phase}
+			p, ok := phases[k]
+			if !ok {
+				continue
+			}
+
+			p.ErrorRate = float64(p.Failures) / float64(p.Requests)
+			p.Latency = latency.Summarize(durations[k])
+
+			if threshold > 0 && phase != phaseBefore {
+				for _, d := range durations[k] {
+					if d > threshold {
+						p.Outliers++
+					}
+				}
+			}
+
+			for index, b := range buckets[version] {
+				if bucketPhase[version][index] == phase && b.Requests > 0 {
+					p.PeakErrors = max(p.PeakErrors, float64(b.Failures)/float64(b.Requests))
+				}
+			}
+
+			v.Phases[phase] = p
+		}
+
+		s.Versions = append(s.Versions, v)
+
+		for _, b := range buckets[version] {
+			s.Timeline = append(s.Timeline, *b)
+		}
+	}
+
+	slices.SortFunc(s.Timeline, func(a, b bucket) int {
+		return cmp.Or(cmp.Compare(a.Offset, b.Offset), cmp.Compare(a.Version, b.Version))
+	})
+
+	return s
+}
+
+// clientVersion returns the version a workload path calls, e.g. v3 for /v3/movies/{{m1}}
+func clientVersion(path string) string {
+	version, _, _ := strings.Cut(strings.TrimPrefix(path, "/"), "/")
+	return version
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"sort"
+	"time"
+
+	"thesis.lefler.eu/internal/latency"
+	"thesis.lefler.eu/internal/workload"
+)
+
+// result of replaying a single entry
+type result struct {
+	entry      int // index in the workload
+	sent       time.Time
+	status     int
+	latency    time.Duration
+	err        error
+	capturedID string
+}
+
+type endpointSummary struct {
+	Requests   int             `json:"requests"`
+	Mismatches int             `json:"mismatches"`
+	Latency    latency.Summary `json:"latency"`
+}
+
+type strategySummary struct {
+	Strategy   string                     `json:"strategy"`
+	Requests   int                        `json:"requests"`
+	Errors     int                        `json:"errors"`     // requests without a response
+	Mismatches int                        `json:"mismatches"` // responses with an unexpected status
+	Duration   float64                    `json:"duration_ms"`
+	Throughput float64                    `json:"throughput_rps"`
+	Latency    latency.Summary            `json:"latency"`
+	Endpoints  map[string]endpointSummary `json:"endpoints"`
+}
+
+// endpointComparison compares the latency of an endpoint between strategies
+type endpointComparison struct {
+	Endpoint   string             `json:"endpoint"`
+	P50        map[string]float64 `json:"p50_ms"`
+	P99        map[string]float64 `json:"p99_ms"`
+	Fastest    string             `json:"fastest"` // by p50
+	Mismatches map[string]int     `json:"mismatches"`
+}
+
+type mismatch struct {
+	Strategy string `json:"strategy"`
+	Method   string `json:"method"`
+	Path     string `json:"path"`
+	Expected int    `json:"expected,omitempty"`
+	Actual   int    `json:"actual,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+func summarize(strategy string, entries []workload.Entry, results []result, elapsed time.Duration) (strategySummary, []mismatch) {
+	s := strategySummary{
+		Strategy:  strategy,
+		Requests:  len(results),
+		Duration:  latency.Milliseconds(elapsed),
+		Endpoints: make(map[string]endpointSummary),
+	}
+	if elapsed > 0 {
+		s.Throughput = float64(len(results)) / elapsed.Seconds()
+	}
+
+	var mismatches []mismatch
+
+	var all []time.Duration
+	byEndpoint := make(map[string][]time.Duration)
+
+	for _, r := range results {
+		e := entries[r.entry]
+		endpoint := e.Endpoint()
+		summary := s.Endpoints[endpoint]
+		summary.Requests++
+
+		switch {
+		case r.err != nil:
+			s.Errors++
+			summary.Mismatches++
+			mismatches = append(mismatches, mismatch{Strategy: strategy, Method: e.Method, Path: e.Path, Expected: e.Status, Error: r.err.Error()})
+		case e.Status != 0 && r.status != e.Status:
+			s.Mismatches++
+			summary.Mismatches++
+			mismatches = append(mismatches, mismatch{Strategy: strategy, Method: e.Method, Path: e.Path, Expected: e.Status, Actual: r.status})
+		}
+
+		if r.err == nil {
+			all = append(all, r.latency)
+			byEndpoint[endpoint] = append(byEndpoint[endpoint], r.latency)
+		}
+
+		s.Endpoints[endpoint] = summary
+	}
+
+	s.Latency = latency.Summarize(all)
+	for endpoint, durations := range byEndpoint {
+		summary := s.Endpoints[endpoint]
+		summary.Latency = latency.Summarize(durations)
+		s.Endpoints[endpoint] = summary
+	}
+
+	return s, mismatches
+}
+
+// compare returns the endpoints replayed against every strategy, with their latency per strategy
+func compare(summaries []strategySummary) []endpointComparison {
+	endpoints := make(map[string]bool)
+	for _, s := range summaries {
+		for endpoint := range s.Endpoints {
+			endpoints[endpoint] = true
+		}
+	}
+
+	comparisons := []endpointComparison{}
+
+	for endpoint := range endpoints {
+		c := endpointComparison{
+			Endpoint:   endpoint,
+			P50:        make(map[string]float64),
+			P99:        make(map[string]float64),
+			Mismatches: make(map[string]int),
+		}
+
+		for _, s := range summaries {
+			summary, ok := s.Endpoints[endpoint]
+			if !ok {
+				continue
+			}
+
+			c.P50[s.Strategy] = summary.Latency.P50
+			c.P99[s.Strategy] = summary.Latency.P99
+			c.Mismatches[s.Strategy] = summary.Mismatches
+
+			if c.Fastest == "" || summary.Latency.P50 < c.P50[c.Fastest] {
+				c.Fastest = s.Strategy
+			}
+		}
+
+		comparisons = append(comparisons, c)
+	}
+
+	sort.Slice(comparisons, func(i, j int) bool { return comparisons[i].Endpoint < comparisons[j].Endpoint })
+
+	return comparisons
+}
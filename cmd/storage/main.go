@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"time"
+
+	"thesis.lefler.eu/internal/storage"
+	"thesis.lefler.eu/migrations"
+
+	_ "github.com/lib/pq"
+)
+
+const usage = `Usage: storage [flags]
+
+Compares the disk usage of the strategy databases and writes a JSON report to stdout: the
+total, table, index and TOAST size, row count and dead tuples of every table, and the data
+a strategy stores twice, e.g. the genre of a movie next to its genres. The same report is
+served by the admin server of the API at /admin/storage.
+
+Flags:
+`
+
+type config struct {
+	strategy string
+	db       struct {
+		dsn struct {
+			views           string
+			expandDeprecate string
+			branches        string
+		}
+	}
+}
+
+func main() {
+	var cfg config
+
+	flag.StringVar(&cfg.strategy, "strategy", "all", "Strategy to report on (views|expand_deprecate|branches|all)")
+
+	flag.StringVar(&cfg.db.dsn.views, "db-dsn-views", os.Getenv("VIEWS_DB_DSN"), "PostgreSQL DSN for Views method")
+	flag.StringVar(&cfg.db.dsn.expandDeprecate, "db-dsn-expand-deprecate", os.Getenv("EXPAND_DEPRECATE_DB_DSN"), "PostgreSQL DSN for Expand & Deprecate method")
+	flag.StringVar(&cfg.db.dsn.branches, "db-dsn-branches", os.Getenv("BRANCHES_DB_DSN"), "PostgreSQL DSN for Branches method")
+
+	flag.Usage = func() {
+		fmt.Fprint(flag.CommandLine.Output(), usage)
+		flag.PrintDefaults()
+	}
+
+	flag.Parse()
+
+	logger := slog.New(slog.NewTextHandler(os.Stderr, nil))
+
+	strategies := migrations.Strategies
+	if cfg.strategy != "all" {
+		if _, ok := migrations.Dirs[cfg.strategy]; !ok {
+			logger.Error("unknown strategy", "strategy", cfg.strategy)
+			os.Exit(2)
+		}
+		strategies = []string{cfg.strategy}
+	}
+
+	dsns := map[string]string{
+		"views":            cfg.db.dsn.views,
+		"expand_deprecate": cfg.db.dsn.expandDeprecate,
+		"branches":         cfg.db.dsn.branches,
+	}
+
+	dbs := make(map[string]*sql.DB)
+	for _, strategy := range strategies {
+		db, err := openDB(dsns[strategy], strategy)
+		if err != nil {
+			logger.Error(err.Error(), "strategy", strategy)
+			os.Exit(2)
+		}
+		defer db.Close()
+
+		dbs[strategy] = db
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	report, err := storage.Collect(ctx, dbs, strategies)
+	if err != nil {
+		logger.Error(err.Error())
+		os.Exit(1)
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+
+	err = enc.Encode(report)
+	if err != nil {
+		logger.Error(err.Error())
+		os.Exit(1)
+	}
+}
+
+func openDB(dsn string, method string) (*sql.DB, error) {
+	if dsn == "" {
+		return nil, fmt.Errorf("missing %s DSN", method)
+	}
+
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	err = db.PingContext(ctx)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return db, nil
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"math/rand"
+	"net/http"
+	"slices"
+
+	"thesis.lefler.eu/internal/workload"
+)
+
+// captures prefix the names of captured ids per resource, e.g. m1 for the first movie
+var captures = map[string]string{
+	"movies": "m",
+	"actors": "a",
+	"people": "p",
+}
+
+type generator struct {
+	rand      *rand.Rand
+	versions  mix
+	resources mix
+	writes    mix
+	reads     float64 // share of reads among all requests
+	lists     float64 // share of list requests among reads
+
+	created map[string]int      // ids captured per resource, for naming
+	live    map[string][]string // captures of records not deleted yet, per resource
+}
+
+func newGenerator(cfg config) *generator {
+	return &generator{
+		rand:      rand.New(rand.NewSource(cfg.seed)),
+		versions:  cfg.versions,
+		resources: cfg.resources,
+		writes:    cfg.writes,
+		reads:     cfg.reads,
+		lists:     cfg.lists,
+		created:   make(map[string]int),
+		live:      make(map[string][]string),
+	}
+}
+
+// next returns the next request of the workload
+func (g *generator) next() workload.Entry {
+	version := g.versions.pick(g.rand, nil)
+	resource := g.resources.pick(g.rand, workload.Resources[version])
+	if resource == "" {
+		resource = "movies"
+	}
+
+	path := fmt.Sprintf("/%s/%s", version, resource)
+
+	// reads and writes of existing records need a record to exist
+	if len(g.live[resource]) == 0 {
+		return g.create(version, resource, path)
+	}
+
+	if g.rand.Float64() < g.reads {
+		if g.rand.Float64() < g.lists {
+			return workload.Entry{Method: http.MethodGet, Path: path, Status: http.StatusOK}
+		}
+		return workload.Entry{Method: http.MethodGet, Path: path + "/{{" + g.existing(resource) + "}}", Status: http.StatusOK}
+	}
+
+	switch g.writes.pick(g.rand, nil) {
+	case "update":
+		return workload.Entry{
+			Method: http.MethodPatch,
+			Path:   path + "/{{" + g.existing(resource) + "}}",
+			Body:   g.body(version, resource, true),
+			Status: http.StatusOK,
+		}
+	case "delete":
+		capture := g.existing(resource)
+		g.live[resource] = slices.DeleteFunc(g.live[resource], func(c string) bool { return c == capture })
+
+		return workload.Entry{Method: http.MethodDelete, Path: path + "/{{" + capture + "}}", Status: http.StatusOK}
+	default:
+		return g.create(version, resource, path)
+	}
+}
+
+func (g *generator) create(version string, resource string, path string) workload.Entry {
+	g.created[resource]++
+	capture := fmt.Sprintf("%s%d", captures[resource], g.created[resource])
+
+	e := workload.Entry{
+		Method:  http.MethodPost,
+		Path:    path,
+		Body:    g.body(version, resource, false),
+		Status:  http.StatusCreated,
+		Capture: capture,
+	}
+
+	g.live[resource] = append(g.live[resource], capture)
+
+	return e
+}
+
+func (g *generator) existing(resource string) string {
+	live := g.live[resource]
+	return live[g.rand.Intn(len(live))]
+}
+
+// body returns a valid request body for the input struct of the version, an update sets
+// a random subset of the fields of a create
+func (g *generator) body(version string, resource string, update bool) json.RawMessage {
+	fields := workload.Fields(g.rand, version, resource)
+	if resource == "movies" && !update {
+		g.cast(version, fields)
+	}
+
+	if update {
+		// in a fixed order, so a seed always generates the same workload
+		keys := slices.Sorted(maps.Keys(fields))
+		for _, field := range keys {
+			if len(fields) > 1 && g.rand.Intn(2) == 0 {
+				delete(fields, field)
+			}
+		}
+	}
+
+	body, err := json.Marshal(fields)
+	if err != nil {
+		panic(err)
+	}
+
+	return body
+}
+
+// cast adds the actors of v4 or the crew of v5 to the fields of a movie, referencing live
+// actors or people. It is only set on create, as updates replace it as a whole.
+func (g *generator) cast(version string, fields map[string]any) {
+	switch version {
+	case "v4":
+		var actors []map[string]any
+		for _, capture := range g.sample("actors", 3) {
+			actors = append(actors, map[string]any{
+				"actor_id": "{{" + capture + "}}",
+				"role":     workload.Role(g.rand),
+			})
+		}
+		if actors != nil {
+			fields["actors"] = actors
+		}
+
+	case "v5":
+		var crew []map[string]any
+		for i, capture := range g.sample("people", 4) {
+			member := map[string]any{"person_id": "{{" + capture + "}}"}
+			switch {
+			case i == 0:
+				member["crew_type"] = "Director"
+			case g.rand.Intn(4) == 0:
+				member["crew_type"] = "Producer"
+			default:
+				member["crew_type"] = "Actor"
+				member["role"] = workload.Role(g.rand)
+			}
+			crew = append(crew, member)
+		}
+		if crew != nil {
+			fields["crew"] = crew
+		}
+	}
+}
+
+// sample returns up to n distinct live records of resource
+func (g *generator) sample(resource string, n int) []string {
+	live := slices.Clone(g.live[resource])
+	g.rand.Shuffle(len(live), func(i, j int) { live[i], live[j] = live[j], live[i] })
+	return live[:min(len(live), g.rand.Intn(n+1))]
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/rand"
+	"os"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const usage = `Usage: workload [flags]
+
+Generates a JSONL workload of mixed-version traffic for replay. Request bodies are valid
+for the input structs of each version, and reads, updates and deletes reference records
+created earlier in the workload.
+
+The expected statuses assume the workload is replayed in order, i.e. with -concurrency 1,
+against a strategy that serves every version.
+
+Flags:
+`
+
+type config struct {
+	requests  int
+	versions  mix
+	resources mix
+	writes    mix
+	reads     float64
+	lists     float64
+	seed      int64
+	output    string
+}
+
+// mix is a weighted choice, e.g. v1=10,v3=30,v5=60
+type mix map[string]int
+
+func (m mix) String() string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	parts := make([]string, len(keys))
+	for i, key := range keys {
+		parts[i] = fmt.Sprintf("%s=%d", key, m[key])
+	}
+	return strings.Join(parts, ",")
+}
+
+// set parses a mix, the keys must be in allowed
+func (m mix) set(value string, allowed []string) error {
+	clear(m)
+
+	for _, part := range strings.Split(value, ",") {
+		key, weight, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			return fmt.Errorf("%q is not key=weight", part)
+		}
+		if !slices.Contains(allowed, key) {
+			return fmt.Errorf("unknown %q, expected one of %s", key, strings.Join(allowed, ", "))
+		}
+
+		n, err := strconv.Atoi(weight)
+		if err != nil || n < 0 {
+			return fmt.Errorf("weight of %q must be a non-negative integer", key)
+		}
+		m[key] = n
+	}
+
+	return nil
+}
+
+// pick returns a key by weight, only from the keys in among if set, or "" if none has a weight
+func (m mix) pick(r *rand.Rand, among []string) string {
+	var keys []string
+	total := 0
+	for key, weight := range m {
+		if weight > 0 && (among == nil || slices.Contains(among, key)) {
+			keys = append(keys, key)
+			total += weight
+		}
+	}
+	if total == 0 {
+		return ""
+	}
+
+	// map order is random, sort so a seed always generates the same workload
+	sort.Strings(keys)
+
+	n := r.Intn(total)
+	for _, key := range keys {
+		n -= m[key]
+		if n < 0 {
+			return key
+		}
+	}
+	return ""
+}
+
+type mixFlag struct {
+	mix     mix
+	allowed []string
+}
+
+func (f mixFlag) String() string {
+	return f.mix.String()
+}
+
+func (f mixFlag) Set(value string) error {
+	return f.mix.set(value, f.allowed)
+}
+
+func main() {
+	cfg := config{
+		versions:  mix{"v1": 20, "v2": 20, "v3": 20, "v4": 20, "v5": 20},
+		resources: mix{"movies": 70, "actors": 15, "people": 15},
+		writes:    mix{"create": 50, "update": 40, "delete": 10},
+	}
+
+	flag.IntVar(&cfg.requests, "requests", 1000, "Number of requests to generate")
+	flag.Var(mixFlag{cfg.versions, []string{"v1", "v2", "v3", "v4", "v5"}}, "versions", "Weighted mix of versions")
+	flag.Var(mixFlag{cfg.resources, []string{"movies", "actors", "people"}}, "resources", "Weighted mix of resources, actors are only served by v4 and people by v5")
+	flag.Var(mixFlag{cfg.writes, []string{"create", "update", "delete"}}, "writes", "Weighted mix of writes")
+	flag.Float64Var(&cfg.reads, "reads", 0.8, "Share of reads among all requests")
+	flag.Float64Var(&cfg.lists, "lists", 0.2, "Share of list requests among reads")
+	flag.Int64Var(&cfg.seed, "seed", time.Now().UnixNano(), "Seed of the generator, the same seed generates the same workload")
+	flag.StringVar(&cfg.output, "o", "", "File to write the workload to (default stdout)")
+
+	flag.Usage = func() {
+		fmt.Fprint(flag.CommandLine.Output(), usage)
+		flag.PrintDefaults()
+	}
+
+	flag.Parse()
+
+	logger := slog.New(slog.NewTextHandler(os.Stderr, nil))
+
+	switch {
+	case cfg.requests < 1:
+		logger.Error("-requests must be at least 1")
+		os.Exit(2)
+	case cfg.reads < 0 || cfg.reads > 1 || cfg.lists < 0 || cfg.lists > 1:
+		logger.Error("-reads and -lists must be between 0 and 1")
+		os.Exit(2)
+	case cfg.versions.pick(rand.New(rand.NewSource(0)), nil) == "":
+		logger.Error("-versions must give a version a weight")
+		os.Exit(2)
+	case cfg.reads < 1 && cfg.writes.pick(rand.New(rand.NewSource(0)), nil) == "":
+		logger.Error("-writes must give a write a weight")
+		os.Exit(2)
+	}
+
+	var out io.Writer = os.Stdout
+	if cfg.output != "" {
+		f, err := os.Create(cfg.output)
+		if err != nil {
+			logger.Error(err.Error())
+			os.Exit(2)
+		}
+		defer f.Close()
+		out = f
+	}
+
+	w := bufio.NewWriter(out)
+	enc := json.NewEncoder(w)
+
+	g := newGenerator(cfg)
+	for i := 0; i < cfg.requests; i++ {
+		err := enc.Encode(g.next())
+		if err != nil {
+			logger.Error(err.Error())
+			os.Exit(1)
+		}
+	}
+
+	err := w.Flush()
+	if err != nil {
+		logger.Error(err.Error())
+		os.Exit(1)
+	}
+
+	logger.Info("generated workload", "requests", cfg.requests, "seed", cfg.seed, "movies", g.created["movies"], "actors", g.created["actors"], "people", g.created["people"])
+}
@@ -0,0 +1,320 @@
+package compat
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"thesis.lefler.eu/internal/schema"
+)
+
+type Kind string
+
+const (
+	ResourceAdded       Kind = "resource_added"
+	ResourceRemoved     Kind = "resource_removed"
+	FieldAdded          Kind = "field_added"
+	FieldRemoved        Kind = "field_removed"
+	FieldRenamed        Kind = "field_renamed" // between the singular and plural of a name, e.g. genre to genres
+	TypeChanged         Kind = "type_changed"
+	BecameNullable      Kind = "became_nullable"
+	BecameNonNullable   Kind = "became_non_nullable"
+	BecameOmittable     Kind = "became_omittable" // may be left out of responses
+	BecameRequired      Kind = "became_required"  // must be sent on create
+	BecameOptional      Kind = "became_optional"
+	EnumValueAdded      Kind = "enum_value_added"
+	EnumValueRemoved    Kind = "enum_value_removed"
+	ConstraintTightened Kind = "constraint_tightened"
+	ConstraintLoosened  Kind = "constraint_loosened"
+	RuleAdded           Kind = "rule_added"
+	RuleRemoved         Kind = "rule_removed"
+)
+
+// breaking lists the kinds of changes that break clients written against the older version.
+// New enum values break clients that switch over the values they know.
+var breaking = map[Kind]bool{
+	ResourceRemoved:     true,
+	FieldRemoved:        true,
+	FieldRenamed:        true,
+	TypeChanged:         true,
+	BecameNullable:      true,
+	BecameOmittable:     true,
+	BecameRequired:      true,
+	EnumValueAdded:      true,
+	EnumValueRemoved:    true,
+	ConstraintTightened: true,
+	RuleAdded:           true,
+}
+
+// Change is a single difference between the schemas of a resource in two versions
+type Change struct {
+	Kind     Kind   `json:"kind"`
+	Path     string `json:"path"` // field path, e.g. crew[].crew_type, empty for the resource itself
+	Breaking bool   `json:"breaking"`
+	Declared bool   `json:"declared"` // breaking, but listed in the declared breaking changes
+	Before   string `json:"before,omitempty"`
+	After    string `json:"after,omitempty"`
+	Reason   string `json:"reason,omitempty"` // reason given by the declaration
+}
+
+// Comparison holds the changes of a resource between two versions of a strategy
+type Comparison struct {
+	Strategy string   `json:"strategy"`
+	From     string   `json:"from"`
+	To       string   `json:"to"`
+	Resource string   `json:"resource"`
+	Changes  []Change `json:"changes"`
+}
+
+// Compare returns the changes of every resource between the versions from and to
+func Compare(strategy string, from string, to string, versions schema.Versions) []Comparison {
+	var resources []string
+	for resource := range versions[from] {
+		resources = append(resources, resource)
+	}
+	for resource := range versions[to] {
+		if _, ok := versions[from][resource]; !ok {
+			resources = append(resources, resource)
+		}
+	}
+	sort.Strings(resources)
+
+	comparisons := []Comparison{}
+
+	for _, resource := range resources {
+		before, inFrom := versions[from][resource]
+		after, inTo := versions[to][resource]
+
+		c := Comparison{Strategy: strategy, From: from, To: to, Resource: resource, Changes: []Change{}}
+
+		switch {
+		case !inTo:
+			c.Changes = append(c.Changes, change(ResourceRemoved, "", resource, ""))
+		case !inFrom:
+			c.Changes = append(c.Changes, change(ResourceAdded, "", "", resource))
+		default:
+			c.Changes = compareType("", before, after, c.Changes)
+		}
+
+		comparisons = append(comparisons, c)
+	}
+
+	return comparisons
+}
+
+// Consecutive returns the versions of a strategy in order, paired with their successor
+func Consecutive(versions schema.Versions) [][2]string {
+	var names []string
+	for version := range versions {
+		names = append(names, version)
+	}
+
+	sort.Slice(names, func(i, j int) bool {
+		return number(names[i]) < number(names[j])
+	})
+
+	var pairs [][2]string
+	for i := 1; i < len(names); i++ {
+		pairs = append(pairs, [2]string{names[i-1], names[i]})
+	}
+
+	return pairs
+}
+
+func number(version string) int {
+	n, _ := strconv.Atoi(strings.TrimPrefix(version, "v"))
+	return n
+}
+
+func change(kind Kind, path string, before string, after string) Change {
+	return Change{Kind: kind, Path: path, Breaking: breaking[kind], Before: before, After: after}
+}
+
+func compareType(path string, before schema.Type, after schema.Type, changes []Change) []Change {
+	if describe(before) != describe(after) {
+		return append(changes, change(TypeChanged, path, describe(before), describe(after)))
+	}
+
+	if before.Nullable != after.Nullable {
+		kind := BecameNullable
+		if !after.Nullable {
+			kind = BecameNonNullable
+		}
+		changes = append(changes, change(kind, path, "", ""))
+	}
+
+	switch before.Kind {
+	case "array":
+		changes = compareType(path+"[]", *before.Items, *after.Items, changes)
+	case "object":
+		changes = compareFields(path, before, after, changes)
+	}
+
+	return changes
+}
+
+func compareFields(path string, before schema.Type, after schema.Type, changes []Change) []Change {
+	prefix := path
+	if prefix != "" {
+		prefix += "."
+	}
+
+	renamed := make(map[string]schema.Field) // new name to old field
+
+	for _, field := range before.Fields {
+		if _, ok := after.Find(field.Name); ok {
+			continue
+		}
+
+		if plural, ok := renamedTo(field.Name, before, after); ok {
+			renamed[plural.Name] = field
+			changes = append(changes, change(FieldRenamed, prefix+field.Name, field.Name, plural.Name))
+			continue
+		}
+
+		changes = append(changes, change(FieldRemoved, prefix+field.Name, describe(field.Type), ""))
+	}
+
+	for _, field := range after.Fields {
+		old, ok := before.Find(field.Name)
+		if !ok {
+			old, ok = renamed[field.Name]
+		}
+		if !ok {
+			changes = append(changes, change(FieldAdded, prefix+field.Name, "", describe(field.Type)))
+			if field.Constraints().Required {
+				changes = append(changes, change(BecameRequired, prefix+field.Name, "", ""))
+			}
+			continue
+		}
+
+		if !old.Optional && field.Optional {
+			changes = append(changes, change(BecameOmittable, prefix+field.Name, "", ""))
+		}
+
+		changes = compareType(prefix+field.Name, old.Type, field.Type, changes)
+
+		// the constraints of different types, e.g. maxLength and maxItems, are not comparable
+		if describe(old.Type) == describe(field.Type) {
+			changes = compareConstraints(prefix+field.Name, old.Constraints(), field.Constraints(), changes)
+		}
+	}
+
+	return changes
+}
+
+// renamedTo returns the field of after that name was renamed to, only renames between the
+// singular and plural of a name are detected, other renames are a removal and an addition
+func renamedTo(name string, before schema.Type, after schema.Type) (schema.Field, bool) {
+	for _, candidate := range []string{name + "s", strings.TrimSuffix(name, "s")} {
+		if candidate == name {
+			continue
+		}
+		if _, inBefore := before.Find(candidate); inBefore {
+			continue
+		}
+		if field, ok := after.Find(candidate); ok {
+			return field, true
+		}
+	}
+	return schema.Field{}, false
+}
+
+func compareConstraints(path string, before schema.Constraints, after schema.Constraints, changes []Change) []Change {
+	switch {
+	case !before.Required && after.Required:
+		changes = append(changes, change(BecameRequired, path, "", ""))
+	case before.Required && !after.Required:
+		changes = append(changes, change(BecameOptional, path, "", ""))
+	}
+
+	if !before.Unique && after.Unique {
+		changes = append(changes, change(ConstraintTightened, path, "", "uniqueItems"))
+	} else if before.Unique && !after.Unique {
+		changes = append(changes, change(ConstraintLoosened, path, "uniqueItems", ""))
+	}
+
+	changes = compareLimit(path, "maxLength", before.MaxLength, after.MaxLength, true, changes)
+	changes = compareLimit(path, "maxItems", before.MaxItems, after.MaxItems, true, changes)
+	changes = compareLimit(path, "minimum", before.Minimum, after.Minimum, false, changes)
+	changes = compareLimit(path, "minItems", before.MinItems, after.MinItems, false, changes)
+
+	switch {
+	case before.Enum == nil && after.Enum != nil:
+		changes = append(changes, change(ConstraintTightened, path, "", "enum "+strings.Join(after.Enum, ", ")))
+	case before.Enum != nil && after.Enum == nil:
+		changes = append(changes, change(ConstraintLoosened, path, "enum "+strings.Join(before.Enum, ", "), ""))
+	default:
+		for _, value := range after.Enum {
+			if !slices.Contains(before.Enum, value) {
+				changes = append(changes, change(EnumValueAdded, path, "", value))
+			}
+		}
+		for _, value := range before.Enum {
+			if !slices.Contains(after.Enum, value) {
+				changes = append(changes, change(EnumValueRemoved, path, value, ""))
+			}
+		}
+	}
+
+	for _, rule := range after.Other {
+		if !slices.Contains(before.Other, rule) {
+			changes = append(changes, change(RuleAdded, path, "", rule))
+		}
+	}
+	for _, rule := range before.Other {
+		if !slices.Contains(after.Other, rule) {
+			changes = append(changes, change(RuleRemoved, path, rule, ""))
+		}
+	}
+
+	return changes
+}
+
+// compareLimit compares an upper (max) or lower bound, a missing bound is unlimited
+func compareLimit(path string, name string, before *int, after *int, max bool, changes []Change) []Change {
+	var tightened bool
+
+	switch {
+	case before == nil && after == nil:
+		return changes
+	case before == nil:
+		tightened = true
+	case after == nil:
+		tightened = false
+	case *before == *after:
+		return changes
+	case max:
+		tightened = *after < *before
+	default:
+		tightened = *after > *before
+	}
+
+	kind := ConstraintLoosened
+	if tightened {
+		kind = ConstraintTightened
+	}
+
+	return append(changes, change(kind, path, limit(name, before), limit(name, after)))
+}
+
+func limit(name string, value *int) string {
+	if value == nil {
+		return ""
+	}
+	return fmt.Sprintf("%s %d", name, *value)
+}
+
+// describe returns the JSON type of t, e.g. string, integer(int32) or array<string>
+func describe(t schema.Type) string {
+	switch {
+	case t.Kind == "array" && t.Items != nil:
+		return fmt.Sprintf("array<%s>", describe(*t.Items))
+	case t.Format != "":
+		return fmt.Sprintf("%s(%s)", t.Kind, t.Format)
+	default:
+		return t.Kind
+	}
+}
@@ -0,0 +1,75 @@
+package compat
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+)
+
+// Declaration acknowledges an intended breaking change, empty fields match any value
+type Declaration struct {
+	Strategy string `json:"strategy,omitempty"`
+	From     string `json:"from"`
+	To       string `json:"to"`
+	Resource string `json:"resource"`
+	Kind     Kind   `json:"kind,omitempty"`
+	Path     string `json:"path,omitempty"`
+	Reason   string `json:"reason"`
+}
+
+// LoadDeclarations reads a JSON array of declarations, a missing file declares nothing
+// unless required is set
+func LoadDeclarations(path string, required bool) ([]Declaration, error) {
+	js, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) && !required {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var declarations []Declaration
+
+	err = json.Unmarshal(js, &declarations)
+	if err != nil {
+		return nil, err
+	}
+
+	return declarations, nil
+}
+
+func (d Declaration) matches(c Comparison, change Change) bool {
+	return (d.Strategy == "" || d.Strategy == c.Strategy) &&
+		d.From == c.From &&
+		d.To == c.To &&
+		(d.Resource == "" || d.Resource == c.Resource) &&
+		(d.Kind == "" || d.Kind == change.Kind) &&
+		(d.Path == "" || d.Path == change.Path)
+}
+
+// Declare marks the breaking changes of comparisons matched by a declaration as declared and
+// returns the number of breaking changes left undeclared
+func Declare(comparisons []Comparison, declarations []Declaration) (undeclared int) {
+	for i := range comparisons {
+		for j := range comparisons[i].Changes {
+			change := &comparisons[i].Changes[j]
+			if !change.Breaking {
+				continue
+			}
+
+			for _, d := range declarations {
+				if d.matches(comparisons[i], *change) {
+					change.Declared, change.Reason = true, d.Reason
+					break
+				}
+			}
+
+			if !change.Declared {
+				undeclared++
+			}
+		}
+	}
+
+	return undeclared
+}
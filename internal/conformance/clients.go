@@ -0,0 +1,324 @@
+package conformance
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+)
+
+// Movie is the version independent form of a movie, every version reads and writes the
+// fields of the matrix it carries
+type Movie struct {
+	Title    string
+	Year     int32
+	Genres   []string
+	Director *string
+	Runtime  *int32
+	Language *string
+	Cast     []Role
+}
+
+type Role struct {
+	Name string `json:"name"`
+	Role string `json:"role"`
+}
+
+// movieJSON is a movie as any version sends and receives it, a version only gets the fields
+// it carries set as the API rejects unknown fields
+type movieJSON struct {
+	ID       int64        `json:"id,omitempty"`
+	Title    string       `json:"title"`
+	Year     int32        `json:"year"`
+	Genre    string       `json:"genre,omitempty"`
+	Genres   []string     `json:"genres,omitempty"`
+	Director *string      `json:"director,omitempty"`
+	Runtime  *int32       `json:"runtime,omitempty"`
+	Language *string      `json:"language,omitempty"`
+	Actors   []actorJSON  `json:"actors,omitempty"`
+	Crew     []memberJSON `json:"crew,omitempty"`
+}
+
+// actorJSON is an actor of a movie in v4
+type actorJSON struct {
+	ActorID   int64  `json:"actor_id"`
+	ActorName string `json:"actor_name,omitempty"`
+	Role      string `json:"role"`
+}
+
+// memberJSON is a member of the crew of a movie in v5
+type memberJSON struct {
+	PersonID   int64  `json:"person_id"`
+	PersonName string `json:"person_name,omitempty"`
+	CrewType   string `json:"crew_type"`
+	Role       string `json:"role,omitempty"`
+}
+
+// birthdate of the actors and people created for a movie, the API requires one
+const birthdate = "1970-01-01"
+
+// client writes and reads movies through the routes of one version, it creates an actor or a
+// person for the director and every member of the cast, and deletes them with the movie
+type client struct {
+	handler http.Handler
+	path    string             // the version below the strategy, e.g. /views/v4
+	version string             // version the client speaks
+	related map[int64][]string // movie to the paths of the actors or people created for it
+}
+
+func clients(strategy string, h http.Handler) map[string]*client {
+	clients := make(map[string]*client, len(Versions))
+	for _, version := range Versions {
+		clients[version] = &client{
+			handler: h,
+			path:    fmt.Sprintf("/%s/%s", strategy, version),
+			version: version,
+			related: make(map[int64][]string),
+		}
+	}
+	return clients
+}
+
+func first(genres []string) string {
+	if len(genres) == 0 {
+		return ""
+	}
+	return genres[0]
+}
+
+func single(genre string) []string {
+	if genre == "" {
+		return nil
+	}
+	return []string{genre}
+}
+
+// do sends a request with body encoded as JSON unless it is nil, and decodes the response into
+// dst unless it is nil. It fails unless the response has the status.
+func (c *client) do(method string, path string, body any, status int, dst any) error {
+	var buf bytes.Buffer
+	if body != nil {
+		err := json.NewEncoder(&buf).Encode(body)
+		if err != nil {
+			return err
+		}
+	}
+
+	r := httptest.NewRequest(method, c.path+path, &buf)
+	w := httptest.NewRecorder()
+	c.handler.ServeHTTP(w, r)
+
+	if w.Code != status {
+		return fmt.Errorf("%s %s: got status %d, want %d: %s", method, r.URL.Path, w.Code, status, bytes.TrimSpace(w.Body.Bytes()))
+	}
+
+	if dst == nil {
+		return nil
+	}
+
+	return json.Unmarshal(w.Body.Bytes(), dst)
+}
+
+func (c *client) create(movie Movie) (int64, error) {
+	input := movieJSON{Title: movie.Title, Year: movie.Year}
+
+	var related []string
+
+	person := func(name string) (int64, error) {
+		id, path, err := c.person(name)
+		if err != nil {
+			return 0, err
+		}
+		related = append(related, path)
+		return id, nil
+	}
+
+	switch c.version {
+	case "v1":
+		input.Genre = first(movie.Genres)
+	case "v2":
+		input.Genre = first(movie.Genres)
+		input.Director, input.Runtime, input.Language = movie.Director, movie.Runtime, movie.Language
+	case "v3":
+		input.Genres = movie.Genres
+		input.Director, input.Runtime, input.Language = movie.Director, movie.Runtime, movie.Language
+	case "v4":
+		input.Genres = movie.Genres
+		input.Director, input.Runtime, input.Language = movie.Director, movie.Runtime, movie.Language
+
+		for _, role := range movie.Cast {
+			id, err := person(role.Name)
+			if err != nil {
+				return 0, c.cleanUp(related, err)
+			}
+			input.Actors = append(input.Actors, actorJSON{ActorID: id, Role: role.Role})
+		}
+	case "v5":
+		input.Genres = movie.Genres
+		input.Runtime, input.Language = movie.Runtime, movie.Language
+
+		if movie.Director != nil {
+			id, err := person(*movie.Director)
+			if err != nil {
+				return 0, c.cleanUp(related, err)
+			}
+			input.Crew = append(input.Crew, memberJSON{PersonID: id, CrewType: "Director"})
+		}
+		for _, role := range movie.Cast {
+			id, err := person(role.Name)
+			if err != nil {
+				return 0, c.cleanUp(related, err)
+			}
+			input.Crew = append(input.Crew, memberJSON{PersonID: id, CrewType: "Actor", Role: role.Role})
+		}
+	}
+
+	var created struct {
+		Movie movieJSON `json:"movie"`
+	}
+	err := c.do(http.MethodPost, "/movies", input, http.StatusCreated, &created)
+	if err != nil {
+		return 0, c.cleanUp(related, err)
+	}
+
+	c.related[created.Movie.ID] = related
+
+	return created.Movie.ID, nil
+}
+
+func (c *client) get(id int64) (Movie, error) {
+	var got struct {
+		Movie movieJSON `json:"movie"`
+	}
+	err := c.do(http.MethodGet, fmt.Sprintf("/movies/%d", id), nil, http.StatusOK, &got)
+	if err != nil {
+		return Movie{}, err
+	}
+
+	m := got.Movie
+	movie := Movie{
+		Title:    m.Title,
+		Year:     m.Year,
+		Genres:   m.Genres,
+		Director: m.Director,
+		Runtime:  m.Runtime,
+		Language: m.Language,
+	}
+
+	// v1 and v2 have a single genre
+	if m.Genre != "" {
+		movie.Genres = single(m.Genre)
+	}
+
+	for _, actor := range m.Actors {
+		movie.Cast = append(movie.Cast, Role{Name: actor.ActorName, Role: actor.Role})
+	}
+
+	for _, member := range m.Crew {
+		switch member.CrewType {
+		case "Director":
+			if movie.Director == nil {
+				name := member.PersonName
+				movie.Director = &name
+			}
+		case "Actor":
+			movie.Cast = append(movie.Cast, Role{Name: member.PersonName, Role: member.Role})
+		}
+	}
+
+	return movie, nil
+}
+
+// person creates an actor in v4 and a person in v5, returning its id and path
+func (c *client) person(name string) (int64, string, error) {
+	resource, envelope := "actors", "actor"
+	if c.version == "v5" {
+		resource, envelope = "people", "person"
+	}
+
+	var created map[string]struct {
+		ID int64 `json:"id"`
+	}
+	err := c.do(http.MethodPost, "/"+resource, map[string]string{"name": name, "birthdate": birthdate}, http.StatusCreated, &created)
+	if err != nil {
+		return 0, "", err
+	}
+
+	id := created[envelope].ID
+	return id, fmt.Sprintf("/%s/%d", resource, id), nil
+}
+
+// clearCrew replaces the crew of a movie in v5 with none
+func (c *client) clearCrew(id int64) error {
+	return c.do(http.MethodPatch, fmt.Sprintf("/movies/%d", id), map[string][]memberJSON{"crew": {}}, http.StatusOK, nil)
+}
+
+// addDirector creates a person and adds it as a director to the crew of a movie in v5
+func (c *client) addDirector(id int64, name string) error {
+	personID, path, err := c.person(name)
+	if err != nil {
+		return err
+	}
+	c.related[id] = append(c.related[id], path)
+
+	return c.do(http.MethodPost, fmt.Sprintf("/movies/%d/crew", id), memberJSON{PersonID: personID, CrewType: "Director"}, http.StatusCreated, nil)
+}
+
+// removeDirectors deletes every director from the crew of a movie in v5, each at its version
+func (c *client) removeDirectors(id int64) error {
+	var got struct {
+		Crew []struct {
+			PersonID int64  `json:"person_id"`
+			CrewType string `json:"crew_type"`
+			Version  int32  `json:"version"`
+		} `json:"crew"`
+	}
+	err := c.do(http.MethodGet, fmt.Sprintf("/movies/%d/crew", id), nil, http.StatusOK, &got)
+	if err != nil {
+		return err
+	}
+
+	for _, member := range got.Crew {
+		if member.CrewType != "Director" {
+			continue
+		}
+
+		path := fmt.Sprintf("/movies/%d/crew/%d/Director?version=%d", id, member.PersonID, member.Version)
+		err := c.do(http.MethodDelete, path, nil, http.StatusOK, nil)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// rename only sends the title, every version leaves the fields it is not sent untouched
+func (c *client) rename(id int64, title string) error {
+	return c.do(http.MethodPatch, fmt.Sprintf("/movies/%d", id), map[string]string{"title": title}, http.StatusOK, nil)
+}
+
+func (c *client) delete(id int64) error {
+	err := c.do(http.MethodDelete, fmt.Sprintf("/movies/%d", id), nil, http.StatusOK, nil)
+	if err != nil {
+		return err
+	}
+
+	related := c.related[id]
+	delete(c.related, id)
+
+	return c.cleanUp(related, nil)
+}
+
+// cleanUp deletes the actors or people created for a movie, returning err or the first
+// error deleting them
+func (c *client) cleanUp(related []string, err error) error {
+	for _, path := range related {
+		deleteErr := c.do(http.MethodDelete, path, nil, http.StatusOK, nil)
+		if err == nil {
+			err = deleteErr
+		}
+	}
+	return err
+}
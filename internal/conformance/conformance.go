@@ -0,0 +1,267 @@
+package conformance
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+)
+
+// Checks run for every pair of versions
+const (
+	CheckRead   = "read"   // written through writer, read through reader
+	CheckUpdate = "update" // written through writer, renamed through reader, read through writer
+)
+
+// Checks of the director of a movie written through the crew of v5, run for every version
+// carrying the director as reader
+const (
+	CheckCrewClear  = "crew_clear"  // crew of a directed movie replaced with none through v5
+	CheckCrewAdd    = "crew_add"    // director added to the crew of a movie without one through v5
+	CheckCrewRemove = "crew_remove" // director removed from the crew of a directed movie through v5
+)
+
+// crewWriter is the version the crew checks write through
+const crewWriter = "v5"
+
+// Result of comparing a field against the matrix, or of a failed write or read
+type Result struct {
+	Strategy string `json:"strategy"`
+	Check    string `json:"check"`
+	Writer   string `json:"writer"`
+	Reader   string `json:"reader"`
+	Field    Field  `json:"field,omitempty"`
+	Expected any    `json:"expected,omitempty"`
+	Actual   any    `json:"actual,omitempty"`
+	Error    string `json:"error,omitempty"`
+	Passed   bool   `json:"passed"`
+}
+
+// Run writes a movie through the routes of every version of a strategy served by h and reads
+// it through every other, comparing each field against the matrix
+func Run(strategy string, h http.Handler) []Result {
+	clients := clients(strategy, h)
+
+	var results []Result
+
+	for _, writer := range Versions {
+		for _, reader := range Versions {
+			results = append(results, check(strategy, CheckRead, writer, reader, clients)...)
+			results = append(results, check(strategy, CheckUpdate, writer, reader, clients)...)
+		}
+	}
+
+	for _, rule := range Matrix {
+		if rule.Field != Director {
+			continue
+		}
+
+		for _, reader := range rule.Versions {
+			for _, kind := range []string{CheckCrewClear, CheckCrewAdd, CheckCrewRemove} {
+				results = append(results, checkCrew(strategy, kind, reader, clients)...)
+			}
+		}
+	}
+
+	return results
+}
+
+// sample is the movie written through writer, with every field set
+func sample(check string, writer string, reader string) Movie {
+	director := "Conformance Director"
+	runtime := int32(120)
+	language := "en"
+
+	return Movie{
+		Title:    fmt.Sprintf("Conformance %s %s to %s", check, writer, reader),
+		Year:     2001,
+		Genres:   []string{"drama", "comedy"},
+		Director: &director,
+		Runtime:  &runtime,
+		Language: &language,
+		Cast: []Role{
+			{Name: "Conformance Lead", Role: "Lead"},
+			{Name: "Conformance Support", Role: "Support"},
+		},
+	}
+}
+
+func check(strategy string, kind string, writer string, reader string, clients map[string]*client) (results []Result) {
+	failed := func(err error) []Result {
+		return []Result{{Strategy: strategy, Check: kind, Writer: writer, Reader: reader, Error: err.Error()}}
+	}
+
+	movie := sample(kind, writer, reader)
+
+	id, err := clients[writer].create(movie)
+	if id > 0 {
+		defer func() {
+			err := clients[writer].delete(id)
+			if err != nil {
+				results = append(results, failed(fmt.Errorf("delete: %w", err))...)
+			}
+		}()
+	}
+	if err != nil {
+		return failed(fmt.Errorf("create: %w", err))
+	}
+
+	var got Movie
+	readBack := reader
+
+	switch kind {
+	case CheckRead:
+		got, err = clients[reader].get(id)
+		if err != nil {
+			return failed(fmt.Errorf("get: %w", err))
+		}
+
+	case CheckUpdate:
+		movie.Title += " (renamed)"
+
+		err = clients[reader].rename(id, movie.Title)
+		if err != nil {
+			return failed(fmt.Errorf("rename: %w", err))
+		}
+
+		got, err = clients[writer].get(id)
+		if err != nil {
+			return failed(fmt.Errorf("get: %w", err))
+		}
+
+		// the writer reads back every field it wrote, the reader only changed the title
+		readBack = writer
+	}
+
+	for _, rule := range Matrix {
+		if !rule.carriedBy(writer) || !rule.carriedBy(readBack) {
+			continue
+		}
+
+		expected := rule.project(movie, writer, readBack)
+		actual := rule.project(got, readBack, readBack)
+
+		results = append(results, Result{
+			Strategy: strategy,
+			Check:    kind,
+			Writer:   writer,
+			Reader:   reader,
+			Field:    rule.Field,
+			Expected: expected,
+			Actual:   actual,
+			Passed:   reflect.DeepEqual(expected, actual),
+		})
+	}
+
+	return results
+}
+
+// checkCrew writes the director of a movie through the crew of v5 and compares the director
+// reader sees with the one the crew names
+func checkCrew(strategy string, kind string, reader string, clients map[string]*client) (results []Result) {
+	failed := func(err error) []Result {
+		return []Result{{Strategy: strategy, Check: kind, Writer: crewWriter, Reader: reader, Field: Director, Error: err.Error()}}
+	}
+
+	writer := clients[crewWriter]
+	movie := sample(kind, crewWriter, reader)
+
+	// the director is added through the crew of the movie once it exists
+	added := movie.Director
+	if kind == CheckCrewAdd {
+		movie.Director = nil
+	}
+
+	id, err := writer.create(movie)
+	if id > 0 {
+		defer func() {
+			err := writer.delete(id)
+			if err != nil {
+				results = append(results, failed(fmt.Errorf("delete: %w", err))...)
+			}
+		}()
+	}
+	if err != nil {
+		return failed(fmt.Errorf("create: %w", err))
+	}
+
+	var expected any
+
+	switch kind {
+	case CheckCrewClear:
+		err = writer.clearCrew(id)
+		if err != nil {
+			return failed(fmt.Errorf("clear crew: %w", err))
+		}
+	case CheckCrewAdd:
+		err = writer.addDirector(id, *added)
+		if err != nil {
+			return failed(fmt.Errorf("add director: %w", err))
+		}
+		expected = *added
+	case CheckCrewRemove:
+		err = writer.removeDirectors(id)
+		if err != nil {
+			return failed(fmt.Errorf("remove director: %w", err))
+		}
+	}
+
+	got, err := clients[reader].get(id)
+	if err != nil {
+		return failed(fmt.Errorf("get: %w", err))
+	}
+
+	actual := deref(got.Director)
+
+	return []Result{{
+		Strategy: strategy,
+		Check:    kind,
+		Writer:   crewWriter,
+		Reader:   reader,
+		Field:    Director,
+		Expected: expected,
+		Actual:   actual,
+		Passed:   reflect.DeepEqual(expected, actual),
+	}}
+}
+
+// project returns the value of the field of movie as reader should see it after it was
+// written through writer, nil if the field is not set
+func (r Rule) project(movie Movie, writer string, reader string) any {
+	switch r.Field {
+	case Title:
+		return movie.Title
+	case Year:
+		return movie.Year
+	case Genres:
+		genres := movie.Genres
+		if r.single(writer) || r.single(reader) {
+			genres = single(first(genres))
+		}
+		if len(genres) == 0 {
+			return nil
+		}
+		return genres
+	case Director:
+		return deref(movie.Director)
+	case Runtime:
+		return deref(movie.Runtime)
+	case Language:
+		return deref(movie.Language)
+	case Cast:
+		if len(movie.Cast) == 0 {
+			return nil
+		}
+		cast := append([]Role(nil), movie.Cast...)
+		sort.Slice(cast, func(i, j int) bool { return cast[i].Name < cast[j].Name })
+		return cast
+	}
+	return nil
+}
+
+func deref[T any](v *T) any {
+	if v == nil {
+		return nil
+	}
+	return *v
+}
@@ -0,0 +1,48 @@
+package conformance
+
+import "slices"
+
+// Field of a movie that is compared across versions
+type Field string
+
+const (
+	Title    Field = "title"
+	Year     Field = "year"
+	Genres   Field = "genres"
+	Director Field = "director"
+	Runtime  Field = "runtime"
+	Language Field = "language"
+	Cast     Field = "cast" // actors of v4, crew of type Actor in v5
+)
+
+// Versions in the order they were released
+var Versions = []string{"v1", "v2", "v3", "v4", "v5"}
+
+// Rule declares the versions a field is written and read through. Versions in Single carry
+// only the first value of the field, e.g. the genre of v1 and v2 is the first of the genres.
+type Rule struct {
+	Field    Field    `json:"field"`
+	Versions []string `json:"versions"`
+	Single   []string `json:"single,omitempty"`
+}
+
+// Matrix is the declared compatibility matrix every strategy must satisfy: a field written
+// through one version reads back through every other version that carries it, and an
+// update through any version leaves the fields it does not carry untouched.
+var Matrix = []Rule{
+	{Field: Title, Versions: Versions},
+	{Field: Year, Versions: Versions},
+	{Field: Genres, Versions: Versions, Single: []string{"v1", "v2"}},
+	{Field: Director, Versions: []string{"v2", "v3", "v4", "v5"}},
+	{Field: Runtime, Versions: []string{"v2", "v3", "v4", "v5"}},
+	{Field: Language, Versions: []string{"v2", "v3", "v4", "v5"}},
+	{Field: Cast, Versions: []string{"v4", "v5"}},
+}
+
+func (r Rule) carriedBy(version string) bool {
+	return slices.Contains(r.Versions, version)
+}
+
+func (r Rule) single(version string) bool {
+	return slices.Contains(r.Single, version)
+}
@@ -0,0 +1,271 @@
+package consistency
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	v5 "thesis.lefler.eu/internal/model/v5"
+	"thesis.lefler.eu/internal/repository"
+)
+
+// Resources compared, actors are people with an Actor crew entry in v5 and compared as
+// people and through the crew of their movies
+var Resources = []string{"movies", "people"}
+
+// fields of every resource in the canonical form, in the order they are compared
+var fields = map[string][]string{
+	"movies": {"title", "year", "genres", "runtime", "language", "crew"},
+	"people": {"name", "birthdate"},
+}
+
+// Record in the canonical form. Records are matched between strategies by a natural key
+// rather than by id, as the ids of people created by migrations differ between strategies.
+type Record struct {
+	Key    string
+	ID     int64
+	Fields map[string]any
+}
+
+// Snapshot of the canonical records of a strategy, by resource and key
+type Snapshot map[string]map[string]Record
+
+// Difference is a record missing in some strategies, or a field that differs between them
+type Difference struct {
+	Resource string           `json:"resource"`
+	Key      string           `json:"key"`
+	Field    string           `json:"field,omitempty"`   // empty if the record is missing
+	Missing  []string         `json:"missing,omitempty"` // strategies without the record
+	IDs      map[string]int64 `json:"ids"`
+	Values   map[string]any   `json:"values,omitempty"`
+}
+
+// Report compares the snapshots of every strategy
+type Report struct {
+	Consistent  bool                      `json:"consistent"`
+	Strategies  []string                  `json:"strategies"`
+	Records     map[string]map[string]int `json:"records"` // per strategy and resource
+	Differences []Difference              `json:"differences"`
+}
+
+// Read reads every movie with its crew and every person through the v5 repositories of a
+// strategy. A crew that cannot be read becomes the error message, so it shows as a difference.
+func Read(repos repository.RepositoriesV5) (Snapshot, error) {
+	s := Snapshot{"movies": {}, "people": {}}
+
+	movies, err := all(func(filters repository.Filters) ([]*v5.Movie, repository.Metadata, error) {
+		return repos.Movies.GetAll(repository.MovieFilter{}, filters, repository.Projection{})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("reading movies: %w", err)
+	}
+
+	for _, movie := range movies {
+		var crew any
+
+		members, err := repos.Crew.GetForMovie(movie.ID)
+		switch {
+		case errors.Is(err, repository.ErrRecordNotFound):
+			crew = []string{}
+		case err != nil:
+			crew = "error: " + err.Error()
+		default:
+			crew = canonicalCrew(members)
+		}
+
+		var runtime, language any
+		if movie.Runtime != nil {
+			runtime = *movie.Runtime
+		}
+		if movie.Language != nil {
+			language = *movie.Language
+		}
+
+		genres := movie.Genres
+		if genres == nil {
+			genres = []string{}
+		}
+
+		s.add("movies", fmt.Sprintf("%s (%d)", movie.Title, movie.Year), movie.ID, map[string]any{
+			"title":    movie.Title,
+			"year":     movie.Year,
+			"genres":   genres,
+			"runtime":  runtime,
+			"language": language,
+			"crew":     crew,
+		})
+	}
+
+	people, err := all(func(filters repository.Filters) ([]*v5.Person, repository.Metadata, error) {
+		return repos.People.GetAll(repository.PersonFilter{}, filters)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("reading people: %w", err)
+	}
+
+	for _, person := range people {
+		var birthdate any
+		key := person.Name
+		if person.Birthdate != nil {
+			birthdate = person.Birthdate.String()
+			key = fmt.Sprintf("%s (%s)", person.Name, person.Birthdate)
+		}
+
+		s.add("people", key, person.ID, map[string]any{
+			"name":      person.Name,
+			"birthdate": birthdate,
+		})
+	}
+
+	return s, nil
+}
+
+// all reads every page of a list, in the order of their ids
+func all[T any](getAll func(filters repository.Filters) ([]T, repository.Metadata, error)) ([]T, error) {
+	filters := repository.Filters{Page: 1, PageSize: repository.MaxPageSize, Sort: "id", SortSafelist: []string{"id"}}
+
+	var records []T
+	for {
+		page, metadata, err := getAll(filters)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, page...)
+
+		if filters.Page >= metadata.LastPage {
+			return records, nil
+		}
+		filters.Page++
+	}
+}
+
+// add stores a record, records sharing a natural key are numbered in the order they were read
+func (s Snapshot) add(resource string, key string, id int64, fields map[string]any) {
+	unique := key
+	for n := 2; ; n++ {
+		if _, ok := s[resource][unique]; !ok {
+			break
+		}
+		unique = fmt.Sprintf("%s #%d", key, n)
+	}
+
+	s[resource][unique] = Record{Key: unique, ID: id, Fields: fields}
+}
+
+// canonicalCrew describes every crew member by name rather than person id, sorted
+func canonicalCrew(members []*v5.Crew) []string {
+	crew := make([]string, 0, len(members))
+	for _, member := range members {
+		entry := member.CrewType + ": " + member.PersonName
+		if member.Role != "" {
+			entry += " as " + member.Role
+		}
+		crew = append(crew, entry)
+	}
+
+	slices.Sort(crew)
+
+	return crew
+}
+
+// Compare reports every record missing in a strategy and every field that differs
+func Compare(snapshots map[string]Snapshot, strategies []string) Report {
+	r := Report{
+		Consistent:  true,
+		Strategies:  strategies,
+		Records:     make(map[string]map[string]int),
+		Differences: []Difference{},
+	}
+
+	for _, strategy := range strategies {
+		r.Records[strategy] = make(map[string]int)
+		for _, resource := range Resources {
+			r.Records[strategy][resource] = len(snapshots[strategy][resource])
+		}
+	}
+
+	for _, resource := range Resources {
+		keys := make(map[string]bool)
+		for _, strategy := range strategies {
+			for key := range snapshots[strategy][resource] {
+				keys[key] = true
+			}
+		}
+
+		for _, key := range slices.Sorted(maps.Keys(keys)) {
+			ids := make(map[string]int64)
+			records := make(map[string]Record)
+			var missing []string
+
+			for _, strategy := range strategies {
+				record, ok := snapshots[strategy][resource][key]
+				if !ok {
+					missing = append(missing, strategy)
+					continue
+				}
+				ids[strategy] = record.ID
+				records[strategy] = record
+			}
+
+			if len(missing) > 0 {
+				r.Differences = append(r.Differences, Difference{Resource: resource, Key: key, Missing: missing, IDs: ids})
+			}
+
+			// fields are compared between the strategies that have the record
+			for _, field := range fields[resource] {
+				values := make(map[string]any)
+				encoded := make(map[string][]byte)
+
+				for strategy, record := range records {
+					values[strategy] = record.Fields[field]
+					encoded[strategy], _ = json.Marshal(record.Fields[field])
+				}
+
+				if !allEqual(encoded) {
+					r.Differences = append(r.Differences, Difference{Resource: resource, Key: key, Field: field, IDs: ids, Values: values})
+				}
+			}
+		}
+	}
+
+	r.Consistent = len(r.Differences) == 0
+
+	return r
+}
+
+func allEqual(encoded map[string][]byte) bool {
+	var first []byte
+	for _, value := range encoded {
+		if first == nil {
+			first = value
+			continue
+		}
+		if !bytes.Equal(first, value) {
+			return false
+		}
+	}
+	return true
+}
+
+// Summary of the differences by resource and field, e.g. "movies.crew: 3"
+func (r Report) Summary() string {
+	counts := make(map[string]int)
+	for _, d := range r.Differences {
+		field := d.Field
+		if field == "" {
+			field = "missing"
+		}
+		counts[d.Resource+"."+field]++
+	}
+
+	parts := []string{}
+	for _, key := range slices.Sorted(maps.Keys(counts)) {
+		parts = append(parts, fmt.Sprintf("%s: %d", key, counts[key]))
+	}
+
+	return strings.Join(parts, ", ")
+}
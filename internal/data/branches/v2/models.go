@@ -2,17 +2,16 @@ package v2
 
 import (
 	"database/sql"
-	"errors"
+
+	"thesis.lefler.eu/internal/repository"
 )
 
 var (
-	ErrRecordNotFound = errors.New("record not found")
-	ErrEditConflict   = errors.New("edit conflict")
+	ErrRecordNotFound = repository.ErrRecordNotFound
+	ErrEditConflict   = repository.ErrEditConflict
 )
 
-type Models struct {
-	Movies MovieModel
-}
+type Models = repository.RepositoriesV2
 
 func NewModels(db *sql.DB) Models {
 	return Models{
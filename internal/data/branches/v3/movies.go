@@ -4,55 +4,21 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"fmt"
 	"time"
 
 	"github.com/lib/pq"
+	model "thesis.lefler.eu/internal/model/v3"
+	"thesis.lefler.eu/internal/repository"
 	"thesis.lefler.eu/internal/util"
-	"thesis.lefler.eu/internal/validator"
 )
 
-// V2 fields still as pointers to allow for null values from pre-v2 records
-// genres does not need to be a pointer as it is a slice and can be nil
-type Movie struct {
-	ID        int64     `json:"id"`                 // Unique identifier
-	CreatedAt time.Time `json:"-"`                  // Timestamp of when the movie was added to the database
-	UpdatedAt time.Time `json:"-"`                  // Timestamp of when the movie record was last updated
-	Title     string    `json:"title"`              // Title of the movie
-	Year      int32     `json:"year,omitempty"`     // Release year
-	Genres    []string  `json:"genres,omitempty"`   // Slice of genres
-	Director  *string   `json:"director,omitempty"` // Director name
-	Runtime   *int32    `json:"runtime,omitempty"`  // Runtime in minutes
-	Language  *string   `json:"language,omitempty"` // Language
-	Version   int32     `json:"version"`            // Version number, starts at 1 and increments each time the movie is updated
-}
+type Movie = model.Movie
 
 type MovieModel struct {
 	DB *sql.DB
 }
 
-func ValidateMovie(v *validator.Validator, movie *Movie) {
-	v.Check(movie.Title != "", "title", "must be provided")
-	v.Check(len(movie.Title) <= 500, "title", "must not be more than 500 bytes long")
-
-	v.Check(movie.Year != 0, "year", "must be provided")
-	v.Check(movie.Year >= 1888, "year", "must be greater than 1888")
-	v.Check(movie.Year <= int32(time.Now().Year()), "year", "must not be in the future")
-
-	v.Check(movie.Genres != nil, "genres", "must be provided")
-	v.Check(len(movie.Genres) >= 1, "genres", "must contain at least 1 genre")
-	v.Check(len(movie.Genres) <= 5, "genres", "must not contain more than 5 genres")
-	v.Check(validator.Unique(movie.Genres), "genres", "must not contain duplicate values")
-
-	v.Check(*movie.Director != "", "director", "must be provided")
-	v.Check(len(*movie.Director) <= 100, "director", "must not be more than 100 bytes long")
-
-	v.Check(*movie.Runtime != 0, "runtime", "must be provided")
-	v.Check(*movie.Runtime > 0, "runtime", "must be a positive integer")
-
-	v.Check(*movie.Language != "", "language", "must be provided")
-	v.Check(len(*movie.Language) <= 50, "language", "must not be more than 50 bytes long")
-}
-
 func (m MovieModel) Insert(movie *Movie) error {
 	query := `
         WITH movie_insert AS (
@@ -127,24 +93,46 @@ func (m MovieModel) Get(id int64) (*Movie, error) {
 	return &movie, nil
 }
 
-func (m MovieModel) GetAll() ([]*Movie, error) {
-	query := `
-        SELECT m.id, created_at, updated_at, title, release_year, genre, v3.genres, v2.director, v2.runtime, v2.language, version
+// movieSortColumns maps the sort fields of the API to the columns of the list query,
+// relevance ranks by the search in $5, negated as the most relevant come first
+var movieSortColumns = map[string]string{
+	"id":        "m.id",
+	"title":     "title",
+	"year":      "release_year",
+	"runtime":   "v2.runtime",
+	"relevance": "-ts_rank(to_tsvector('simple', title), to_tsquery('simple', $5))",
+}
+
+func (m MovieModel) GetAll(filter repository.MovieFilter, filters repository.Filters) ([]*Movie, repository.Metadata, error) {
+	keyset, orderBy, limit, keysetArgs := filters.Keyset(movieSortColumns, "m.id", 6)
+
+	query := fmt.Sprintf(`
+        SELECT count(*) OVER(), m.id, created_at, updated_at, title, release_year, genre, v3.genres, v2.director, v2.runtime, v2.language, version
 				FROM movies m
 				LEFT JOIN movies_branch_v2 v2 ON m.id = v2.id
 				LEFT JOIN movies_branch_v3 v3 ON m.id = v3.id
-				ORDER BY m.id`
+				WHERE strpos(lower(title), lower($1)) > 0
+				AND (coalesce(v3.genres, '{}') || genre) @> $2
+				AND ($3 = 0 OR release_year >= $3)
+				AND ($4 = 0 OR release_year <= $4)
+				AND ($5 = '' OR to_tsvector('simple', title) @@ to_tsquery('simple', $5))
+				AND %s
+				ORDER BY %s
+				%s`, keyset, orderBy, limit)
+
+	args := append([]any{filter.Title, pq.Array(filter.Genres), filter.YearFrom, filter.YearTo, repository.SearchQuery(filter.Query)}, keysetArgs...)
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	rows, err := m.DB.QueryContext(ctx, query)
+	rows, err := m.DB.QueryContext(ctx, query, args...)
 	if err != nil {
-		return nil, err
+		return nil, repository.Metadata{}, err
 	}
 
 	defer rows.Close()
 
+	totalRecords := 0
 	movies := []*Movie{}
 
 	for rows.Next() {
@@ -152,6 +140,7 @@ func (m MovieModel) GetAll() ([]*Movie, error) {
 		var genre string
 
 		err := rows.Scan(
+			&totalRecords,
 			&movie.ID,
 			&movie.CreatedAt,
 			&movie.UpdatedAt,
@@ -165,7 +154,7 @@ func (m MovieModel) GetAll() ([]*Movie, error) {
 			&movie.Version,
 		)
 		if err != nil {
-			return nil, err
+			return nil, repository.Metadata{}, err
 		}
 
 		movie.Genres = util.MergeGenres(genre, movie.Genres)
@@ -174,10 +163,12 @@ func (m MovieModel) GetAll() ([]*Movie, error) {
 	}
 
 	if err = rows.Err(); err != nil {
-		return nil, err
+		return nil, repository.Metadata{}, err
 	}
 
-	return movies, nil
+	movies, metadata := repository.Paginate(movies, totalRecords, filters)
+
+	return movies, metadata, nil
 }
 
 func (m MovieModel) Update(movie *Movie) error {
@@ -4,34 +4,21 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"fmt"
 	"time"
 
 	"cloud.google.com/go/civil"
 
-	"thesis.lefler.eu/internal/validator"
+	model "thesis.lefler.eu/internal/model/v4"
+	"thesis.lefler.eu/internal/repository"
 )
 
-type Actor struct {
-	ID        int64       `json:"id"`                  // Unique identifier
-	CreatedAt time.Time   `json:"-"`                   // Timestamp of when the movie was added to the database
-	UpdatedAt time.Time   `json:"-"`                   // Timestamp of when the movie record was last updated
-	Name      string      `json:"name"`                // Actor name
-	Birthdate *civil.Date `json:"birthdate,omitempty"` // Actor birthdate
-	Version   int32       `json:"version"`             // Version number, starts at 1 and increments each time the movie is updated
-}
+type Actor = model.Actor
 
 type ActorModel struct {
 	DB *sql.DB
 }
 
-func ValidateActor(v *validator.Validator, actor *Actor) {
-	v.Check(actor.Name != "", "name", "must be provided")
-	v.Check(len(actor.Name) <= 500, "name", "must not be more than 500 bytes long")
-
-	v.Check(actor.Birthdate.IsValid(), "birthdate", "is not valid")
-	v.Check(actor.Birthdate.Before(civil.DateOf(time.Now())), "birthdate", "must not be in the future")
-}
-
 func (m ActorModel) Insert(actor *Actor) error {
 	query := `
 				INSERT INTO actors (name, birthdate) 
@@ -88,21 +75,39 @@ func (m ActorModel) Get(id int64) (*Actor, error) {
 	return &actor, nil
 }
 
-func (m ActorModel) GetAll() ([]*Actor, error) {
-	query := `
-		SELECT id, created_at, updated_at, name, birthdate, version
+// actorSortColumns maps the sort fields of the API to the columns of the list query,
+// relevance ranks by the search in $2, negated as the most relevant come first
+var actorSortColumns = map[string]string{
+	"id":        "id",
+	"name":      "name",
+	"birthdate": "birthdate",
+	"relevance": "-ts_rank(to_tsvector('simple', name), to_tsquery('simple', $2))",
+}
+
+func (m ActorModel) GetAll(filter repository.PersonFilter, filters repository.Filters) ([]*Actor, repository.Metadata, error) {
+	keyset, orderBy, limit, keysetArgs := filters.Keyset(actorSortColumns, "id", 3)
+
+	query := fmt.Sprintf(`
+		SELECT count(*) OVER(), id, created_at, updated_at, name, birthdate, version
 		FROM actors
-		ORDER BY id`
+		WHERE strpos(lower(name), lower($1)) > 0
+		AND ($2 = '' OR to_tsvector('simple', name) @@ to_tsquery('simple', $2))
+		AND %s
+		ORDER BY %s
+		%s`, keyset, orderBy, limit)
+
+	args := append([]any{filter.Name, repository.SearchQuery(filter.Query)}, keysetArgs...)
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	rows, err := m.DB.QueryContext(ctx, query)
+	rows, err := m.DB.QueryContext(ctx, query, args...)
 	if err != nil {
-		return nil, err
+		return nil, repository.Metadata{}, err
 	}
 	defer rows.Close()
 
+	totalRecords := 0
 	actors := []*Actor{}
 
 	for rows.Next() {
@@ -110,6 +115,7 @@ func (m ActorModel) GetAll() ([]*Actor, error) {
 		var birthdate *time.Time
 
 		err := rows.Scan(
+			&totalRecords,
 			&actor.ID,
 			&actor.CreatedAt,
 			&actor.UpdatedAt,
@@ -118,7 +124,7 @@ func (m ActorModel) GetAll() ([]*Actor, error) {
 			&actor.Version)
 
 		if err != nil {
-			return nil, err
+			return nil, repository.Metadata{}, err
 		}
 
 		if birthdate != nil {
@@ -129,10 +135,12 @@ func (m ActorModel) GetAll() ([]*Actor, error) {
 	}
 
 	if err = rows.Err(); err != nil {
-		return nil, err
+		return nil, repository.Metadata{}, err
 	}
 
-	return actors, nil
+	actors, metadata := repository.Paginate(actors, totalRecords, filters)
+
+	return actors, metadata, nil
 }
 
 func (m ActorModel) Update(actor *Actor) error {
@@ -2,19 +2,16 @@ package v4
 
 import (
 	"database/sql"
-	"errors"
+
+	"thesis.lefler.eu/internal/repository"
 )
 
 var (
-	ErrRecordNotFound = errors.New("record not found")
-	ErrEditConflict   = errors.New("edit conflict")
+	ErrRecordNotFound = repository.ErrRecordNotFound
+	ErrEditConflict   = repository.ErrEditConflict
 )
 
-type Models struct {
-	Movies      MovieModel
-	Actors      ActorModel
-	MovieActors MovieActorModel
-}
+type Models = repository.RepositoriesV4
 
 func NewModels(db *sql.DB) Models {
 	return Models{
@@ -3,34 +3,18 @@ package v4
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"time"
 
-	"thesis.lefler.eu/internal/validator"
+	model "thesis.lefler.eu/internal/model/v4"
 )
 
-type MovieActor struct {
-	MovieID   int64     `json:"-"`
-	ActorID   int64     `json:"actor_id"`
-	ActorName string    `json:"actor_name"`
-	Role      string    `json:"role"`
-	CreatedAt time.Time `json:"-"`
-	UpdatedAt time.Time `json:"-"`
-	Version   int32     `json:"-"`
-}
+type MovieActor = model.MovieActor
 
 type MovieActorModel struct {
 	DB *sql.DB
 }
 
-func ValidateCrew(v *validator.Validator, movieActor *MovieActor) {
-	v.Check(movieActor.MovieID > 0, "movie_id", "must be a positive integer")
-	v.Check(movieActor.ActorID > 0, "actor_id", "must be a positive integer")
-
-	v.Check(movieActor.Role != "", "role", "must be provided")
-	v.Check(len(movieActor.Role) <= 500, "role", "must not be more than 500 bytes long")
-
-}
-
 func (m MovieActorModel) Insert(movieActor *MovieActor) error {
 	query := `
 		INSERT INTO movie_actors (movie_id, actor_id, role)
@@ -45,6 +29,43 @@ func (m MovieActorModel) Insert(movieActor *MovieActor) error {
 	return m.DB.QueryRowContext(ctx, query, args...).Scan(&movieActor.CreatedAt, &movieActor.UpdatedAt, &movieActor.Version)
 }
 
+func (m MovieActorModel) Get(movieID, actorID int64) (*MovieActor, error) {
+	if movieID < 1 || actorID < 1 {
+		return nil, ErrRecordNotFound
+	}
+
+	query := `
+		SELECT ma.movie_id, ma.actor_id, a.name AS actor_name, ma.role, ma.created_at, ma.updated_at, ma.version
+		FROM movie_actors ma
+		LEFT JOIN actors a ON ma.actor_id = a.id
+		WHERE ma.movie_id = $1 AND ma.actor_id = $2`
+
+	var movieActor MovieActor
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, movieID, actorID).Scan(
+		&movieActor.MovieID,
+		&movieActor.ActorID,
+		&movieActor.ActorName,
+		&movieActor.Role,
+		&movieActor.CreatedAt,
+		&movieActor.UpdatedAt,
+		&movieActor.Version)
+
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &movieActor, nil
+}
+
 func (m MovieActorModel) GetForMovie(movieID int64) ([]*MovieActor, error) {
 	if movieID < 1 {
 		return nil, ErrRecordNotFound
@@ -93,6 +114,60 @@ func (m MovieActorModel) GetForMovie(movieID int64) ([]*MovieActor, error) {
 	return movieActors, nil
 }
 
+func (m MovieActorModel) Update(movieActor *MovieActor) error {
+	query := `
+		UPDATE movie_actors
+		SET role = $1, version = version + 1
+		WHERE movie_id = $2 AND actor_id = $3 AND version = $4
+		RETURNING updated_at, version`
+
+	args := []interface{}{movieActor.Role, movieActor.MovieID, movieActor.ActorID, movieActor.Version}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&movieActor.UpdatedAt, &movieActor.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (m MovieActorModel) Delete(movieActor *MovieActor) error {
+	if movieActor.MovieID < 1 || movieActor.ActorID < 1 {
+		return ErrRecordNotFound
+	}
+
+	query := `
+		DELETE FROM movie_actors
+		WHERE movie_id = $1 AND actor_id = $2 AND version = $3`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, movieActor.MovieID, movieActor.ActorID, movieActor.Version)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrEditConflict
+	}
+
+	return nil
+}
+
 func (m MovieActorModel) DeleteForMovie(movieID int64) error {
 	if movieID < 1 {
 		return ErrRecordNotFound
@@ -3,46 +3,36 @@ package v5
 import (
 	"context"
 	"database/sql"
-	"strings"
+	"errors"
+	"fmt"
 	"time"
 
-	"thesis.lefler.eu/internal/validator"
+	"github.com/lib/pq"
+
+	model "thesis.lefler.eu/internal/model/v5"
 )
 
-type Crew struct {
-	MovieID    int64     `json:"-"`
-	PersonID   int64     `json:"person_id"`
-	PersonName string    `json:"person_name"`
-	CrewType   string    `json:"crew_type"`
-	Role       string    `json:"role,omitempty"`
-	CreatedAt  time.Time `json:"-"`
-	UpdatedAt  time.Time `json:"-"`
-	Version    int32     `json:"-"`
-}
+type Crew = model.Crew
 
 type CrewModel struct {
 	DB *sql.DB
 }
 
-func ValidateCrew(v *validator.Validator, crew *Crew) {
-	v.Check(crew.PersonID > 0, "person_id", "must be a positive integer")
-
-	v.Check(crew.CrewType != "", "crew_type", "must be provided")
-	v.Check(len(crew.CrewType) <= 500, "crew_type", "must not be more than 500 bytes long")
-	v.Check(validator.PermittedValue(crew.CrewType, "Actor", "Director", "Producer"), "crew_type", "must be either 'Actor', 'Director', or 'Producer'")
-
-	if strings.ToLower(crew.CrewType) == "actor" {
-		v.Check(crew.Role != "", "role", "must be provided if crew_type is 'actor'")
-		v.Check(len(crew.Role) <= 500, "role", "must not be more than 500 bytes long")
-	}
-}
-
+// Insert adds the member to the crew and, for actors, to movie_actors. A director is written as
+// the director of v2 to v4 unless the movie has one in its crew already.
 func (m CrewModel) Insert(crew *Crew) error {
 	query := `
 		WITH old AS (
 			INSERT INTO movie_actors (movie_id, actor_id, role)
 			SELECT $1, (SELECT old_actor_id FROM people WHERE id = $2), $4
 			WHERE EXISTS (SELECT 1 WHERE $3 = 'Actor')
+		), director AS (
+			INSERT INTO movies_branch_v2 (id, director)
+			SELECT $1, name FROM people
+			WHERE id = $2 AND $3 = 'Director'
+				AND NOT EXISTS (SELECT 1 FROM crew WHERE movie_id = $1 AND crew_type = 'Director')
+			ON CONFLICT (id) DO UPDATE
+				SET director = EXCLUDED.director
 		)
 		INSERT INTO crew (movie_id, person_id, crew_type, role)
 			VALUES ($1, $2, $3, $4)
@@ -56,27 +46,88 @@ func (m CrewModel) Insert(crew *Crew) error {
 	return m.DB.QueryRowContext(ctx, query, args...).Scan(&crew.CreatedAt, &crew.UpdatedAt, &crew.Version)
 }
 
+// Get reads a member from the crew, or an actor of v4 that is only in movie_actors
+func (m CrewModel) Get(movieID, personID int64, crewType string) (*Crew, error) {
+	if movieID < 1 || personID < 1 {
+		return nil, ErrRecordNotFound
+	}
+
+	query := `
+		SELECT movie_id, person_id, person_name, crew_type, role, created_at, updated_at, version
+		FROM (
+			SELECT c.movie_id, c.person_id, p.name AS person_name, c.crew_type, c.role, c.created_at, c.updated_at, c.version, 1 AS source
+				FROM crew c
+				LEFT JOIN people p ON c.person_id = p.id
+				WHERE c.movie_id = $1 AND c.person_id = $2 AND c.crew_type = $3
+			UNION ALL
+			SELECT ma.movie_id, p.id AS person_id, p.name AS person_name, 'Actor' AS crew_type, ma.role, ma.created_at, ma.updated_at, ma.version, 2 AS source
+				FROM movie_actors ma
+				JOIN people p ON ma.actor_id = p.old_actor_id
+				WHERE ma.movie_id = $1 AND p.id = $2 AND $3 = 'Actor'
+		) member
+		ORDER BY source
+		LIMIT 1`
+
+	var crew Crew
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, movieID, personID, crewType).Scan(
+		&crew.MovieID,
+		&crew.PersonID,
+		&crew.PersonName,
+		&crew.CrewType,
+		&crew.Role,
+		&crew.CreatedAt,
+		&crew.UpdatedAt,
+		&crew.Version)
+
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &crew, nil
+}
+
 func (m CrewModel) GetForMovie(movieID int64) ([]*Crew, error) {
 	if movieID < 1 {
 		return nil, ErrRecordNotFound
 	}
+
+	return m.GetForMovies([]int64{movieID}, true)
+}
+
+func (m CrewModel) GetForMovies(movieIDs []int64, names bool) ([]*Crew, error) {
+	// people are joined to the crew only for their names, to the actors of v4 for their ids as well
+	name, people, actorName, actors := "''", "", "''", ""
+	if names {
+		name, people = "p.name", "LEFT JOIN people p ON c.person_id = p.id"
+		actorName, actors = "COALESCE(p.name, a.name)", "LEFT JOIN actors a ON ma.actor_id = a.id"
+	}
+
 	// postgres union eliminates duplicates, but the potential null ID is a problem, we should take care of forward synchronization of actors to crew using a trigger or versioning
-	query := `
-		SELECT c.movie_id, c.person_id, p.name AS person_name, c.crew_type, c.role, c.created_at, c.updated_at, c.version
+	query := fmt.Sprintf(`
+		SELECT c.movie_id, c.person_id, %s AS person_name, c.crew_type, c.role, c.created_at, c.updated_at, c.version
 			FROM crew c
-			LEFT JOIN people p ON c.person_id = p.id
-			WHERE c.movie_id = $1
+			%s
+			WHERE c.movie_id = ANY($1)
 		UNION
-		SELECT ma.movie_id, COALESCE(p.id, NULL) AS person_id, COALESCE(p.name, a.name) AS person_name, 'Actor' AS crew_type, ma.role, ma.created_at, ma.updated_at, ma.version
+		SELECT ma.movie_id, COALESCE(p.id, NULL) AS person_id, %s AS person_name, 'Actor' AS crew_type, ma.role, ma.created_at, ma.updated_at, ma.version
 			FROM movie_actors ma
 			LEFT JOIN people p ON ma.actor_id = p.old_actor_id
-			LEFT JOIN actors a ON ma.actor_id = a.id
-			WHERE ma.movie_id = $1`
+			%s
+			WHERE ma.movie_id = ANY($1)`, name, people, actorName, actors)
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	rows, err := m.DB.QueryContext(ctx, query, movieID)
+	rows, err := m.DB.QueryContext(ctx, query, pq.Array(movieIDs))
 	if err != nil {
 		return nil, err
 	}
@@ -111,6 +162,96 @@ func (m CrewModel) GetForMovie(movieID int64) ([]*Crew, error) {
 	return crews, nil
 }
 
+// Update writes the role to the crew and, for actors, to movie_actors. The version is the one
+// of the crew, or of movie_actors for actors of v4 that are not in the crew, as Get reads it.
+func (m CrewModel) Update(crew *Crew) error {
+	query := `
+		WITH member AS (
+			SELECT version FROM crew WHERE movie_id = $2 AND person_id = $3 AND crew_type = $4
+		), old AS (
+			UPDATE movie_actors
+			SET role = $1, version = version + 1
+			WHERE movie_id = $2 AND actor_id = (SELECT old_actor_id FROM people WHERE id = $3) AND $4 = 'Actor'
+				AND (EXISTS (SELECT 1 FROM member WHERE version = $5) OR (version = $5 AND NOT EXISTS (SELECT 1 FROM member)))
+			RETURNING updated_at, version, 2 AS source
+		), new AS (
+			UPDATE crew
+			SET role = $1, version = version + 1
+			WHERE movie_id = $2 AND person_id = $3 AND crew_type = $4 AND version = $5
+			RETURNING updated_at, version, 1 AS source
+		)
+		SELECT updated_at, version
+		FROM (SELECT * FROM new UNION ALL SELECT * FROM old) updated
+		ORDER BY source
+		LIMIT 1`
+
+	args := []interface{}{crew.Role, crew.MovieID, crew.PersonID, crew.CrewType, crew.Version}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&crew.UpdatedAt, &crew.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Delete removes the member from the crew and, for actors, from movie_actors, at the version
+// Update expects. The director of v2 to v4 falls back to the next director in the crew, or none.
+func (m CrewModel) Delete(crew *Crew) error {
+	if crew.MovieID < 1 || crew.PersonID < 1 {
+		return ErrRecordNotFound
+	}
+
+	query := `
+		WITH member AS (
+			SELECT version FROM crew WHERE movie_id = $1 AND person_id = $2 AND crew_type = $3
+		), old AS (
+			DELETE FROM movie_actors
+			WHERE movie_id = $1 AND actor_id = (SELECT old_actor_id FROM people WHERE id = $2) AND $3 = 'Actor'
+				AND (EXISTS (SELECT 1 FROM member WHERE version = $4) OR (version = $4 AND NOT EXISTS (SELECT 1 FROM member)))
+			RETURNING 1
+		), new AS (
+			DELETE FROM crew
+			WHERE movie_id = $1 AND person_id = $2 AND crew_type = $3 AND version = $4
+			RETURNING 1
+		), director AS (
+			UPDATE movies_branch_v2
+			SET director = (
+				SELECT p.name
+				FROM crew c
+				JOIN people p ON c.person_id = p.id
+				WHERE c.movie_id = $1 AND c.crew_type = 'Director' AND c.person_id <> $2
+				ORDER BY c.created_at, c.person_id
+				LIMIT 1)
+			WHERE id = $1 AND $3 = 'Director' AND EXISTS (SELECT 1 FROM new)
+		)
+		SELECT (SELECT count(*) FROM new) + (SELECT count(*) FROM old)`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var rowsAffected int64
+
+	err := m.DB.QueryRowContext(ctx, query, crew.MovieID, crew.PersonID, crew.CrewType, crew.Version).Scan(&rowsAffected)
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrEditConflict
+	}
+
+	return nil
+}
+
 func (m CrewModel) DeleteForMovie(movieID int64) error {
 	if movieID < 1 {
 		return ErrRecordNotFound
@@ -4,53 +4,35 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"fmt"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/lib/pq"
-	"thesis.lefler.eu/internal/util"
-	"thesis.lefler.eu/internal/validator"
+	model "thesis.lefler.eu/internal/model/v5"
+	"thesis.lefler.eu/internal/repository"
 )
 
-// V2 fields still as pointers to allow for null values from pre-v2 records
-// genres does not need to be a pointer as it is a slice and can be nil
-type Movie struct {
-	ID        int64     `json:"id"`                 // Unique identifier
-	CreatedAt time.Time `json:"-"`                  // Timestamp of when the movie was added to the database
-	UpdatedAt time.Time `json:"-"`                  // Timestamp of when the movie record was last updated
-	Title     string    `json:"title"`              // Title of the movie
-	Year      int32     `json:"year,omitempty"`     // Release year
-	Genres    []string  `json:"genres,omitempty"`   // Slice of genres
-	Runtime   *int32    `json:"runtime,omitempty"`  // Runtime in minutes
-	Language  *string   `json:"language,omitempty"` // Language
-	Crew      []*Crew   `json:"crew,omitempty"`     // Slice of crew
-	Version   int32     `json:"version"`            // Version number, starts at 1 and increments each time the movie is updated
-}
+type Movie = model.Movie
 
 type MovieModel struct {
 	DB *sql.DB
 }
 
-func ValidateMovie(v *validator.Validator, movie *Movie) {
-	v.Check(movie.Title != "", "title", "must be provided")
-	v.Check(len(movie.Title) <= 500, "title", "must not be more than 500 bytes long")
-
-	v.Check(movie.Year != 0, "year", "must be provided")
-	v.Check(movie.Year >= 1888, "year", "must be greater than 1888")
-	v.Check(movie.Year <= int32(time.Now().Year()), "year", "must not be in the future")
-
-	v.Check(movie.Genres != nil, "genres", "must be provided")
-	v.Check(len(movie.Genres) >= 1, "genres", "must contain at least 1 genre")
-	v.Check(len(movie.Genres) <= 5, "genres", "must not contain more than 5 genres")
-	v.Check(validator.Unique(movie.Genres), "genres", "must not contain duplicate values")
-
-	v.Check(*movie.Runtime != 0, "runtime", "must be provided")
-	v.Check(*movie.Runtime > 0, "runtime", "must be a positive integer")
-
-	v.Check(*movie.Language != "", "language", "must be provided")
-	v.Check(len(*movie.Language) <= 50, "language", "must not be more than 50 bytes long")
+// director returns the name of the first director in the crew of movie, which is still written
+// for clients of v2 to v4, or nil if the crew names none. Update keeps the stored director unless
+// the crew of movie is set, a crew without a director clears it.
+func director(movie *Movie) *string {
+	for _, crew := range movie.Crew {
+		if crew.CrewType == "Director" {
+			return &crew.PersonName
+		}
+	}
+	return nil
 }
 
-func (m MovieModel) Insert(movie *Movie, director string) error {
+func (m MovieModel) Insert(movie *Movie) error {
 	query := `
         WITH movie_insert AS (
 					INSERT INTO movies (title, release_year, genre) 
@@ -64,13 +46,13 @@ func (m MovieModel) Insert(movie *Movie, director string) error {
 				),
 				branch_v2_insert AS (
 					INSERT INTO movies_branch_v2 (id, director, runtime, language)
-					SELECT id, $5, $6, $7
+					SELECT id, $7, $5, $6
 					FROM movie_insert
 				)
 				SELECT id, created_at, updated_at, version
 				FROM movie_insert m`
 
-	args := []any{movie.Title, movie.Year, movie.Genres[0], pq.Array(movie.Genres), movie.Runtime, movie.Language, director}
+	args := []any{movie.Title, movie.Year, movie.Genres[0], pq.Array(movie.Genres), movie.Runtime, movie.Language, director(movie)}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
@@ -78,38 +60,55 @@ func (m MovieModel) Insert(movie *Movie, director string) error {
 	return m.DB.QueryRowContext(ctx, query, args...).Scan(&movie.ID, &movie.CreatedAt, &movie.UpdatedAt, &movie.Version)
 }
 
+// movieColumns maps the fields of a movie to the columns the queries read them from and the
+// branch tables they are in, the genre of v1 and v2 is merged into the genres like
+// util.MergeGenres does
+var movieColumns = repository.Columns[Movie]{
+	{Field: "id", Expr: "m.id", Dest: func(m *Movie) any { return &m.ID }},
+	{Expr: "created_at", Dest: func(m *Movie) any { return &m.CreatedAt }},
+	{Expr: "updated_at", Dest: func(m *Movie) any { return &m.UpdatedAt }},
+	{Field: "title", Expr: "title", Dest: func(m *Movie) any { return &m.Title }},
+	{Field: "year", Expr: "release_year", Dest: func(m *Movie) any { return &m.Year }},
+	{Field: "genres", Expr: "CASE WHEN genre IS NULL OR genre = ANY(v3.genres) THEN v3.genres ELSE array_append(v3.genres, genre) END", Join: "v3", Dest: func(m *Movie) any { return pq.Array(&m.Genres) }},
+	{Field: "runtime", Expr: "v2.runtime", Join: "v2", Dest: func(m *Movie) any { return &m.Runtime }},
+	{Field: "language", Expr: "v2.language", Join: "v2", Dest: func(m *Movie) any { return &m.Language }},
+	{Field: "version", Expr: "version", Dest: func(m *Movie) any { return &m.Version }},
+}
+
+// branches joins the branch tables the columns are in, and the ones named, to movies m
+func branches(columns repository.Columns[Movie], named ...string) string {
+	var joins []string
+	for _, branch := range []string{"v2", "v3"} {
+		if columns.Joins(branch) || slices.Contains(named, branch) {
+			joins = append(joins, fmt.Sprintf("LEFT JOIN movies_branch_%s %s ON m.id = %s.id", branch, branch, branch))
+		}
+	}
+	return strings.Join(joins, "\n")
+}
+
 func (m MovieModel) Get(id int64) (*Movie, error) {
+	return m.Select(id, repository.Projection{})
+}
+
+func (m MovieModel) Select(id int64, projection repository.Projection) (*Movie, error) {
 	if id < 1 {
 		return nil, ErrRecordNotFound
 	}
 
-	query := `
-        SELECT m.id, created_at, updated_at, title, release_year, genre, v3.genres, v2.director, v2.runtime, v2.language, version
+	columns := repository.Select(movieColumns, projection)
+
+	query := fmt.Sprintf(`
+        SELECT %s
 				FROM movies m
-				LEFT JOIN movies_branch_v2 v2 ON m.id = v2.id
-				LEFT JOIN movies_branch_v3 v3 ON m.id = v3.id
-				WHERE m.id = $1`
+				%s
+				WHERE m.id = $1`, columns.List(), branches(columns))
 
 	var movie Movie
-	var genre string
-	var director string
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	err := m.DB.QueryRowContext(ctx, query, id).Scan(
-		&movie.ID,
-		&movie.CreatedAt,
-		&movie.UpdatedAt,
-		&movie.Title,
-		&movie.Year,
-		&genre,
-		pq.Array(&movie.Genres),
-		&movie.Runtime,
-		&movie.Language,
-		&director,
-		&movie.Version,
-	)
+	err := m.DB.QueryRowContext(ctx, query, id).Scan(columns.Dest(&movie)...)
 
 	if err != nil {
 		switch {
@@ -119,64 +118,84 @@ func (m MovieModel) Get(id int64) (*Movie, error) {
 			return nil, err
 		}
 	}
-	movie.Genres = util.MergeGenres(genre, movie.Genres)
-	movie.Crew = []*Crew{}
 
 	return &movie, nil
 }
 
-func (m MovieModel) GetAll() ([]*Movie, error) {
-	query := `
-        SELECT m.id, created_at, updated_at, title, release_year, genre, v3.genres, v2.director, v2.runtime, v2.language, version
+// movieSortColumns maps the sort fields of the API to the columns of the list query,
+// relevance ranks by the search in $5, negated as the most relevant come first
+var movieSortColumns = map[string]string{
+	"id":        "m.id",
+	"title":     "title",
+	"year":      "release_year",
+	"runtime":   "v2.runtime",
+	"relevance": "-ts_rank(to_tsvector('simple', title), to_tsquery('simple', $5))",
+}
+
+func (m MovieModel) GetAll(filter repository.MovieFilter, filters repository.Filters, projection repository.Projection) ([]*Movie, repository.Metadata, error) {
+	keyset, orderBy, limit, keysetArgs := filters.Keyset(movieSortColumns, "m.id", 6)
+	columns := repository.Select(movieColumns, projection.Sorted(filters))
+
+	// branch tables are only joined for the fields read, the sort and the genres filter
+	var named []string
+	genres := "cardinality($2::text[]) = 0"
+	if len(filter.Genres) > 0 {
+		named = append(named, "v3")
+		genres = "(coalesce(v3.genres, '{}') || genre) @> $2"
+	}
+	if filters.SortField() == "runtime" {
+		named = append(named, "v2")
+	}
+
+	query := fmt.Sprintf(`
+        SELECT count(*) OVER(), %s
 				FROM movies m
-				LEFT JOIN movies_branch_v2 v2 ON m.id = v2.id
-				LEFT JOIN movies_branch_v3 v3 ON m.id = v3.id
-				ORDER BY m.id`
+				%s
+				WHERE strpos(lower(title), lower($1)) > 0
+				AND %s
+				AND ($3 = 0 OR release_year >= $3)
+				AND ($4 = 0 OR release_year <= $4)
+				AND ($5 = '' OR to_tsvector('simple', title) @@ to_tsquery('simple', $5))
+				AND %s
+				ORDER BY %s
+				%s`, columns.List(), branches(columns, named...), genres, keyset, orderBy, limit)
+
+	args := append([]any{filter.Title, pq.Array(filter.Genres), filter.YearFrom, filter.YearTo, repository.SearchQuery(filter.Query)}, keysetArgs...)
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	rows, err := m.DB.QueryContext(ctx, query)
+	rows, err := m.DB.QueryContext(ctx, query, args...)
 	if err != nil {
-		return nil, err
+		return nil, repository.Metadata{}, err
 	}
 
 	defer rows.Close()
 
+	totalRecords := 0
 	movies := []*Movie{}
 
 	for rows.Next() {
 		var movie Movie
-		var genre string
-
-		err := rows.Scan(
-			&movie.ID,
-			&movie.CreatedAt,
-			&movie.UpdatedAt,
-			&movie.Title,
-			&movie.Year,
-			&genre,
-			pq.Array(&movie.Genres),
-			&movie.Runtime,
-			&movie.Language,
-			&movie.Version,
-		)
+
+		err := rows.Scan(append([]any{&totalRecords}, columns.Dest(&movie)...)...)
 		if err != nil {
-			return nil, err
+			return nil, repository.Metadata{}, err
 		}
-		movie.Genres = util.MergeGenres(genre, movie.Genres)
 
 		movies = append(movies, &movie)
 	}
 
 	if err = rows.Err(); err != nil {
-		return nil, err
+		return nil, repository.Metadata{}, err
 	}
 
-	return movies, nil
+	movies, metadata := repository.Paginate(movies, totalRecords, filters)
+
+	return movies, metadata, nil
 }
 
-func (m MovieModel) Update(movie *Movie, director string) error {
+func (m MovieModel) Update(movie *Movie) error {
 	query := `
         WITH movie_update AS (
 					UPDATE movies
@@ -193,9 +212,9 @@ func (m MovieModel) Update(movie *Movie, director string) error {
 				),
 				branch_v2_update AS (
 					INSERT INTO movies_branch_v2 (id, director, runtime, language)
-					VALUES($8, $5, $6, $7)
+					VALUES($8, $7, $5, $6)
 					ON CONFLICT (id) DO UPDATE
-					SET director = EXCLUDED.director,
+					SET director = CASE WHEN $10 THEN EXCLUDED.director ELSE COALESCE(EXCLUDED.director, movies_branch_v2.director) END,
 							runtime = EXCLUDED.runtime,
 							language = EXCLUDED.language
 				)
@@ -209,9 +228,10 @@ func (m MovieModel) Update(movie *Movie, director string) error {
 		pq.Array(movie.Genres),
 		movie.Runtime,
 		movie.Language,
-		director,
+		director(movie),
 		movie.ID,
 		movie.Version,
+		movie.Crew != nil,
 	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
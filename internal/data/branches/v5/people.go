@@ -4,34 +4,21 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"fmt"
 	"time"
 
 	"cloud.google.com/go/civil"
 
-	"thesis.lefler.eu/internal/validator"
+	model "thesis.lefler.eu/internal/model/v5"
+	"thesis.lefler.eu/internal/repository"
 )
 
-type Person struct {
-	ID        int64       `json:"id"`                  // Unique identifier
-	CreatedAt time.Time   `json:"-"`                   // Timestamp of when the movie was added to the database
-	UpdatedAt time.Time   `json:"-"`                   // Timestamp of when the movie record was last updated
-	Name      string      `json:"name"`                // Person name
-	Birthdate *civil.Date `json:"birthdate,omitempty"` // Person birthdate
-	Version   int32       `json:"version"`             // Version number, starts at 1 and increments each time the movie is updated
-}
+type Person = model.Person
 
 type PersonModel struct {
 	DB *sql.DB
 }
 
-func ValidatePerson(v *validator.Validator, person *Person) {
-	v.Check(person.Name != "", "name", "must be provided")
-	v.Check(len(person.Name) <= 500, "name", "must not be more than 500 bytes long")
-
-	v.Check(person.Birthdate.IsValid(), "birthdate", "is not valid")
-	v.Check(person.Birthdate.Before(civil.DateOf(time.Now())), "birthdate", "must not be in the future")
-}
-
 func (m PersonModel) Insert(person *Person) error {
 	query := `
 				WITH old AS (
@@ -93,21 +80,39 @@ func (m PersonModel) Get(id int64) (*Person, error) {
 	return &person, nil
 }
 
-func (m PersonModel) GetAll() ([]*Person, error) {
-	query := `
-		SELECT id, created_at, updated_at, name, birthdate, version
+// personSortColumns maps the sort fields of the API to the columns of the list query,
+// relevance ranks by the search in $2, negated as the most relevant come first
+var personSortColumns = map[string]string{
+	"id":        "id",
+	"name":      "name",
+	"birthdate": "birthdate",
+	"relevance": "-ts_rank(to_tsvector('simple', name), to_tsquery('simple', $2))",
+}
+
+func (m PersonModel) GetAll(filter repository.PersonFilter, filters repository.Filters) ([]*Person, repository.Metadata, error) {
+	keyset, orderBy, limit, keysetArgs := filters.Keyset(personSortColumns, "id", 3)
+
+	query := fmt.Sprintf(`
+		SELECT count(*) OVER(), id, created_at, updated_at, name, birthdate, version
 		FROM people
-		ORDER BY id`
+		WHERE strpos(lower(name), lower($1)) > 0
+		AND ($2 = '' OR to_tsvector('simple', name) @@ to_tsquery('simple', $2))
+		AND %s
+		ORDER BY %s
+		%s`, keyset, orderBy, limit)
+
+	args := append([]any{filter.Name, repository.SearchQuery(filter.Query)}, keysetArgs...)
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	rows, err := m.DB.QueryContext(ctx, query)
+	rows, err := m.DB.QueryContext(ctx, query, args...)
 	if err != nil {
-		return nil, err
+		return nil, repository.Metadata{}, err
 	}
 	defer rows.Close()
 
+	totalRecords := 0
 	persons := []*Person{}
 
 	for rows.Next() {
@@ -115,6 +120,7 @@ func (m PersonModel) GetAll() ([]*Person, error) {
 		var birthdate *time.Time
 
 		err := rows.Scan(
+			&totalRecords,
 			&person.ID,
 			&person.CreatedAt,
 			&person.UpdatedAt,
@@ -123,7 +129,7 @@ func (m PersonModel) GetAll() ([]*Person, error) {
 			&person.Version)
 
 		if err != nil {
-			return nil, err
+			return nil, repository.Metadata{}, err
 		}
 
 		if birthdate != nil {
@@ -134,10 +140,12 @@ func (m PersonModel) GetAll() ([]*Person, error) {
 	}
 
 	if err = rows.Err(); err != nil {
-		return nil, err
+		return nil, repository.Metadata{}, err
 	}
 
-	return persons, nil
+	persons, metadata := repository.Paginate(persons, totalRecords, filters)
+
+	return persons, metadata, nil
 }
 
 func (m PersonModel) Update(person *Person) error {
@@ -4,50 +4,21 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"fmt"
 	"time"
 
-	"thesis.lefler.eu/internal/validator"
+	"github.com/lib/pq"
+
+	model "thesis.lefler.eu/internal/model/v2"
+	"thesis.lefler.eu/internal/repository"
 )
 
-// New fields as pointers to allow for null values from pre-v2 records
-type Movie struct {
-	ID        int64     `json:"id"`                 // Unique identifier
-	CreatedAt time.Time `json:"-"`                  // Timestamp of when the movie was added to the database
-	UpdatedAt time.Time `json:"-"`                  // Timestamp of when the movie record was last updated
-	Title     string    `json:"title"`              // Title of the movie
-	Year      int32     `json:"year,omitempty"`     // Release year
-	Genre     string    `json:"genre,omitempty"`    // Genre
-	Director  *string   `json:"director,omitempty"` // Director name
-	Runtime   *int32    `json:"runtime,omitempty"`  // Runtime in minutes
-	Language  *string   `json:"language,omitempty"` // Language
-	Version   int32     `json:"version"`            // Version number, starts at 1 and increments each time the movie is updated
-}
+type Movie = model.Movie
 
 type MovieModel struct {
 	DB *sql.DB
 }
 
-func ValidateMovie(v *validator.Validator, movie *Movie) {
-	v.Check(movie.Title != "", "title", "must be provided")
-	v.Check(len(movie.Title) <= 500, "title", "must not be more than 500 bytes long")
-
-	v.Check(movie.Year != 0, "year", "must be provided")
-	v.Check(movie.Year >= 1888, "year", "must be greater than 1888")
-	v.Check(movie.Year <= int32(time.Now().Year()), "year", "must not be in the future")
-
-	v.Check(movie.Genre != "", "genre", "must be provided")
-	v.Check(len(movie.Genre) <= 50, "title", "must not be more than 50 bytes long")
-
-	v.Check(*movie.Director != "", "director", "must be provided")
-	v.Check(len(*movie.Director) <= 100, "director", "must not be more than 100 bytes long")
-
-	v.Check(*movie.Runtime != 0, "runtime", "must be provided")
-	v.Check(*movie.Runtime > 0, "runtime", "must be a positive integer")
-
-	v.Check(*movie.Language != "", "language", "must be provided")
-	v.Check(len(*movie.Language) <= 50, "language", "must not be more than 50 bytes long")
-}
-
 func (m MovieModel) Insert(movie *Movie) error {
 	query := `
         INSERT INTO movies (title, release_year, genre, director, runtime, language) 
@@ -102,28 +73,52 @@ func (m MovieModel) Get(id int64) (*Movie, error) {
 	return &movie, nil
 }
 
-func (m MovieModel) GetAll() ([]*Movie, error) {
-	query := `
-        SELECT id, created_at, updated_at, title, release_year, genre, director, runtime, language, version
+// movieSortColumns maps the sort fields of the API to the columns of the list query,
+// relevance ranks by the search in $5, negated as the most relevant come first
+var movieSortColumns = map[string]string{
+	"id":        "id",
+	"title":     "title",
+	"year":      "release_year",
+	"genre":     "genre",
+	"runtime":   "runtime",
+	"relevance": "-ts_rank(to_tsvector('simple', title), to_tsquery('simple', $5))",
+}
+
+func (m MovieModel) GetAll(filter repository.MovieFilter, filters repository.Filters) ([]*Movie, repository.Metadata, error) {
+	keyset, orderBy, limit, keysetArgs := filters.Keyset(movieSortColumns, "id", 6)
+
+	query := fmt.Sprintf(`
+        SELECT count(*) OVER(), id, created_at, updated_at, title, release_year, genre, director, runtime, language, version
         FROM movies
-        ORDER BY id`
+        WHERE strpos(lower(title), lower($1)) > 0
+        AND genre = ALL($2)
+        AND ($3 = 0 OR release_year >= $3)
+        AND ($4 = 0 OR release_year <= $4)
+        AND ($5 = '' OR to_tsvector('simple', title) @@ to_tsquery('simple', $5))
+        AND %s
+        ORDER BY %s
+        %s`, keyset, orderBy, limit)
+
+	args := append([]any{filter.Title, pq.Array(filter.Genres), filter.YearFrom, filter.YearTo, repository.SearchQuery(filter.Query)}, keysetArgs...)
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	rows, err := m.DB.QueryContext(ctx, query)
+	rows, err := m.DB.QueryContext(ctx, query, args...)
 	if err != nil {
-		return nil, err
+		return nil, repository.Metadata{}, err
 	}
 
 	defer rows.Close()
 
+	totalRecords := 0
 	movies := []*Movie{}
 
 	for rows.Next() {
 		var movie Movie
 
 		err := rows.Scan(
+			&totalRecords,
 			&movie.ID,
 			&movie.CreatedAt,
 			&movie.UpdatedAt,
@@ -136,17 +131,19 @@ func (m MovieModel) GetAll() ([]*Movie, error) {
 			&movie.Version,
 		)
 		if err != nil {
-			return nil, err
+			return nil, repository.Metadata{}, err
 		}
 
 		movies = append(movies, &movie)
 	}
 
 	if err = rows.Err(); err != nil {
-		return nil, err
+		return nil, repository.Metadata{}, err
 	}
 
-	return movies, nil
+	movies, metadata := repository.Paginate(movies, totalRecords, filters)
+
+	return movies, metadata, nil
 }
 
 func (m MovieModel) Update(movie *Movie) error {
@@ -2,19 +2,16 @@ package v5
 
 import (
 	"database/sql"
-	"errors"
+
+	"thesis.lefler.eu/internal/repository"
 )
 
 var (
-	ErrRecordNotFound = errors.New("record not found")
-	ErrEditConflict   = errors.New("edit conflict")
+	ErrRecordNotFound = repository.ErrRecordNotFound
+	ErrEditConflict   = repository.ErrEditConflict
 )
 
-type Models struct {
-	Movies MovieModel
-	People PersonModel
-	Crew   CrewModel
-}
+type Models = repository.RepositoriesV5
 
 func NewModels(db *sql.DB) Models {
 	return Models{
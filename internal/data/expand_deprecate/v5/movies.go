@@ -4,59 +4,39 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"fmt"
 	"time"
 
 	"github.com/lib/pq"
-	"thesis.lefler.eu/internal/util"
-	"thesis.lefler.eu/internal/validator"
+	model "thesis.lefler.eu/internal/model/v5"
+	"thesis.lefler.eu/internal/repository"
 )
 
-// V2 fields still as pointers to allow for null values from pre-v2 records
-// genres does not need to be a pointer as it is a slice and can be nil
-type Movie struct {
-	ID        int64     `json:"id"`                 // Unique identifier
-	CreatedAt time.Time `json:"-"`                  // Timestamp of when the movie was added to the database
-	UpdatedAt time.Time `json:"-"`                  // Timestamp of when the movie record was last updated
-	Title     string    `json:"title"`              // Title of the movie
-	Year      int32     `json:"year,omitempty"`     // Release year
-	Genres    []string  `json:"genres,omitempty"`   // Slice of genres
-	Runtime   *int32    `json:"runtime,omitempty"`  // Runtime in minutes
-	Language  *string   `json:"language,omitempty"` // Language
-	Crew      []*Crew   `json:"crew,omitempty"`     // Slice of crew
-	Version   int32     `json:"version"`            // Version number, starts at 1 and increments each time the movie is updated
-}
+type Movie = model.Movie
 
 type MovieModel struct {
 	DB *sql.DB
 }
 
-func ValidateMovie(v *validator.Validator, movie *Movie) {
-	v.Check(movie.Title != "", "title", "must be provided")
-	v.Check(len(movie.Title) <= 500, "title", "must not be more than 500 bytes long")
-
-	v.Check(movie.Year != 0, "year", "must be provided")
-	v.Check(movie.Year >= 1888, "year", "must be greater than 1888")
-	v.Check(movie.Year <= int32(time.Now().Year()), "year", "must not be in the future")
-
-	v.Check(movie.Genres != nil, "genres", "must be provided")
-	v.Check(len(movie.Genres) >= 1, "genres", "must contain at least 1 genre")
-	v.Check(len(movie.Genres) <= 5, "genres", "must not contain more than 5 genres")
-	v.Check(validator.Unique(movie.Genres), "genres", "must not contain duplicate values")
-
-	v.Check(*movie.Runtime != 0, "runtime", "must be provided")
-	v.Check(*movie.Runtime > 0, "runtime", "must be a positive integer")
-
-	v.Check(*movie.Language != "", "language", "must be provided")
-	v.Check(len(*movie.Language) <= 50, "language", "must not be more than 50 bytes long")
+// director returns the name of the first director in the crew of movie, which is still written
+// for clients of v2 to v4, or nil if the crew names none. Update keeps the stored director unless
+// the crew of movie is set, a crew without a director clears it.
+func director(movie *Movie) *string {
+	for _, crew := range movie.Crew {
+		if crew.CrewType == "Director" {
+			return &crew.PersonName
+		}
+	}
+	return nil
 }
 
-func (m MovieModel) Insert(movie *Movie, director string) error {
+func (m MovieModel) Insert(movie *Movie) error {
 	query := `
         INSERT INTO movies (title, release_year, genre, genres, runtime, language, director) 
         VALUES ($1, $2, $3, $4, $5, $6, $7)
         RETURNING id, created_at, updated_at, version`
 
-	args := []any{movie.Title, movie.Year, movie.Genres[0], pq.Array(movie.Genres), movie.Runtime, movie.Language, director}
+	args := []any{movie.Title, movie.Year, movie.Genres[0], pq.Array(movie.Genres), movie.Runtime, movie.Language, director(movie)}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
@@ -64,36 +44,42 @@ func (m MovieModel) Insert(movie *Movie, director string) error {
 	return m.DB.QueryRowContext(ctx, query, args...).Scan(&movie.ID, &movie.CreatedAt, &movie.UpdatedAt, &movie.Version)
 }
 
+// movieColumns maps the fields of a movie to the columns the queries read them from, the
+// genre of v1 and v2 is merged into the genres like util.MergeGenres does
+var movieColumns = repository.Columns[Movie]{
+	{Field: "id", Expr: "id", Dest: func(m *Movie) any { return &m.ID }},
+	{Expr: "created_at", Dest: func(m *Movie) any { return &m.CreatedAt }},
+	{Expr: "updated_at", Dest: func(m *Movie) any { return &m.UpdatedAt }},
+	{Field: "title", Expr: "title", Dest: func(m *Movie) any { return &m.Title }},
+	{Field: "year", Expr: "release_year", Dest: func(m *Movie) any { return &m.Year }},
+	{Field: "genres", Expr: "CASE WHEN genre IS NULL OR genre = ANY(genres) THEN genres ELSE array_append(genres, genre) END", Dest: func(m *Movie) any { return pq.Array(&m.Genres) }},
+	{Field: "runtime", Expr: "runtime", Dest: func(m *Movie) any { return &m.Runtime }},
+	{Field: "language", Expr: "language", Dest: func(m *Movie) any { return &m.Language }},
+	{Field: "version", Expr: "version", Dest: func(m *Movie) any { return &m.Version }},
+}
+
 func (m MovieModel) Get(id int64) (*Movie, error) {
+	return m.Select(id, repository.Projection{})
+}
+
+func (m MovieModel) Select(id int64, projection repository.Projection) (*Movie, error) {
 	if id < 1 {
 		return nil, ErrRecordNotFound
 	}
 
-	query := `
-        SELECT id, created_at, updated_at, title, release_year, genre, genres, runtime, language, director, version
+	columns := repository.Select(movieColumns, projection)
+
+	query := fmt.Sprintf(`
+        SELECT %s
         FROM movies
-        WHERE id = $1`
+        WHERE id = $1`, columns.List())
 
 	var movie Movie
-	var genre string
-	var director string
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	err := m.DB.QueryRowContext(ctx, query, id).Scan(
-		&movie.ID,
-		&movie.CreatedAt,
-		&movie.UpdatedAt,
-		&movie.Title,
-		&movie.Year,
-		&genre,
-		pq.Array(&movie.Genres),
-		&movie.Runtime,
-		&movie.Language,
-		&director,
-		&movie.Version,
-	)
+	err := m.DB.QueryRowContext(ctx, query, id).Scan(columns.Dest(&movie)...)
 
 	if err != nil {
 		switch {
@@ -103,67 +89,77 @@ func (m MovieModel) Get(id int64) (*Movie, error) {
 			return nil, err
 		}
 	}
-	movie.Genres = util.MergeGenres(genre, movie.Genres)
-	movie.Crew = []*Crew{}
 
 	return &movie, nil
 }
 
-func (m MovieModel) GetAll() ([]*Movie, error) {
-	query := `
-        SELECT id, created_at, updated_at, title, release_year, genre, genres, runtime, language, version
+// movieSortColumns maps the sort fields of the API to the columns of the list query,
+// relevance ranks by the search in $5, negated as the most relevant come first
+var movieSortColumns = map[string]string{
+	"id":        "id",
+	"title":     "title",
+	"year":      "release_year",
+	"runtime":   "runtime",
+	"relevance": "-ts_rank(to_tsvector('simple', title), to_tsquery('simple', $5))",
+}
+
+func (m MovieModel) GetAll(filter repository.MovieFilter, filters repository.Filters, projection repository.Projection) ([]*Movie, repository.Metadata, error) {
+	keyset, orderBy, limit, keysetArgs := filters.Keyset(movieSortColumns, "id", 6)
+	columns := repository.Select(movieColumns, projection.Sorted(filters))
+
+	query := fmt.Sprintf(`
+        SELECT count(*) OVER(), %s
         FROM movies
-        ORDER BY id`
+        WHERE strpos(lower(title), lower($1)) > 0
+        AND (coalesce(genres, '{}') || genre) @> $2
+        AND ($3 = 0 OR release_year >= $3)
+        AND ($4 = 0 OR release_year <= $4)
+        AND ($5 = '' OR to_tsvector('simple', title) @@ to_tsquery('simple', $5))
+        AND %s
+        ORDER BY %s
+        %s`, columns.List(), keyset, orderBy, limit)
+
+	args := append([]any{filter.Title, pq.Array(filter.Genres), filter.YearFrom, filter.YearTo, repository.SearchQuery(filter.Query)}, keysetArgs...)
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	rows, err := m.DB.QueryContext(ctx, query)
+	rows, err := m.DB.QueryContext(ctx, query, args...)
 	if err != nil {
-		return nil, err
+		return nil, repository.Metadata{}, err
 	}
 
 	defer rows.Close()
 
+	totalRecords := 0
 	movies := []*Movie{}
 
 	for rows.Next() {
 		var movie Movie
-		var genre string
-
-		err := rows.Scan(
-			&movie.ID,
-			&movie.CreatedAt,
-			&movie.UpdatedAt,
-			&movie.Title,
-			&movie.Year,
-			&genre,
-			pq.Array(&movie.Genres),
-			&movie.Runtime,
-			&movie.Language,
-			&movie.Version,
-		)
+
+		err := rows.Scan(append([]any{&totalRecords}, columns.Dest(&movie)...)...)
 		if err != nil {
-			return nil, err
+			return nil, repository.Metadata{}, err
 		}
-		movie.Genres = util.MergeGenres(genre, movie.Genres)
 
 		movies = append(movies, &movie)
 	}
 
 	if err = rows.Err(); err != nil {
-		return nil, err
+		return nil, repository.Metadata{}, err
 	}
 
-	return movies, nil
+	movies, metadata := repository.Paginate(movies, totalRecords, filters)
+
+	return movies, metadata, nil
 }
 
-func (m MovieModel) Update(movie *Movie, director string) error {
+func (m MovieModel) Update(movie *Movie) error {
 	query := `
         UPDATE movies
         SET title = $1, release_year = $2, genre = $3, genres = $4, 
 					runtime = $5, language = $6,
-					director = $7
+					director = CASE WHEN $10 THEN $7 ELSE COALESCE($7, director) END,
 					version = version + 1
         WHERE id = $8 AND version = $9
         RETURNING version`
@@ -175,9 +171,10 @@ func (m MovieModel) Update(movie *Movie, director string) error {
 		pq.Array(movie.Genres),
 		movie.Runtime,
 		movie.Language,
-		director,
+		director(movie),
 		movie.ID,
 		movie.Version,
+		movie.Crew != nil,
 	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
@@ -0,0 +1,521 @@
+package memory
+
+import (
+	"cmp"
+	"errors"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"cloud.google.com/go/civil"
+
+	"thesis.lefler.eu/internal/repository"
+)
+
+var (
+	ErrForeignKey   = errors.New("referenced record does not exist")
+	ErrDuplicateKey = errors.New("duplicate key")
+)
+
+type movieRecord struct {
+	id        int64
+	title     string
+	year      int32
+	genres    []string
+	runtime   *int32
+	language  *string
+	createdAt time.Time
+	updatedAt time.Time
+	version   int32
+}
+
+type personRecord struct {
+	id        int64
+	name      string
+	birthdate *civil.Date
+	createdAt time.Time
+	updatedAt time.Time
+	version   int32
+}
+
+type crewRecord struct {
+	movieID   int64
+	personID  int64
+	crewType  string
+	role      string
+	createdAt time.Time
+	updatedAt time.Time
+	version   int32
+}
+
+// Store keeps the data of one strategy in memory, in the shape of the fully migrated schema:
+// movies with genres, people and crew. The repositories of every version read and write it
+// with the same cross-version semantics the views and triggers of the SQL implementation have.
+type Store struct {
+	mu           sync.Mutex
+	movies       map[int64]*movieRecord
+	people       map[int64]*personRecord
+	crew         []*crewRecord // in insertion order, which decides the director of v2 to v4
+	lastMovieID  int64
+	lastPersonID int64
+}
+
+func New() *Store {
+	return &Store{
+		movies: make(map[int64]*movieRecord),
+		people: make(map[int64]*personRecord),
+	}
+}
+
+// Repositories returns the repositories of every version, all backed by s
+func (s *Store) Repositories() repository.Repositories {
+	return repository.Repositories{
+		V1: repository.RepositoriesV1{
+			Movies: moviesV1{s},
+		},
+		V2: repository.RepositoriesV2{
+			Movies: moviesV2{s},
+		},
+		V3: repository.RepositoriesV3{
+			Movies: moviesV3{s},
+		},
+		V4: repository.RepositoriesV4{
+			Movies:      moviesV4{s},
+			Actors:      actorsV4{s},
+			MovieActors: movieActorsV4{s},
+		},
+		V5: repository.RepositoriesV5{
+			Movies: moviesV5{s},
+			People: peopleV5{s},
+			Crew:   crewV5{s},
+		},
+	}
+}
+
+// now has the precision of timestamp(0) columns
+func now() time.Time {
+	return time.Now().Truncate(time.Second)
+}
+
+// The methods below expect s.mu to be held
+
+func (s *Store) insertMovie(m *movieRecord) {
+	s.lastMovieID++
+	m.id = s.lastMovieID
+	m.createdAt = now()
+	m.updatedAt = m.createdAt
+	m.version = 1
+	s.movies[m.id] = m
+}
+
+func (s *Store) movie(id int64) (*movieRecord, error) {
+	m, ok := s.movies[id]
+	if id < 1 || !ok {
+		return nil, repository.ErrRecordNotFound
+	}
+	return m, nil
+}
+
+// updateMovie applies update to the movie if it is still at version, like the
+// WHERE id = $1 AND version = $2 of the SQL implementation
+func (s *Store) updateMovie(id int64, version int32, update func(m *movieRecord)) (*movieRecord, error) {
+	m, ok := s.movies[id]
+	if !ok || m.version != version {
+		return nil, repository.ErrEditConflict
+	}
+
+	update(m)
+	m.version++
+	m.updatedAt = now()
+
+	return m, nil
+}
+
+func (s *Store) deleteMovie(id int64) error {
+	if _, err := s.movie(id); err != nil {
+		return err
+	}
+
+	delete(s.movies, id)
+	s.crew = slices.DeleteFunc(s.crew, func(c *crewRecord) bool { return c.movieID == id })
+
+	return nil
+}
+
+func (s *Store) sortedMovies() []*movieRecord {
+	movies := make([]*movieRecord, 0, len(s.movies))
+	for _, m := range s.movies {
+		movies = append(movies, m)
+	}
+	sort.Slice(movies, func(i, j int) bool { return movies[i].id < movies[j].id })
+	return movies
+}
+
+// listMovies filters and sorts the movies like the list queries of the SQL implementation,
+// genres returns the genres of a movie as the version sees them
+func (s *Store) listMovies(filter repository.MovieFilter, filters repository.Filters, genres func(m *movieRecord) []string) []*movieRecord {
+	var movies []*movieRecord
+	for _, m := range s.sortedMovies() {
+		switch {
+		case !strings.Contains(strings.ToLower(m.title), strings.ToLower(filter.Title)):
+		case !containsAll(genres(m), filter.Genres):
+		case filter.YearFrom != 0 && m.year < filter.YearFrom:
+		case filter.YearTo != 0 && m.year > filter.YearTo:
+		case filter.Query != "" && rank(m.title, filter.Query) == 0:
+		default:
+			movies = append(movies, m)
+		}
+	}
+
+	sortBy(movies, filters, func(a, b *movieRecord) int {
+		switch filters.SortField() {
+		case "title":
+			return cmp.Compare(a.title, b.title)
+		case "year":
+			return cmp.Compare(a.year, b.year)
+		case "genre":
+			return cmp.Compare(firstGenre(a.genres), firstGenre(b.genres))
+		case "runtime":
+			return compareNullable(a.runtime, b.runtime, cmp.Compare[int32])
+		case "relevance":
+			return cmp.Compare(rank(b.title, filter.Query), rank(a.title, filter.Query))
+		default:
+			return cmp.Compare(a.id, b.id)
+		}
+	})
+
+	return movies
+}
+
+func (s *Store) insertPerson(p *personRecord) {
+	s.lastPersonID++
+	p.id = s.lastPersonID
+	p.createdAt = now()
+	p.updatedAt = p.createdAt
+	p.version = 1
+	s.people[p.id] = p
+}
+
+func (s *Store) person(id int64) (*personRecord, error) {
+	p, ok := s.people[id]
+	if id < 1 || !ok {
+		return nil, repository.ErrRecordNotFound
+	}
+	return p, nil
+}
+
+func (s *Store) updatePerson(id int64, version int32, update func(p *personRecord)) (*personRecord, error) {
+	p, ok := s.people[id]
+	if !ok || p.version != version {
+		return nil, repository.ErrEditConflict
+	}
+
+	update(p)
+	p.version++
+	p.updatedAt = now()
+
+	return p, nil
+}
+
+func (s *Store) deletePerson(id int64) error {
+	if _, err := s.person(id); err != nil {
+		return err
+	}
+
+	delete(s.people, id)
+	s.crew = slices.DeleteFunc(s.crew, func(c *crewRecord) bool { return c.personID == id })
+
+	return nil
+}
+
+func (s *Store) sortedPeople() []*personRecord {
+	people := make([]*personRecord, 0, len(s.people))
+	for _, p := range s.people {
+		people = append(people, p)
+	}
+	sort.Slice(people, func(i, j int) bool { return people[i].id < people[j].id })
+	return people
+}
+
+// listPeople filters and sorts the people like the list queries of the SQL implementation
+func (s *Store) listPeople(filter repository.PersonFilter, filters repository.Filters) []*personRecord {
+	var people []*personRecord
+	for _, p := range s.sortedPeople() {
+		switch {
+		case !strings.Contains(strings.ToLower(p.name), strings.ToLower(filter.Name)):
+		case filter.Query != "" && rank(p.name, filter.Query) == 0:
+		default:
+			people = append(people, p)
+		}
+	}
+
+	sortBy(people, filters, func(a, b *personRecord) int {
+		switch filters.SortField() {
+		case "name":
+			return cmp.Compare(a.name, b.name)
+		case "birthdate":
+			return compareNullable(a.birthdate, b.birthdate, civil.Date.Compare)
+		case "relevance":
+			return cmp.Compare(rank(b.name, filter.Query), rank(a.name, filter.Query))
+		default:
+			return cmp.Compare(a.id, b.id)
+		}
+	})
+
+	return people
+}
+
+func (s *Store) insertCrew(c *crewRecord) error {
+	if _, ok := s.movies[c.movieID]; !ok {
+		return ErrForeignKey
+	}
+	if _, ok := s.people[c.personID]; !ok {
+		return ErrForeignKey
+	}
+
+	for _, existing := range s.crew {
+		if existing.movieID == c.movieID && existing.personID == c.personID && existing.crewType == c.crewType {
+			return ErrDuplicateKey
+		}
+	}
+
+	c.createdAt = now()
+	c.updatedAt = c.createdAt
+	c.version = 1
+	s.crew = append(s.crew, c)
+
+	return nil
+}
+
+func (s *Store) crewOf(movieID int64, crewType string) []*crewRecord {
+	var crew []*crewRecord
+	for _, c := range s.crew {
+		if c.movieID == movieID && (crewType == "" || c.crewType == crewType) {
+			crew = append(crew, c)
+		}
+	}
+	return crew
+}
+
+// crewMember finds a member of the crew by the primary key of the crew table
+func (s *Store) crewMember(movieID int64, personID int64, crewType string) (*crewRecord, error) {
+	for _, c := range s.crew {
+		if c.movieID == movieID && c.personID == personID && c.crewType == crewType {
+			return c, nil
+		}
+	}
+	return nil, repository.ErrRecordNotFound
+}
+
+// updateCrew sets the role of a crew member if it is still at version
+func (s *Store) updateCrew(movieID int64, personID int64, crewType string, version int32, role string) (*crewRecord, error) {
+	c, err := s.crewMember(movieID, personID, crewType)
+	if err != nil || c.version != version {
+		return nil, repository.ErrEditConflict
+	}
+
+	c.role = role
+	c.version++
+	c.updatedAt = now()
+
+	return c, nil
+}
+
+// deleteCrewMember removes a crew member if it is still at version
+func (s *Store) deleteCrewMember(movieID int64, personID int64, crewType string, version int32) error {
+	c, err := s.crewMember(movieID, personID, crewType)
+	if err != nil || c.version != version {
+		return repository.ErrEditConflict
+	}
+
+	s.crew = slices.DeleteFunc(s.crew, func(other *crewRecord) bool { return other == c })
+
+	return nil
+}
+
+// deleteCrew removes the crew of a movie, or only its members of crewType if set
+func (s *Store) deleteCrew(movieID int64, crewType string) error {
+	if movieID < 1 {
+		return repository.ErrRecordNotFound
+	}
+
+	before := len(s.crew)
+	s.crew = slices.DeleteFunc(s.crew, func(c *crewRecord) bool {
+		return c.movieID == movieID && (crewType == "" || c.crewType == crewType)
+	})
+
+	if len(s.crew) == before {
+		return repository.ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// director is the name of the first director in the crew of a movie, as v2 to v4 read it
+func (s *Store) director(movieID int64) *string {
+	for _, c := range s.crewOf(movieID, "Director") {
+		if p, ok := s.people[c.personID]; ok {
+			name := p.name
+			return &name
+		}
+	}
+	return nil
+}
+
+// linkDirector writes the director of v2 to v4: the person is matched by name, or created,
+// and added to the crew of the movie. A nil name leaves the crew unchanged.
+func (s *Store) linkDirector(movieID int64, name *string) {
+	if name == nil {
+		return
+	}
+
+	var director *personRecord
+	for _, p := range s.sortedPeople() {
+		if p.name == *name {
+			director = p
+			break
+		}
+	}
+
+	if director == nil {
+		director = &personRecord{name: *name}
+		s.insertPerson(director)
+	}
+
+	// already linked is fine, like ON CONFLICT DO NOTHING
+	_ = s.insertCrew(&crewRecord{movieID: movieID, personID: director.id, crewType: "Director"})
+}
+
+// mergeGenre adds the single genre of v1 and v2 in front of the genres of a movie, so writes
+// through older versions do not lose the genres added by newer ones
+func mergeGenre(genre string, genres []string) []string {
+	merged := []string{genre}
+	for _, g := range genres {
+		if !slices.Contains(merged, g) {
+			merged = append(merged, g)
+		}
+	}
+	return merged
+}
+
+// firstGenre is the genre of v1 and v2
+func firstGenre(genres []string) string {
+	if len(genres) == 0 {
+		return ""
+	}
+	return genres[0]
+}
+
+func containsAll(values []string, wanted []string) bool {
+	for _, w := range wanted {
+		if !slices.Contains(values, w) {
+			return false
+		}
+	}
+	return true
+}
+
+// rank approximates the full-text search of the SQL implementation: text matches q if every
+// word of q is the prefix of one of its words, and ranks by the share of its words matched.
+// It is 0 if text does not match.
+func rank(text string, q string) float64 {
+	words := repository.SearchTerms(text)
+	matched := make([]bool, len(words))
+
+	for _, term := range repository.SearchTerms(q) {
+		found := false
+		for i, word := range words {
+			if strings.HasPrefix(word, term) {
+				matched[i] = true
+				found = true
+			}
+		}
+		if !found {
+			return 0
+		}
+	}
+
+	n := 0
+	for _, m := range matched {
+		if m {
+			n++
+		}
+	}
+
+	return float64(n) / float64(len(words))
+}
+
+// sortBy sorts records already sorted by id by compare in the direction of filters, records
+// that compare equal keep their order by id, which is descending in descending lists read by cursor
+func sortBy[T any](records []T, filters repository.Filters, compare func(a, b T) int) {
+	if filters.Cursor && filters.SortDirection() == "DESC" {
+		slices.Reverse(records)
+	}
+
+	slices.SortStableFunc(records, func(a, b T) int {
+		if filters.SortDirection() == "DESC" {
+			return compare(b, a)
+		}
+		return compare(a, b)
+	})
+}
+
+// compareNullable orders NULLs after every value, which sortBy turns into last in ascending
+// and first in descending order, like PostgreSQL
+func compareNullable[T any](a *T, b *T, compare func(T, T) int) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return 1
+	case b == nil:
+		return -1
+	}
+	return compare(*a, *b)
+}
+
+// page returns the page of the models of a list, filtered and sorted, selected by filters.
+// Lists read by cursor select the records the list queries built with Keyset read.
+func page[T any](records []T, filters repository.Filters) ([]T, repository.Metadata) {
+	if !filters.Cursor {
+		start := min(filters.Offset(), len(records))
+		end := min(start+filters.Limit(), len(records))
+
+		return repository.Paginate(records[start:end], len(records), filters)
+	}
+
+	cursor := filters.After
+	if filters.Before != nil {
+		cursor = filters.Before
+		records = slices.Clone(records)
+		slices.Reverse(records)
+	}
+
+	start := 0
+	if cursor != nil {
+		start = len(records)
+		for i, record := range records {
+			c := repository.CompareCursors(repository.CursorOf(record, filters.Sort), *cursor)
+			if filters.Before != nil {
+				c = -c
+			}
+			if c > 0 {
+				start = i
+				break
+			}
+		}
+	}
+	end := min(start+filters.Limit()+1, len(records))
+
+	return repository.Paginate(records[start:end], len(records), filters)
+}
+
+func clone[T any](v *T) *T {
+	if v == nil {
+		return nil
+	}
+	c := *v
+	return &c
+}
@@ -0,0 +1,93 @@
+package memory
+
+import (
+	v1 "thesis.lefler.eu/internal/model/v1"
+	"thesis.lefler.eu/internal/repository"
+)
+
+// moviesV1 reads the first genre of a movie and merges the written genre into its genres
+type moviesV1 struct {
+	store *Store
+}
+
+func (r moviesV1) Insert(movie *v1.Movie) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	m := &movieRecord{
+		title:  movie.Title,
+		year:   movie.Year,
+		genres: []string{movie.Genre},
+	}
+	r.store.insertMovie(m)
+
+	movie.ID = m.id
+	movie.CreatedAt = m.createdAt
+	movie.UpdatedAt = m.updatedAt
+	movie.Version = m.version
+
+	return nil
+}
+
+func (r moviesV1) Get(id int64) (*v1.Movie, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	m, err := r.store.movie(id)
+	if err != nil {
+		return nil, err
+	}
+
+	return toV1(m), nil
+}
+
+func (r moviesV1) GetAll(filter repository.MovieFilter, filters repository.Filters) ([]*v1.Movie, repository.Metadata, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	movies := []*v1.Movie{}
+	for _, m := range r.store.listMovies(filter, filters, func(m *movieRecord) []string { return []string{firstGenre(m.genres)} }) {
+		movies = append(movies, toV1(m))
+	}
+
+	movies, metadata := page(movies, filters)
+
+	return movies, metadata, nil
+}
+
+func (r moviesV1) Update(movie *v1.Movie) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	m, err := r.store.updateMovie(movie.ID, movie.Version, func(m *movieRecord) {
+		m.title = movie.Title
+		m.year = movie.Year
+		m.genres = mergeGenre(movie.Genre, m.genres)
+	})
+	if err != nil {
+		return err
+	}
+
+	movie.Version = m.version
+
+	return nil
+}
+
+func (r moviesV1) Delete(id int64) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	return r.store.deleteMovie(id)
+}
+
+func toV1(m *movieRecord) *v1.Movie {
+	return &v1.Movie{
+		ID:        m.id,
+		CreatedAt: m.createdAt,
+		UpdatedAt: m.updatedAt,
+		Title:     m.title,
+		Year:      m.year,
+		Genre:     firstGenre(m.genres),
+		Version:   m.version,
+	}
+}
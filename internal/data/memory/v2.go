@@ -0,0 +1,107 @@
+package memory
+
+import (
+	v2 "thesis.lefler.eu/internal/model/v2"
+	"thesis.lefler.eu/internal/repository"
+)
+
+// moviesV2 works like moviesV1, the director is the first director in the crew, and
+// director, runtime and language are only overwritten when set
+type moviesV2 struct {
+	store *Store
+}
+
+func (r moviesV2) Insert(movie *v2.Movie) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	m := &movieRecord{
+		title:    movie.Title,
+		year:     movie.Year,
+		genres:   []string{movie.Genre},
+		runtime:  clone(movie.Runtime),
+		language: clone(movie.Language),
+	}
+	r.store.insertMovie(m)
+	r.store.linkDirector(m.id, movie.Director)
+
+	movie.ID = m.id
+	movie.CreatedAt = m.createdAt
+	movie.UpdatedAt = m.updatedAt
+	movie.Version = m.version
+
+	return nil
+}
+
+func (r moviesV2) Get(id int64) (*v2.Movie, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	m, err := r.store.movie(id)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.toV2(m), nil
+}
+
+func (r moviesV2) GetAll(filter repository.MovieFilter, filters repository.Filters) ([]*v2.Movie, repository.Metadata, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	movies := []*v2.Movie{}
+	for _, m := range r.store.listMovies(filter, filters, func(m *movieRecord) []string { return []string{firstGenre(m.genres)} }) {
+		movies = append(movies, r.toV2(m))
+	}
+
+	movies, metadata := page(movies, filters)
+
+	return movies, metadata, nil
+}
+
+func (r moviesV2) Update(movie *v2.Movie) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	m, err := r.store.updateMovie(movie.ID, movie.Version, func(m *movieRecord) {
+		m.title = movie.Title
+		m.year = movie.Year
+		m.genres = mergeGenre(movie.Genre, m.genres)
+		if movie.Runtime != nil {
+			m.runtime = clone(movie.Runtime)
+		}
+		if movie.Language != nil {
+			m.language = clone(movie.Language)
+		}
+	})
+	if err != nil {
+		return err
+	}
+	r.store.linkDirector(m.id, movie.Director)
+
+	movie.Version = m.version
+
+	return nil
+}
+
+func (r moviesV2) Delete(id int64) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	return r.store.deleteMovie(id)
+}
+
+func (r moviesV2) toV2(m *movieRecord) *v2.Movie {
+	return &v2.Movie{
+		ID:        m.id,
+		CreatedAt: m.createdAt,
+		UpdatedAt: m.updatedAt,
+		Title:     m.title,
+		Year:      m.year,
+		Genre:     firstGenre(m.genres),
+		Director:  r.store.director(m.id),
+		Runtime:   clone(m.runtime),
+		Language:  clone(m.language),
+		Version:   m.version,
+	}
+}
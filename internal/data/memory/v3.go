@@ -0,0 +1,104 @@
+package memory
+
+import (
+	"slices"
+
+	v3 "thesis.lefler.eu/internal/model/v3"
+	"thesis.lefler.eu/internal/repository"
+)
+
+// moviesV3 overwrites genres, runtime and language, the director is the first director in the crew
+type moviesV3 struct {
+	store *Store
+}
+
+func (r moviesV3) Insert(movie *v3.Movie) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	m := &movieRecord{
+		title:    movie.Title,
+		year:     movie.Year,
+		genres:   slices.Clone(movie.Genres),
+		runtime:  clone(movie.Runtime),
+		language: clone(movie.Language),
+	}
+	r.store.insertMovie(m)
+	r.store.linkDirector(m.id, movie.Director)
+
+	movie.ID = m.id
+	movie.CreatedAt = m.createdAt
+	movie.UpdatedAt = m.updatedAt
+	movie.Version = m.version
+
+	return nil
+}
+
+func (r moviesV3) Get(id int64) (*v3.Movie, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	m, err := r.store.movie(id)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.toV3(m), nil
+}
+
+func (r moviesV3) GetAll(filter repository.MovieFilter, filters repository.Filters) ([]*v3.Movie, repository.Metadata, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	movies := []*v3.Movie{}
+	for _, m := range r.store.listMovies(filter, filters, func(m *movieRecord) []string { return m.genres }) {
+		movies = append(movies, r.toV3(m))
+	}
+
+	movies, metadata := page(movies, filters)
+
+	return movies, metadata, nil
+}
+
+func (r moviesV3) Update(movie *v3.Movie) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	m, err := r.store.updateMovie(movie.ID, movie.Version, func(m *movieRecord) {
+		m.title = movie.Title
+		m.year = movie.Year
+		m.genres = slices.Clone(movie.Genres)
+		m.runtime = clone(movie.Runtime)
+		m.language = clone(movie.Language)
+	})
+	if err != nil {
+		return err
+	}
+	r.store.linkDirector(m.id, movie.Director)
+
+	movie.Version = m.version
+
+	return nil
+}
+
+func (r moviesV3) Delete(id int64) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	return r.store.deleteMovie(id)
+}
+
+func (r moviesV3) toV3(m *movieRecord) *v3.Movie {
+	return &v3.Movie{
+		ID:        m.id,
+		CreatedAt: m.createdAt,
+		UpdatedAt: m.updatedAt,
+		Title:     m.title,
+		Year:      m.year,
+		Genres:    slices.Clone(m.genres),
+		Director:  r.store.director(m.id),
+		Runtime:   clone(m.runtime),
+		Language:  clone(m.language),
+		Version:   m.version,
+	}
+}
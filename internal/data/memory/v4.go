@@ -0,0 +1,285 @@
+package memory
+
+import (
+	"slices"
+
+	v4 "thesis.lefler.eu/internal/model/v4"
+	"thesis.lefler.eu/internal/repository"
+)
+
+// moviesV4 stores movies like moviesV3, the actors of a movie are stored by movieActorsV4
+type moviesV4 struct {
+	store *Store
+}
+
+func (r moviesV4) Insert(movie *v4.Movie) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	m := &movieRecord{
+		title:    movie.Title,
+		year:     movie.Year,
+		genres:   slices.Clone(movie.Genres),
+		runtime:  clone(movie.Runtime),
+		language: clone(movie.Language),
+	}
+	r.store.insertMovie(m)
+	r.store.linkDirector(m.id, movie.Director)
+
+	movie.ID = m.id
+	movie.CreatedAt = m.createdAt
+	movie.UpdatedAt = m.updatedAt
+	movie.Version = m.version
+
+	return nil
+}
+
+func (r moviesV4) Get(id int64) (*v4.Movie, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	m, err := r.store.movie(id)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.toV4(m), nil
+}
+
+func (r moviesV4) GetAll(filter repository.MovieFilter, filters repository.Filters) ([]*v4.Movie, repository.Metadata, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	movies := []*v4.Movie{}
+	for _, m := range r.store.listMovies(filter, filters, func(m *movieRecord) []string { return m.genres }) {
+		movies = append(movies, r.toV4(m))
+	}
+
+	movies, metadata := page(movies, filters)
+
+	return movies, metadata, nil
+}
+
+func (r moviesV4) Update(movie *v4.Movie) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	m, err := r.store.updateMovie(movie.ID, movie.Version, func(m *movieRecord) {
+		m.title = movie.Title
+		m.year = movie.Year
+		m.genres = slices.Clone(movie.Genres)
+		m.runtime = clone(movie.Runtime)
+		m.language = clone(movie.Language)
+	})
+	if err != nil {
+		return err
+	}
+	r.store.linkDirector(m.id, movie.Director)
+
+	movie.Version = m.version
+
+	return nil
+}
+
+func (r moviesV4) Delete(id int64) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	return r.store.deleteMovie(id)
+}
+
+func (r moviesV4) toV4(m *movieRecord) *v4.Movie {
+	return &v4.Movie{
+		ID:        m.id,
+		CreatedAt: m.createdAt,
+		UpdatedAt: m.updatedAt,
+		Title:     m.title,
+		Year:      m.year,
+		Genres:    slices.Clone(m.genres),
+		Director:  r.store.director(m.id),
+		Runtime:   clone(m.runtime),
+		Language:  clone(m.language),
+		Version:   m.version,
+	}
+}
+
+// actorsV4 stores actors as people, so directors written through v2 to v4 are actors too
+type actorsV4 struct {
+	store *Store
+}
+
+func (r actorsV4) Insert(actor *v4.Actor) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	p := &personRecord{
+		name:      actor.Name,
+		birthdate: clone(actor.Birthdate),
+	}
+	r.store.insertPerson(p)
+
+	actor.ID = p.id
+	actor.CreatedAt = p.createdAt
+	actor.UpdatedAt = p.updatedAt
+	actor.Version = p.version
+
+	return nil
+}
+
+func (r actorsV4) Get(id int64) (*v4.Actor, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	p, err := r.store.person(id)
+	if err != nil {
+		return nil, err
+	}
+
+	return toActor(p), nil
+}
+
+func (r actorsV4) GetAll(filter repository.PersonFilter, filters repository.Filters) ([]*v4.Actor, repository.Metadata, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	actors := []*v4.Actor{}
+	for _, p := range r.store.listPeople(filter, filters) {
+		actors = append(actors, toActor(p))
+	}
+
+	actors, metadata := page(actors, filters)
+
+	return actors, metadata, nil
+}
+
+func (r actorsV4) Update(actor *v4.Actor) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	p, err := r.store.updatePerson(actor.ID, actor.Version, func(p *personRecord) {
+		p.name = actor.Name
+		p.birthdate = clone(actor.Birthdate)
+	})
+	if err != nil {
+		return err
+	}
+
+	actor.Version = p.version
+
+	return nil
+}
+
+func (r actorsV4) Delete(id int64) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	return r.store.deletePerson(id)
+}
+
+func toActor(p *personRecord) *v4.Actor {
+	return &v4.Actor{
+		ID:        p.id,
+		CreatedAt: p.createdAt,
+		UpdatedAt: p.updatedAt,
+		Name:      p.name,
+		Birthdate: clone(p.birthdate),
+		Version:   p.version,
+	}
+}
+
+// movieActorsV4 stores the actors of a movie as crew members of type Actor
+type movieActorsV4 struct {
+	store *Store
+}
+
+func (r movieActorsV4) Insert(movieActor *v4.MovieActor) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	c := &crewRecord{
+		movieID:  movieActor.MovieID,
+		personID: movieActor.ActorID,
+		crewType: "Actor",
+		role:     movieActor.Role,
+	}
+
+	err := r.store.insertCrew(c)
+	if err != nil {
+		return err
+	}
+
+	movieActor.CreatedAt = c.createdAt
+	movieActor.UpdatedAt = c.updatedAt
+	movieActor.Version = c.version
+
+	return nil
+}
+
+func (r movieActorsV4) Get(movieID int64, actorID int64) (*v4.MovieActor, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	c, err := r.store.crewMember(movieID, actorID, "Actor")
+	if err != nil {
+		return nil, err
+	}
+
+	return r.movieActor(c), nil
+}
+
+func (r movieActorsV4) GetForMovie(movieID int64) ([]*v4.MovieActor, error) {
+	if movieID < 1 {
+		return nil, repository.ErrRecordNotFound
+	}
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	movieActors := []*v4.MovieActor{}
+	for _, c := range r.store.crewOf(movieID, "Actor") {
+		movieActors = append(movieActors, r.movieActor(c))
+	}
+
+	return movieActors, nil
+}
+
+func (r movieActorsV4) Update(movieActor *v4.MovieActor) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	c, err := r.store.updateCrew(movieActor.MovieID, movieActor.ActorID, "Actor", movieActor.Version, movieActor.Role)
+	if err != nil {
+		return err
+	}
+
+	movieActor.UpdatedAt = c.updatedAt
+	movieActor.Version = c.version
+
+	return nil
+}
+
+func (r movieActorsV4) Delete(movieActor *v4.MovieActor) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	return r.store.deleteCrewMember(movieActor.MovieID, movieActor.ActorID, "Actor", movieActor.Version)
+}
+
+func (r movieActorsV4) DeleteForMovie(movieID int64) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	return r.store.deleteCrew(movieID, "Actor")
+}
+
+func (r movieActorsV4) movieActor(c *crewRecord) *v4.MovieActor {
+	return &v4.MovieActor{
+		MovieID:   c.movieID,
+		ActorID:   c.personID,
+		ActorName: r.store.people[c.personID].name,
+		Role:      c.role,
+		CreatedAt: c.createdAt,
+		UpdatedAt: c.updatedAt,
+		Version:   c.version,
+	}
+}
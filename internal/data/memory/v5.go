@@ -0,0 +1,301 @@
+package memory
+
+import (
+	"slices"
+
+	v5 "thesis.lefler.eu/internal/model/v5"
+	"thesis.lefler.eu/internal/repository"
+)
+
+// moviesV5 stores the movie only, its crew is stored by crewV5
+type moviesV5 struct {
+	store *Store
+}
+
+func (r moviesV5) Insert(movie *v5.Movie) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	m := &movieRecord{
+		title:    movie.Title,
+		year:     movie.Year,
+		genres:   slices.Clone(movie.Genres),
+		runtime:  clone(movie.Runtime),
+		language: clone(movie.Language),
+	}
+	r.store.insertMovie(m)
+
+	movie.ID = m.id
+	movie.CreatedAt = m.createdAt
+	movie.UpdatedAt = m.updatedAt
+	movie.Version = m.version
+
+	return nil
+}
+
+func (r moviesV5) Get(id int64) (*v5.Movie, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	m, err := r.store.movie(id)
+	if err != nil {
+		return nil, err
+	}
+
+	return toV5(m), nil
+}
+
+// Select reads the whole movie, the store holds every field in memory and there is nothing
+// to leave out
+func (r moviesV5) Select(id int64, projection repository.Projection) (*v5.Movie, error) {
+	return r.Get(id)
+}
+
+func (r moviesV5) GetAll(filter repository.MovieFilter, filters repository.Filters, projection repository.Projection) ([]*v5.Movie, repository.Metadata, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	movies := []*v5.Movie{}
+	for _, m := range r.store.listMovies(filter, filters, func(m *movieRecord) []string { return m.genres }) {
+		movies = append(movies, toV5(m))
+	}
+
+	movies, metadata := page(movies, filters)
+
+	return movies, metadata, nil
+}
+
+func (r moviesV5) Update(movie *v5.Movie) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	m, err := r.store.updateMovie(movie.ID, movie.Version, func(m *movieRecord) {
+		m.title = movie.Title
+		m.year = movie.Year
+		m.genres = slices.Clone(movie.Genres)
+		m.runtime = clone(movie.Runtime)
+		m.language = clone(movie.Language)
+	})
+	if err != nil {
+		return err
+	}
+
+	movie.Version = m.version
+
+	return nil
+}
+
+func (r moviesV5) Delete(id int64) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	return r.store.deleteMovie(id)
+}
+
+func toV5(m *movieRecord) *v5.Movie {
+	return &v5.Movie{
+		ID:        m.id,
+		CreatedAt: m.createdAt,
+		UpdatedAt: m.updatedAt,
+		Title:     m.title,
+		Year:      m.year,
+		Genres:    slices.Clone(m.genres),
+		Runtime:   clone(m.runtime),
+		Language:  clone(m.language),
+		Version:   m.version,
+	}
+}
+
+type peopleV5 struct {
+	store *Store
+}
+
+func (r peopleV5) Insert(person *v5.Person) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	p := &personRecord{
+		name:      person.Name,
+		birthdate: clone(person.Birthdate),
+	}
+	r.store.insertPerson(p)
+
+	person.ID = p.id
+	person.CreatedAt = p.createdAt
+	person.UpdatedAt = p.updatedAt
+	person.Version = p.version
+
+	return nil
+}
+
+func (r peopleV5) Get(id int64) (*v5.Person, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	p, err := r.store.person(id)
+	if err != nil {
+		return nil, err
+	}
+
+	return toPerson(p), nil
+}
+
+func (r peopleV5) GetAll(filter repository.PersonFilter, filters repository.Filters) ([]*v5.Person, repository.Metadata, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	people := []*v5.Person{}
+	for _, p := range r.store.listPeople(filter, filters) {
+		people = append(people, toPerson(p))
+	}
+
+	people, metadata := page(people, filters)
+
+	return people, metadata, nil
+}
+
+func (r peopleV5) Update(person *v5.Person) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	p, err := r.store.updatePerson(person.ID, person.Version, func(p *personRecord) {
+		p.name = person.Name
+		p.birthdate = clone(person.Birthdate)
+	})
+	if err != nil {
+		return err
+	}
+
+	person.Version = p.version
+
+	return nil
+}
+
+func (r peopleV5) Delete(id int64) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	return r.store.deletePerson(id)
+}
+
+func toPerson(p *personRecord) *v5.Person {
+	return &v5.Person{
+		ID:        p.id,
+		CreatedAt: p.createdAt,
+		UpdatedAt: p.updatedAt,
+		Name:      p.name,
+		Birthdate: clone(p.birthdate),
+		Version:   p.version,
+	}
+}
+
+type crewV5 struct {
+	store *Store
+}
+
+func (r crewV5) Insert(crew *v5.Crew) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	c := &crewRecord{
+		movieID:  crew.MovieID,
+		personID: crew.PersonID,
+		crewType: crew.CrewType,
+		role:     crew.Role,
+	}
+
+	err := r.store.insertCrew(c)
+	if err != nil {
+		return err
+	}
+
+	crew.CreatedAt = c.createdAt
+	crew.UpdatedAt = c.updatedAt
+	crew.Version = c.version
+
+	return nil
+}
+
+func (r crewV5) Get(movieID int64, personID int64, crewType string) (*v5.Crew, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	c, err := r.store.crewMember(movieID, personID, crewType)
+	if err != nil {
+		return nil, err
+	}
+
+	return &v5.Crew{
+		MovieID:    c.movieID,
+		PersonID:   c.personID,
+		PersonName: r.store.people[c.personID].name,
+		CrewType:   c.crewType,
+		Role:       c.role,
+		CreatedAt:  c.createdAt,
+		UpdatedAt:  c.updatedAt,
+		Version:    c.version,
+	}, nil
+}
+
+func (r crewV5) GetForMovie(movieID int64) ([]*v5.Crew, error) {
+	if movieID < 1 {
+		return nil, repository.ErrRecordNotFound
+	}
+
+	return r.GetForMovies([]int64{movieID}, true)
+}
+
+func (r crewV5) GetForMovies(movieIDs []int64, names bool) ([]*v5.Crew, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	crew := []*v5.Crew{}
+	for _, movieID := range movieIDs {
+		for _, c := range r.store.crewOf(movieID, "") {
+			member := &v5.Crew{
+				MovieID:   c.movieID,
+				PersonID:  c.personID,
+				CrewType:  c.crewType,
+				Role:      c.role,
+				CreatedAt: c.createdAt,
+				UpdatedAt: c.updatedAt,
+				Version:   c.version,
+			}
+			if names {
+				member.PersonName = r.store.people[c.personID].name
+			}
+			crew = append(crew, member)
+		}
+	}
+
+	return crew, nil
+}
+
+func (r crewV5) Update(crew *v5.Crew) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	c, err := r.store.updateCrew(crew.MovieID, crew.PersonID, crew.CrewType, crew.Version, crew.Role)
+	if err != nil {
+		return err
+	}
+
+	crew.UpdatedAt = c.updatedAt
+	crew.Version = c.version
+
+	return nil
+}
+
+func (r crewV5) Delete(crew *v5.Crew) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	return r.store.deleteCrewMember(crew.MovieID, crew.PersonID, crew.CrewType, crew.Version)
+}
+
+func (r crewV5) DeleteForMovie(movieID int64) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	return r.store.deleteCrew(movieID, "")
+}
@@ -5,6 +5,7 @@ import (
 
 	branches "thesis.lefler.eu/internal/data/branches"
 	expandDeprecate "thesis.lefler.eu/internal/data/expand_deprecate"
+	"thesis.lefler.eu/internal/data/memory"
 	views "thesis.lefler.eu/internal/data/views"
 )
 
@@ -27,3 +28,13 @@ func NewModels(db DbConns) Models {
 		Branches:        branches.NewModels(db.Branches),
 	}
 }
+
+// NewMemoryModels returns models of every strategy backed by their own in-memory store,
+// for running the handlers without a database
+func NewMemoryModels() Models {
+	return Models{
+		Views:           memory.New().Repositories(),
+		ExpandDeprecate: memory.New().Repositories(),
+		Branches:        memory.New().Repositories(),
+	}
+}
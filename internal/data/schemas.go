@@ -0,0 +1,15 @@
+package data
+
+import (
+	"thesis.lefler.eu/internal/model"
+	"thesis.lefler.eu/internal/schema"
+)
+
+// Schemas returns the resource schemas of every strategy, keyed like the route prefixes
+func Schemas() map[string]schema.Versions {
+	return map[string]schema.Versions{
+		"views":            model.Schemas(),
+		"expand_deprecate": model.Schemas(),
+		"branches":         model.Schemas(),
+	}
+}
@@ -3,6 +3,8 @@ package data
 import (
 	"database/sql"
 
+	"thesis.lefler.eu/internal/repository"
+
 	v1 "thesis.lefler.eu/internal/data/views/v1"
 	v2 "thesis.lefler.eu/internal/data/views/v2"
 	v3 "thesis.lefler.eu/internal/data/views/v3"
@@ -10,13 +12,7 @@ import (
 	v5 "thesis.lefler.eu/internal/data/views/v5"
 )
 
-type Models struct {
-	V1 v1.Models
-	V2 v2.Models
-	V3 v3.Models
-	V4 v4.Models
-	V5 v5.Models
-}
+type Models = repository.Repositories
 
 func NewModels(db *sql.DB) Models {
 	return Models{
@@ -6,32 +6,15 @@ import (
 	"errors"
 	"time"
 
-	"thesis.lefler.eu/internal/validator"
+	model "thesis.lefler.eu/internal/model/v4"
 )
 
-type MovieActor struct {
-	MovieID   int64     `json:"-"`
-	ActorID   int64     `json:"actor_id"`
-	ActorName string    `json:"actor_name"`
-	Role      string    `json:"role"`
-	CreatedAt time.Time `json:"-"`
-	UpdatedAt time.Time `json:"-"`
-	Version   int32     `json:"-"`
-}
+type MovieActor = model.MovieActor
 
 type MovieActorModel struct {
 	DB *sql.DB
 }
 
-func ValidateCrew(v *validator.Validator, movieActor *MovieActor) {
-	v.Check(movieActor.MovieID > 0, "movie_id", "must be a positive integer")
-	v.Check(movieActor.ActorID > 0, "actor_id", "must be a positive integer")
-
-	v.Check(movieActor.Role != "", "role", "must be provided")
-	v.Check(len(movieActor.Role) <= 500, "role", "must not be more than 500 bytes long")
-
-}
-
 func (m MovieActorModel) Insert(movieActor *MovieActor) error {
 	query := `
 		INSERT INTO movie_actors_v1 (movie_id, actor_id, role)
@@ -135,10 +118,10 @@ func (m MovieActorModel) Update(movieActor *MovieActor) error {
 	query := `
 		UPDATE movie_actors_v1
 		SET role = $1, updated_at = CURRENT_TIMESTAMP, version = version + 1
-		WHERE movie_id = $2 AND actor_id = $3
+		WHERE movie_id = $2 AND actor_id = $3 AND version = $4
 		RETURNING updated_at, version`
 
-	args := []interface{}{movieActor.Role, movieActor.MovieID, movieActor.ActorID}
+	args := []interface{}{movieActor.Role, movieActor.MovieID, movieActor.ActorID, movieActor.Version}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
@@ -156,19 +139,19 @@ func (m MovieActorModel) Update(movieActor *MovieActor) error {
 	return nil
 }
 
-func (m MovieActorModel) Delete(movieID, actorID int64) error {
-	if movieID < 1 || actorID < 1 {
+func (m MovieActorModel) Delete(movieActor *MovieActor) error {
+	if movieActor.MovieID < 1 || movieActor.ActorID < 1 {
 		return ErrRecordNotFound
 	}
 
 	query := `
 		DELETE FROM movie_actors_v1
-		WHERE movie_id = $1 AND actor_id = $2`
+		WHERE movie_id = $1 AND actor_id = $2 AND version = $3`
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	result, err := m.DB.ExecContext(ctx, query, movieID, actorID)
+	result, err := m.DB.ExecContext(ctx, query, movieActor.MovieID, movieActor.ActorID, movieActor.Version)
 	if err != nil {
 		return err
 	}
@@ -179,7 +162,7 @@ func (m MovieActorModel) Delete(movieID, actorID int64) error {
 	}
 
 	if rowsAffected == 0 {
-		return ErrRecordNotFound
+		return ErrEditConflict
 	}
 
 	return nil
@@ -4,40 +4,20 @@ import (
 	"context"
 	"database/sql"
 	"errors"
-	"strings"
+	"fmt"
 	"time"
 
-	"thesis.lefler.eu/internal/validator"
+	"github.com/lib/pq"
+
+	model "thesis.lefler.eu/internal/model/v5"
 )
 
-type Crew struct {
-	MovieID    int64     `json:"-"`
-	PersonID   int64     `json:"person_id"`
-	PersonName string    `json:"person_name"`
-	CrewType   string    `json:"crew_type"`
-	Role       string    `json:"role,omitempty"`
-	CreatedAt  time.Time `json:"-"`
-	UpdatedAt  time.Time `json:"-"`
-	Version    int32     `json:"-"`
-}
+type Crew = model.Crew
 
 type CrewModel struct {
 	DB *sql.DB
 }
 
-func ValidateCrew(v *validator.Validator, crew *Crew) {
-	v.Check(crew.PersonID > 0, "person_id", "must be a positive integer")
-
-	v.Check(crew.CrewType != "", "crew_type", "must be provided")
-	v.Check(len(crew.CrewType) <= 500, "crew_type", "must not be more than 500 bytes long")
-	v.Check(validator.PermittedValue(crew.CrewType, "Actor", "Director", "Producer"), "crew_type", "must be either 'Actor', 'Director', or 'Producer'")
-
-	if strings.ToLower(crew.CrewType) == "actor" {
-		v.Check(crew.Role != "", "role", "must be provided if crew_type is 'actor'")
-		v.Check(len(crew.Role) <= 500, "role", "must not be more than 500 bytes long")
-	}
-}
-
 func (m CrewModel) Insert(crew *Crew) error {
 	query := `
 		INSERT INTO crew_v1 (movie_id, person_id, crew_type, role)
@@ -52,8 +32,8 @@ func (m CrewModel) Insert(crew *Crew) error {
 	return m.DB.QueryRowContext(ctx, query, args...).Scan(&crew.CreatedAt, &crew.UpdatedAt, &crew.Version)
 }
 
-func (m CrewModel) Get(movieID, actorID int64) (*Crew, error) {
-	if movieID < 1 || actorID < 1 {
+func (m CrewModel) Get(movieID, personID int64, crewType string) (*Crew, error) {
+	if movieID < 1 || personID < 1 {
 		return nil, ErrRecordNotFound
 	}
 
@@ -61,14 +41,14 @@ func (m CrewModel) Get(movieID, actorID int64) (*Crew, error) {
 		SELECT c.movie_id, c.person_id, p.name AS person_name, c.crew_type, c.role, c.created_at, c.updated_at, c.version
 		FROM crew_v1 c
 		LEFT JOIN people_v1 p ON c.person_id = p.id
-		WHERE c.movie_id = $1 AND c.person_id = $2`
+		WHERE c.movie_id = $1 AND c.person_id = $2 AND c.crew_type = $3`
 
 	var crew Crew
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	err := m.DB.QueryRowContext(ctx, query, movieID, actorID).Scan(
+	err := m.DB.QueryRowContext(ctx, query, movieID, personID, crewType).Scan(
 		&crew.MovieID,
 		&crew.PersonID,
 		&crew.PersonName,
@@ -95,16 +75,26 @@ func (m CrewModel) GetForMovie(movieID int64) ([]*Crew, error) {
 		return nil, ErrRecordNotFound
 	}
 
-	query := `
-		SELECT c.movie_id, c.person_id, p.name AS person_name, c.crew_type, c.role, c.created_at, c.updated_at, c.version
+	return m.GetForMovies([]int64{movieID}, true)
+}
+
+func (m CrewModel) GetForMovies(movieIDs []int64, names bool) ([]*Crew, error) {
+	// people are only joined for their names
+	name, people := "''", ""
+	if names {
+		name, people = "p.name", "LEFT JOIN people_v1 p ON c.person_id = p.id"
+	}
+
+	query := fmt.Sprintf(`
+		SELECT c.movie_id, c.person_id, %s AS person_name, c.crew_type, c.role, c.created_at, c.updated_at, c.version
 		FROM crew_v1 c
-		LEFT JOIN people_v1 p ON c.person_id = p.id
-		WHERE c.movie_id = $1`
+		%s
+		WHERE c.movie_id = ANY($1)`, name, people)
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	rows, err := m.DB.QueryContext(ctx, query, movieID)
+	rows, err := m.DB.QueryContext(ctx, query, pq.Array(movieIDs))
 	if err != nil {
 		return nil, err
 	}
@@ -142,11 +132,11 @@ func (m CrewModel) GetForMovie(movieID int64) ([]*Crew, error) {
 func (m CrewModel) Update(crew *Crew) error {
 	query := `
 		UPDATE crew_v1
-		SET crew_type = $1, role = $2, version = version + 1
-		WHERE movie_id = $3 AND person_id = $4
+		SET role = $1, version = version + 1
+		WHERE movie_id = $2 AND person_id = $3 AND crew_type = $4 AND version = $5
 		RETURNING updated_at, version`
 
-	args := []interface{}{crew.CrewType, crew.Role, crew.MovieID, crew.PersonID}
+	args := []interface{}{crew.Role, crew.MovieID, crew.PersonID, crew.CrewType, crew.Version}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
@@ -164,19 +154,19 @@ func (m CrewModel) Update(crew *Crew) error {
 	return nil
 }
 
-func (m CrewModel) Delete(movieID, actorID int64) error {
-	if movieID < 1 || actorID < 1 {
+func (m CrewModel) Delete(crew *Crew) error {
+	if crew.MovieID < 1 || crew.PersonID < 1 {
 		return ErrRecordNotFound
 	}
 
 	query := `
 		DELETE FROM crew_v1
-		WHERE movie_id = $1 AND person_id = $2`
+		WHERE movie_id = $1 AND person_id = $2 AND crew_type = $3 AND version = $4`
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	result, err := m.DB.ExecContext(ctx, query, movieID, actorID)
+	result, err := m.DB.ExecContext(ctx, query, crew.MovieID, crew.PersonID, crew.CrewType, crew.Version)
 	if err != nil {
 		return err
 	}
@@ -187,7 +177,7 @@ func (m CrewModel) Delete(movieID, actorID int64) error {
 	}
 
 	if rowsAffected == 0 {
-		return ErrRecordNotFound
+		return ErrEditConflict
 	}
 
 	return nil
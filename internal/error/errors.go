@@ -64,3 +64,22 @@ func (handler *Errors) EditConflictResponse(w http.ResponseWriter, r *http.Reque
 	message := "unable to update the record due to an edit conflict, please try again"
 	handler.ErrorResponse(w, r, http.StatusConflict, message)
 }
+
+func (handler *Errors) NotAcceptableResponse(w http.ResponseWriter, r *http.Request, message string) {
+	handler.ErrorResponse(w, r, http.StatusNotAcceptable, message)
+}
+
+func (handler *Errors) VersionNotAvailableResponse(w http.ResponseWriter, r *http.Request, message string) {
+	handler.ErrorResponse(w, r, http.StatusNotFound, message)
+}
+
+func (handler *Errors) GoneResponse(w http.ResponseWriter, r *http.Request, message any) {
+	handler.ErrorResponse(w, r, http.StatusGone, message)
+}
+
+func (handler *Errors) InvalidAuthenticationTokenResponse(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("WWW-Authenticate", "Bearer")
+
+	message := "invalid or missing authentication token"
+	handler.ErrorResponse(w, r, http.StatusUnauthorized, message)
+}
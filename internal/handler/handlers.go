@@ -5,15 +5,27 @@ import (
 
 	data "thesis.lefler.eu/internal/data"
 	e "thesis.lefler.eu/internal/error"
-	branches "thesis.lefler.eu/internal/handler/branches"
-	expandDeprecate "thesis.lefler.eu/internal/handler/expand_deprecate"
-	views "thesis.lefler.eu/internal/handler/views"
+	v1 "thesis.lefler.eu/internal/handler/v1"
+	v2 "thesis.lefler.eu/internal/handler/v2"
+	v3 "thesis.lefler.eu/internal/handler/v3"
+	v4 "thesis.lefler.eu/internal/handler/v4"
+	v5 "thesis.lefler.eu/internal/handler/v5"
+	"thesis.lefler.eu/internal/repository"
 )
 
 type Handlers struct {
-	Views           views.Handlers
-	ExpandDeprecate expandDeprecate.Handlers
-	Branches        branches.Handlers
+	Views           Versions
+	ExpandDeprecate Versions
+	Branches        Versions
+}
+
+// Versions are the handlers of every version, bound to the repositories of one strategy
+type Versions struct {
+	V1 v1.Handlers
+	V2 v2.Handlers
+	V3 v3.Handlers
+	V4 v4.Handlers
+	V5 v5.Handlers
 }
 
 type Handler interface {
@@ -26,8 +38,18 @@ type Handler interface {
 
 func NewHandlers(errors *e.Errors, models *data.Models) Handlers {
 	return Handlers{
-		Views:           views.NewHandlers(errors, &models.Views),
-		ExpandDeprecate: expandDeprecate.NewHandlers(errors, &models.ExpandDeprecate),
-		Branches:        branches.NewHandlers(errors, &models.Branches),
+		Views:           NewVersions(errors, &models.Views),
+		ExpandDeprecate: NewVersions(errors, &models.ExpandDeprecate),
+		Branches:        NewVersions(errors, &models.Branches),
+	}
+}
+
+func NewVersions(errors *e.Errors, repositories *repository.Repositories) Versions {
+	return Versions{
+		V1: v1.NewHandlers(errors, &repositories.V1),
+		V2: v2.NewHandlers(errors, &repositories.V2),
+		V3: v3.NewHandlers(errors, &repositories.V3),
+		V4: v4.NewHandlers(errors, &repositories.V4),
+		V5: v5.NewHandlers(errors, &repositories.V5),
 	}
 }
@@ -0,0 +1,121 @@
+package handler_test
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"testing"
+
+	"thesis.lefler.eu/internal/handler/handlertest"
+)
+
+// movie holds the fields of a movie in any version
+type movie struct {
+	ID       int64    `json:"id"`
+	Title    string   `json:"title"`
+	Genre    string   `json:"genre"`
+	Genres   []string `json:"genres"`
+	Director *string  `json:"director"`
+	Runtime  *int32   `json:"runtime"`
+	Language *string  `json:"language"`
+	Actors   []struct {
+		ActorID int64  `json:"actor_id"`
+		Role    string `json:"role"`
+	} `json:"actors"`
+	Crew    []crewMember `json:"crew"`
+	Version int32        `json:"version"`
+}
+
+type crewMember struct {
+	PersonID   int64  `json:"person_id"`
+	PersonName string `json:"person_name"`
+	CrewType   string `json:"crew_type"`
+	Role       string `json:"role"`
+}
+
+// TestRoundTrip writes a movie through one version and reads it through the others, every version
+// sees the fields it knows of the same record
+func TestRoundTrip(t *testing.T) {
+	h, _ := handlertest.New()
+
+	var created struct {
+		Movie movie `json:"movie"`
+	}
+	handlertest.Decode(t, handlertest.Serve(t, h.V1.Movies.CreateHandler, http.MethodPost, "/v1/movies", `{"title": "Memento", "year": 2000, "genre": "thriller"}`), http.StatusCreated, &created)
+
+	id := fmt.Sprint(created.Movie.ID)
+	target := "/movies/" + id
+
+	get := func(t *testing.T, handler http.HandlerFunc) movie {
+		t.Helper()
+
+		var got struct {
+			Movie movie `json:"movie"`
+		}
+		handlertest.Decode(t, handlertest.Serve(t, handler, http.MethodGet, target, "", "id", id), http.StatusOK, &got)
+
+		return got.Movie
+	}
+
+	// a movie created before v2 has none of the fields added since, and can still be updated
+	got := get(t, h.V5.Movies.GetHandler)
+	if !slices.Equal(got.Genres, []string{"thriller"}) || got.Runtime != nil || got.Language != nil || len(got.Crew) != 0 {
+		t.Fatalf("v5 got %+v, want the genre of v1 as the only genre", got)
+	}
+
+	handlertest.Decode(t, handlertest.Serve(t, h.V3.Movies.UpdateHandler, http.MethodPatch, target, `{"genres": ["mystery", "thriller"], "director": "Christopher Nolan", "runtime": 113}`, "id", id), http.StatusOK, nil)
+
+	if got := get(t, h.V1.Movies.GetHandler); got.Genre != "mystery" || got.Version != 2 {
+		t.Errorf("v1 got %+v, want the first genre at version 2", got)
+	}
+
+	if got := get(t, h.V2.Movies.GetHandler); got.Director == nil || *got.Director != "Christopher Nolan" || got.Runtime == nil || *got.Runtime != 113 {
+		t.Errorf("v2 got %+v, want the director and runtime written through v3", got)
+	}
+
+	got = get(t, h.V5.Movies.GetHandler)
+	if !slices.Equal(got.Genres, []string{"mystery", "thriller"}) {
+		t.Errorf("v5 got genres %v, want the genres written through v3", got.Genres)
+	}
+	if len(got.Crew) != 1 || got.Crew[0].PersonName != "Christopher Nolan" || got.Crew[0].CrewType != "Director" {
+		t.Errorf("v5 got crew %+v, want the director of v3 as the Director", got.Crew)
+	}
+
+	// a genre written through v1 goes first, the others are kept
+	handlertest.Decode(t, handlertest.Serve(t, h.V1.Movies.UpdateHandler, http.MethodPatch, target, `{"genre": "drama"}`, "id", id), http.StatusOK, nil)
+
+	if got := get(t, h.V4.Movies.GetHandler); !slices.Equal(got.Genres, []string{"drama", "mystery", "thriller"}) {
+		t.Errorf("v4 got genres %v, want the genre of v1 before the others", got.Genres)
+	}
+
+	// the actors of v4 are the people of v5, a crew written through v5 is the cast and director of v4
+	var actor struct {
+		Actor movie `json:"actor"`
+	}
+	handlertest.Decode(t, handlertest.Serve(t, h.V4.Actors.CreateHandler, http.MethodPost, "/v4/actors", `{"name": "Guy Pearce", "birthdate": "1967-10-05"}`), http.StatusCreated, &actor)
+
+	var person struct {
+		Person struct {
+			Name string `json:"name"`
+		} `json:"person"`
+	}
+	handlertest.Decode(t, handlertest.Serve(t, h.V5.People.GetHandler, http.MethodGet, "/v5/people", "", "id", fmt.Sprint(actor.Actor.ID)), http.StatusOK, &person)
+
+	if person.Person.Name != "Guy Pearce" {
+		t.Errorf("v5 got person %+v, want the actor of v4", person.Person)
+	}
+
+	body := fmt.Sprintf(`{"crew": [{"person_id": %d, "crew_type": "Director"}, {"person_id": %d, "crew_type": "Actor", "role": "Leonard"}]}`, actor.Actor.ID, actor.Actor.ID)
+	handlertest.Decode(t, handlertest.Serve(t, h.V5.Movies.UpdateHandler, http.MethodPatch, target, body, "id", id), http.StatusOK, nil)
+
+	got = get(t, h.V4.Movies.GetHandler)
+	if got.Director == nil || *got.Director != "Guy Pearce" {
+		t.Errorf("v4 got director %v, want the Director of v5", got.Director)
+	}
+	if len(got.Actors) != 1 || got.Actors[0].ActorID != actor.Actor.ID || got.Actors[0].Role != "Leonard" {
+		t.Errorf("v4 got actors %+v, want the Actor of v5", got.Actors)
+	}
+
+	handlertest.Decode(t, handlertest.Serve(t, h.V2.Movies.DeleteHandler, http.MethodDelete, target, "", "id", id), http.StatusOK, nil)
+	handlertest.Decode(t, handlertest.Serve(t, h.V5.Movies.GetHandler, http.MethodGet, target, "", "id", id), http.StatusNotFound, nil)
+}
@@ -0,0 +1,66 @@
+// Package handlertest calls the handlers of every version directly on the in-memory
+// repositories, with the route params the router would set
+package handlertest
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/julienschmidt/httprouter"
+	"thesis.lefler.eu/internal/data/memory"
+	e "thesis.lefler.eu/internal/error"
+	"thesis.lefler.eu/internal/handler"
+	"thesis.lefler.eu/internal/repository"
+)
+
+// New returns the handlers of every version on an empty memory store, and its repositories,
+// which tests can seed or replace
+func New() (handler.Versions, *repository.Repositories) {
+	errors := e.NewErrors(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	models := memory.New().Repositories()
+
+	return handler.NewVersions(&errors, &models), &models
+}
+
+// Serve calls a handler with the route params given as key, value pairs
+func Serve(t *testing.T, handler http.HandlerFunc, method, target, body string, params ...string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	var ps httprouter.Params
+	for i := 0; i+1 < len(params); i += 2 {
+		ps = append(ps, httprouter.Param{Key: params[i], Value: params[i+1]})
+	}
+
+	r := httptest.NewRequest(method, target, strings.NewReader(body))
+	r = r.WithContext(context.WithValue(r.Context(), httprouter.ParamsKey, ps))
+
+	w := httptest.NewRecorder()
+	handler(w, r)
+
+	return w
+}
+
+// Decode reads the envelope of a response into dst unless it is nil, failing unless the
+// response has the status
+func Decode(t *testing.T, w *httptest.ResponseRecorder, status int, dst any) {
+	t.Helper()
+
+	if w.Code != status {
+		t.Fatalf("got status %d, want %d: %s", w.Code, status, w.Body)
+	}
+
+	if dst == nil {
+		return
+	}
+
+	err := json.Unmarshal(w.Body.Bytes(), dst)
+	if err != nil {
+		t.Fatalf("decode %s: %v", w.Body, err)
+	}
+}
@@ -1,15 +1,15 @@
 package v2
 
 import (
-	data "thesis.lefler.eu/internal/data/views/v2"
 	e "thesis.lefler.eu/internal/error"
+	"thesis.lefler.eu/internal/repository"
 )
 
 type Handlers struct {
 	Movies MovieHandler
 }
 
-func NewHandlers(errors *e.Errors, models *data.Models) Handlers {
+func NewHandlers(errors *e.Errors, models *repository.RepositoriesV2) Handlers {
 	return Handlers{
 		Movies: MovieHandler{
 			errors: errors,
@@ -6,15 +6,17 @@ import (
 	"net/http"
 
 	"cloud.google.com/go/civil"
-	data "thesis.lefler.eu/internal/data/expand_deprecate/v4"
+
 	e "thesis.lefler.eu/internal/error"
+	model "thesis.lefler.eu/internal/model/v4"
+	"thesis.lefler.eu/internal/repository"
 	"thesis.lefler.eu/internal/util"
 	"thesis.lefler.eu/internal/validator"
 )
 
 type ActorHandler struct {
 	errors *e.Errors
-	models *data.Models
+	models *repository.RepositoriesV4
 }
 
 func (handler *ActorHandler) CreateHandler(w http.ResponseWriter, r *http.Request) {
@@ -29,14 +31,14 @@ func (handler *ActorHandler) CreateHandler(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	actor := &data.Actor{
+	actor := &model.Actor{
 		Name:      input.Name,
 		Birthdate: &input.Birthdate,
 	}
 
 	v := validator.New()
 
-	if data.ValidateActor(v, actor); !v.Valid() {
+	if model.ValidateActor(v, actor); !v.Valid() {
 		handler.errors.FailedValidationResponse(w, r, v.Errors)
 		return
 	}
@@ -66,7 +68,7 @@ func (handler *ActorHandler) GetHandler(w http.ResponseWriter, r *http.Request)
 	actor, err := handler.models.Actors.Get(id)
 	if err != nil {
 		switch {
-		case errors.Is(err, data.ErrRecordNotFound):
+		case errors.Is(err, repository.ErrRecordNotFound):
 			handler.errors.NotFoundResponse(w, r)
 		default:
 			handler.errors.ServerErrorResponse(w, r, err)
@@ -90,7 +92,7 @@ func (handler *ActorHandler) UpdateHandler(w http.ResponseWriter, r *http.Reques
 	actor, err := handler.models.Actors.Get(id)
 	if err != nil {
 		switch {
-		case errors.Is(err, data.ErrRecordNotFound):
+		case errors.Is(err, repository.ErrRecordNotFound):
 			handler.errors.NotFoundResponse(w, r)
 		default:
 			handler.errors.ServerErrorResponse(w, r, err)
@@ -119,7 +121,7 @@ func (handler *ActorHandler) UpdateHandler(w http.ResponseWriter, r *http.Reques
 
 	v := validator.New()
 
-	if data.ValidateActor(v, actor); !v.Valid() {
+	if model.ValidateActor(v, actor); !v.Valid() {
 		handler.errors.FailedValidationResponse(w, r, v.Errors)
 		return
 	}
@@ -127,7 +129,7 @@ func (handler *ActorHandler) UpdateHandler(w http.ResponseWriter, r *http.Reques
 	err = handler.models.Actors.Update(actor)
 	if err != nil {
 		switch {
-		case errors.Is(err, data.ErrEditConflict):
+		case errors.Is(err, repository.ErrEditConflict):
 			handler.errors.EditConflictResponse(w, r)
 		default:
 			handler.errors.ServerErrorResponse(w, r, err)
@@ -151,7 +153,7 @@ func (handler *ActorHandler) DeleteHandler(w http.ResponseWriter, r *http.Reques
 	err = handler.models.Actors.Delete(id)
 	if err != nil {
 		switch {
-		case errors.Is(err, data.ErrRecordNotFound):
+		case errors.Is(err, repository.ErrRecordNotFound):
 			handler.errors.NotFoundResponse(w, r)
 		default:
 			handler.errors.ServerErrorResponse(w, r, err)
@@ -166,13 +168,54 @@ func (handler *ActorHandler) DeleteHandler(w http.ResponseWriter, r *http.Reques
 }
 
 func (handler *ActorHandler) ListHandler(w http.ResponseWriter, r *http.Request) {
-	actors, err := handler.models.Actors.GetAll()
+	var input struct {
+		repository.PersonFilter
+		repository.Filters
+	}
+
+	v := validator.New()
+
+	qs := r.URL.Query()
+
+	input.Name = util.ReadString(qs, "name", "")
+	input.Query = util.ReadString(qs, "q", "")
+
+	input.Page = util.ReadInt(qs, "page", 1, v)
+	input.PageSize = util.ReadInt(qs, "page_size", 20, v)
+	input.After = util.ReadCursor(qs, "after", v)
+	input.Before = util.ReadCursor(qs, "before", v)
+
+	// a limit or a cursor pages the list by cursor instead of page number
+	input.Cursor = qs.Has("limit") || input.After != nil || input.Before != nil
+	if input.Cursor {
+		input.PageSize = util.ReadInt(qs, "limit", 20, v)
+	}
+
+	// searched lists are sorted by relevance unless sorted otherwise
+	sort := "id"
+	if input.Query != "" && !input.Cursor {
+		sort = "relevance"
+	}
+
+	input.Sort = util.ReadString(qs, "sort", sort)
+	input.SortSafelist = []string{"id", "name", "birthdate", "-id", "-name", "-birthdate", "relevance"}
+
+	repository.ValidateSearch(v, input.Query, input.Filters)
+
+	if repository.ValidateFilters(v, input.Filters); !v.Valid() {
+		handler.errors.FailedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	actors, metadata, err := handler.models.Actors.GetAll(input.PersonFilter, input.Filters)
 	if err != nil {
 		handler.errors.ServerErrorResponse(w, r, err)
 		return
 	}
 
-	err = util.WriteJSON(w, http.StatusOK, util.Envelope{"actors": actors}, nil)
+	metadata.Link(r.URL)
+
+	err = util.WriteJSON(w, http.StatusOK, util.Envelope{"actors": actors, "metadata": metadata}, nil)
 	if err != nil {
 		handler.errors.ServerErrorResponse(w, r, err)
 	}
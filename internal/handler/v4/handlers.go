@@ -0,0 +1,29 @@
+package v4
+
+import (
+	e "thesis.lefler.eu/internal/error"
+	"thesis.lefler.eu/internal/repository"
+)
+
+type Handlers struct {
+	Movies      MovieHandler
+	Actors      ActorHandler
+	MovieActors MovieActorHandler
+}
+
+func NewHandlers(errors *e.Errors, models *repository.RepositoriesV4) Handlers {
+	return Handlers{
+		Movies: MovieHandler{
+			errors: errors,
+			models: models,
+		},
+		Actors: ActorHandler{
+			errors: errors,
+			models: models,
+		},
+		MovieActors: MovieActorHandler{
+			errors: errors,
+			models: models,
+		},
+	}
+}
@@ -0,0 +1,279 @@
+package v4
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	e "thesis.lefler.eu/internal/error"
+	model "thesis.lefler.eu/internal/model/v4"
+	"thesis.lefler.eu/internal/repository"
+	"thesis.lefler.eu/internal/util"
+	"thesis.lefler.eu/internal/validator"
+)
+
+// MovieActorHandler serves the actors of a movie at /movies/:id/actors, each of them at
+// /movies/:id/actors/:actor_id
+type MovieActorHandler struct {
+	errors *e.Errors
+	models *repository.RepositoriesV4
+}
+
+// movieActor is an actor of a movie with the version it is updated and deleted at
+type movieActor struct {
+	*model.MovieActor
+	Version int32 `json:"version"`
+}
+
+func (handler *MovieActorHandler) CreateHandler(w http.ResponseWriter, r *http.Request) {
+	movieID, ok := handler.movie(w, r)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		ActorID int64  `json:"actor_id"`
+		Role    string `json:"role"`
+	}
+
+	err := util.ReadJSON(w, r, &input)
+	if err != nil {
+		handler.errors.BadRequestResponse(w, r, err)
+		return
+	}
+
+	ma := &model.MovieActor{
+		MovieID: movieID,
+		ActorID: input.ActorID,
+		Role:    input.Role,
+	}
+
+	v := validator.New()
+
+	if model.ValidateCrew(v, ma); !v.Valid() {
+		handler.errors.FailedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	actor, err := handler.models.Actors.Get(ma.ActorID)
+	if err != nil {
+		switch {
+		case errors.Is(err, repository.ErrRecordNotFound):
+			handler.errors.NotFoundResponse(w, r)
+		default:
+			handler.errors.ServerErrorResponse(w, r, err)
+		}
+		return
+	}
+	ma.ActorName = actor.Name
+
+	_, err = handler.models.MovieActors.Get(ma.MovieID, ma.ActorID)
+	switch {
+	case err == nil:
+		v.AddError("actor_id", "the actor is already in the movie")
+		handler.errors.FailedValidationResponse(w, r, v.Errors)
+		return
+	case !errors.Is(err, repository.ErrRecordNotFound):
+		handler.errors.ServerErrorResponse(w, r, err)
+		return
+	}
+
+	err = handler.models.MovieActors.Insert(ma)
+	if err != nil {
+		handler.errors.ServerErrorResponse(w, r, err)
+		return
+	}
+
+	// the actors are served under the prefix of each strategy, the actor is below the actors it was posted to
+	headers := make(http.Header)
+	headers.Set("Location", fmt.Sprintf("%s/%d", r.URL.Path, ma.ActorID))
+
+	err = util.WriteJSON(w, http.StatusCreated, util.Envelope{"actor": movieActor{ma, ma.Version}}, headers)
+	if err != nil {
+		handler.errors.ServerErrorResponse(w, r, err)
+	}
+}
+
+func (handler *MovieActorHandler) GetHandler(w http.ResponseWriter, r *http.Request) {
+	ma, ok := handler.movieActor(w, r)
+	if !ok {
+		return
+	}
+
+	err := util.WriteJSON(w, http.StatusOK, util.Envelope{"actor": movieActor{ma, ma.Version}}, nil)
+	if err != nil {
+		handler.errors.ServerErrorResponse(w, r, err)
+	}
+}
+
+// UpdateHandler changes the role of an actor in the movie. The version in the body must match
+// the current one, so changes made since the client read it are not lost.
+func (handler *MovieActorHandler) UpdateHandler(w http.ResponseWriter, r *http.Request) {
+	ma, ok := handler.movieActor(w, r)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		Role    *string `json:"role"`
+		Version *int32  `json:"version"`
+	}
+
+	err := util.ReadJSON(w, r, &input)
+	if err != nil {
+		handler.errors.BadRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+
+	if v.Check(input.Version != nil, "version", "must be provided"); !v.Valid() {
+		handler.errors.FailedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	if *input.Version != ma.Version {
+		handler.errors.EditConflictResponse(w, r)
+		return
+	}
+
+	if input.Role != nil {
+		ma.Role = *input.Role
+	}
+
+	if model.ValidateCrew(v, ma); !v.Valid() {
+		handler.errors.FailedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = handler.models.MovieActors.Update(ma)
+	if err != nil {
+		switch {
+		case errors.Is(err, repository.ErrEditConflict):
+			handler.errors.EditConflictResponse(w, r)
+		default:
+			handler.errors.ServerErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = util.WriteJSON(w, http.StatusOK, util.Envelope{"actor": movieActor{ma, ma.Version}}, nil)
+	if err != nil {
+		handler.errors.ServerErrorResponse(w, r, err)
+	}
+}
+
+// DeleteHandler removes an actor from the movie, at the version in the query string
+func (handler *MovieActorHandler) DeleteHandler(w http.ResponseWriter, r *http.Request) {
+	ma, ok := handler.movieActor(w, r)
+	if !ok {
+		return
+	}
+
+	v := validator.New()
+
+	qs := r.URL.Query()
+
+	v.Check(qs.Has("version"), "version", "must be provided")
+	version := util.ReadInt(qs, "version", 0, v)
+
+	if !v.Valid() {
+		handler.errors.FailedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	if version != int(ma.Version) {
+		handler.errors.EditConflictResponse(w, r)
+		return
+	}
+
+	err := handler.models.MovieActors.Delete(ma)
+	if err != nil {
+		switch {
+		case errors.Is(err, repository.ErrEditConflict):
+			handler.errors.EditConflictResponse(w, r)
+		default:
+			handler.errors.ServerErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = util.WriteJSON(w, http.StatusOK, util.Envelope{"message": "actor successfully removed from the movie"}, nil)
+	if err != nil {
+		handler.errors.ServerErrorResponse(w, r, err)
+	}
+}
+
+func (handler *MovieActorHandler) ListHandler(w http.ResponseWriter, r *http.Request) {
+	movieID, ok := handler.movie(w, r)
+	if !ok {
+		return
+	}
+
+	movieActors, err := handler.models.MovieActors.GetForMovie(movieID)
+	if err != nil {
+		handler.errors.ServerErrorResponse(w, r, err)
+		return
+	}
+
+	actors := make([]movieActor, len(movieActors))
+	for i, ma := range movieActors {
+		actors[i] = movieActor{ma, ma.Version}
+	}
+
+	err = util.WriteJSON(w, http.StatusOK, util.Envelope{"actors": actors}, nil)
+	if err != nil {
+		handler.errors.ServerErrorResponse(w, r, err)
+	}
+}
+
+// movie reads the id of the movie the actors belong to, the actors of a movie that does not
+// exist are not found rather than empty. It responds itself if it is not ok.
+func (handler *MovieActorHandler) movie(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	id, err := util.ReadIDParam(r)
+	if err != nil {
+		handler.errors.NotFoundResponse(w, r)
+		return 0, false
+	}
+
+	_, err = handler.models.Movies.Get(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, repository.ErrRecordNotFound):
+			handler.errors.NotFoundResponse(w, r)
+		default:
+			handler.errors.ServerErrorResponse(w, r, err)
+		}
+		return 0, false
+	}
+
+	return id, true
+}
+
+// movieActor reads the actor of the movie the route points to. It responds itself if it is not ok.
+func (handler *MovieActorHandler) movieActor(w http.ResponseWriter, r *http.Request) (*model.MovieActor, bool) {
+	movieID, err := util.ReadIDParam(r)
+	if err != nil {
+		handler.errors.NotFoundResponse(w, r)
+		return nil, false
+	}
+
+	actorID, err := util.ReadNamedIDParam(r, "actor_id")
+	if err != nil {
+		handler.errors.NotFoundResponse(w, r)
+		return nil, false
+	}
+
+	ma, err := handler.models.MovieActors.Get(movieID, actorID)
+	if err != nil {
+		switch {
+		case errors.Is(err, repository.ErrRecordNotFound):
+			handler.errors.NotFoundResponse(w, r)
+		default:
+			handler.errors.ServerErrorResponse(w, r, err)
+		}
+		return nil, false
+	}
+
+	return ma, true
+}
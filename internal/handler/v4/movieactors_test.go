@@ -0,0 +1,150 @@
+package v4_test
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"thesis.lefler.eu/internal/handler"
+	"thesis.lefler.eu/internal/handler/handlertest"
+	model "thesis.lefler.eu/internal/model/v4"
+	"thesis.lefler.eu/internal/repository"
+)
+
+type actorResponse struct {
+	Actor struct {
+		ActorID   int64  `json:"actor_id"`
+		ActorName string `json:"actor_name"`
+		Role      string `json:"role"`
+		Version   int32  `json:"version"`
+	} `json:"actor"`
+}
+
+// insertActor seeds an actor, as a movie only refers to existing actors
+func insertActor(t *testing.T, models *repository.Repositories, name string) *model.Actor {
+	t.Helper()
+
+	actor := &model.Actor{Name: name}
+
+	err := models.V4.Actors.Insert(actor)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return actor
+}
+
+// insertMovie seeds a movie without actors
+func insertMovie(t *testing.T, models *repository.Repositories) *model.Movie {
+	t.Helper()
+
+	director, runtime, language := "Christopher Nolan", int32(113), "English"
+	movie := &model.Movie{Title: "Memento", Year: 2000, Genres: []string{"thriller"}, Director: &director, Runtime: &runtime, Language: &language}
+
+	err := models.V4.Movies.Insert(movie)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return movie
+}
+
+// addActor adds a new actor to the movie through the handler and returns the route params of it
+func addActor(t *testing.T, h handler.Versions, movieID, actorID int64) []string {
+	t.Helper()
+
+	body := fmt.Sprintf(`{"actor_id": %d, "role": "Leonard"}`, actorID)
+	w := handlertest.Serve(t, h.V4.MovieActors.CreateHandler, http.MethodPost, fmt.Sprintf("/v4/movies/%d/actors", movieID), body, "id", fmt.Sprint(movieID))
+	handlertest.Decode(t, w, http.StatusCreated, nil)
+
+	return []string{"id", fmt.Sprint(movieID), "actor_id", fmt.Sprint(actorID)}
+}
+
+func TestMovieActorCreateLocation(t *testing.T) {
+	h, models := handlertest.New()
+	movie := insertMovie(t, models)
+	actor := insertActor(t, models, "Guy Pearce")
+
+	// the actor is below the actors of the movie it was posted to, whatever prefix the strategy serves it at
+	target := fmt.Sprintf("/branches/v4/movies/%d/actors", movie.ID)
+	body := fmt.Sprintf(`{"actor_id": %d, "role": "Leonard"}`, actor.ID)
+	w := handlertest.Serve(t, h.V4.MovieActors.CreateHandler, http.MethodPost, target, body, "id", fmt.Sprint(movie.ID))
+
+	var created actorResponse
+	handlertest.Decode(t, w, http.StatusCreated, &created)
+
+	want := fmt.Sprintf("%s/%d", target, actor.ID)
+	if got := w.Header().Get("Location"); got != want {
+		t.Errorf("got Location %q, want %q", got, want)
+	}
+	if created.Actor.ActorName != "Guy Pearce" || created.Actor.Version != 1 {
+		t.Errorf("got %+v, want Guy Pearce at version 1", created.Actor)
+	}
+}
+
+func TestMovieActorUpdateVersion(t *testing.T) {
+	h, models := handlertest.New()
+	movie := insertMovie(t, models)
+	actor := insertActor(t, models, "Guy Pearce")
+	params := addActor(t, h, movie.ID, actor.ID)
+	target := fmt.Sprintf("/v4/movies/%d/actors/%d", movie.ID, actor.ID)
+
+	tests := []struct {
+		name   string
+		body   string
+		status int
+	}{
+		{"missing version", `{"role": "Leonard Shelby"}`, http.StatusUnprocessableEntity},
+		{"stale version", `{"role": "Leonard Shelby", "version": 2}`, http.StatusConflict},
+		{"current version", `{"role": "Leonard Shelby", "version": 1}`, http.StatusOK},
+		{"version used up", `{"role": "Teddy", "version": 1}`, http.StatusConflict},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := handlertest.Serve(t, h.V4.MovieActors.UpdateHandler, http.MethodPatch, target, tt.body, params...)
+			handlertest.Decode(t, w, tt.status, nil)
+		})
+	}
+
+	var got actorResponse
+	handlertest.Decode(t, handlertest.Serve(t, h.V4.MovieActors.GetHandler, http.MethodGet, target, "", params...), http.StatusOK, &got)
+
+	if got.Actor.Role != "Leonard Shelby" || got.Actor.Version != 2 {
+		t.Errorf("got %+v, want the role of the one update at version 2", got.Actor)
+	}
+}
+
+func TestMovieActorDeleteVersion(t *testing.T) {
+	h, models := handlertest.New()
+	movie := insertMovie(t, models)
+	actor := insertActor(t, models, "Guy Pearce")
+	params := addActor(t, h, movie.ID, actor.ID)
+	target := fmt.Sprintf("/v4/movies/%d/actors/%d", movie.ID, actor.ID)
+
+	tests := []struct {
+		name   string
+		query  string
+		status int
+	}{
+		{"missing version", "", http.StatusUnprocessableEntity},
+		{"stale version", "?version=2", http.StatusConflict},
+		{"current version", "?version=1", http.StatusOK},
+		{"deleted", "?version=1", http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := handlertest.Serve(t, h.V4.MovieActors.DeleteHandler, http.MethodDelete, target+tt.query, "", params...)
+			handlertest.Decode(t, w, tt.status, nil)
+		})
+	}
+
+	actors, err := models.V4.MovieActors.GetForMovie(movie.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(actors) != 0 {
+		t.Errorf("got actors %+v, want none", actors)
+	}
+}
@@ -5,15 +5,16 @@ import (
 	"fmt"
 	"net/http"
 
-	data "thesis.lefler.eu/internal/data/expand_deprecate/v4"
 	e "thesis.lefler.eu/internal/error"
+	model "thesis.lefler.eu/internal/model/v4"
+	"thesis.lefler.eu/internal/repository"
 	util "thesis.lefler.eu/internal/util"
 	"thesis.lefler.eu/internal/validator"
 )
 
 type MovieHandler struct {
 	errors *e.Errors
-	models *data.Models
+	models *repository.RepositoriesV4
 }
 
 func (handler *MovieHandler) CreateHandler(w http.ResponseWriter, r *http.Request) {
@@ -37,7 +38,7 @@ func (handler *MovieHandler) CreateHandler(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	movie := &data.Movie{
+	movie := &model.Movie{
 		Title:    input.Title,
 		Year:     input.Year,
 		Genres:   input.Genres,
@@ -48,7 +49,7 @@ func (handler *MovieHandler) CreateHandler(w http.ResponseWriter, r *http.Reques
 
 	v := validator.New()
 
-	if data.ValidateMovie(v, movie); !v.Valid() {
+	if model.ValidateMovie(v, movie); !v.Valid() {
 		handler.errors.FailedValidationResponse(w, r, v.Errors)
 		return
 	}
@@ -65,7 +66,7 @@ func (handler *MovieHandler) CreateHandler(w http.ResponseWriter, r *http.Reques
 			return
 		}
 
-		movieActor := data.MovieActor{
+		movieActor := model.MovieActor{
 			MovieID: movie.ID,
 			ActorID: a.ActorID,
 			Role:    a.Role,
@@ -97,7 +98,7 @@ func (handler *MovieHandler) GetHandler(w http.ResponseWriter, r *http.Request)
 	movie, err := handler.models.Movies.Get(id)
 	if err != nil {
 		switch {
-		case errors.Is(err, data.ErrRecordNotFound):
+		case errors.Is(err, repository.ErrRecordNotFound):
 			handler.errors.NotFoundResponse(w, r)
 		default:
 			handler.errors.ServerErrorResponse(w, r, err)
@@ -108,7 +109,7 @@ func (handler *MovieHandler) GetHandler(w http.ResponseWriter, r *http.Request)
 	movie.Actors, err = handler.models.MovieActors.GetForMovie(movie.ID)
 	if err != nil {
 		switch {
-		case errors.Is(err, data.ErrRecordNotFound):
+		case errors.Is(err, repository.ErrRecordNotFound):
 			handler.errors.NotFoundResponse(w, r)
 		default:
 			handler.errors.ServerErrorResponse(w, r, err)
@@ -132,7 +133,7 @@ func (handler *MovieHandler) UpdateHandler(w http.ResponseWriter, r *http.Reques
 	movie, err := handler.models.Movies.Get(id)
 	if err != nil {
 		switch {
-		case errors.Is(err, data.ErrRecordNotFound):
+		case errors.Is(err, repository.ErrRecordNotFound):
 			handler.errors.NotFoundResponse(w, r)
 		default:
 			handler.errors.ServerErrorResponse(w, r, err)
@@ -181,7 +182,7 @@ func (handler *MovieHandler) UpdateHandler(w http.ResponseWriter, r *http.Reques
 
 	v := validator.New()
 
-	if data.ValidateMovie(v, movie); !v.Valid() {
+	if model.ValidateMovie(v, movie); !v.Valid() {
 		handler.errors.FailedValidationResponse(w, r, v.Errors)
 		return
 	}
@@ -189,7 +190,7 @@ func (handler *MovieHandler) UpdateHandler(w http.ResponseWriter, r *http.Reques
 	err = handler.models.Movies.Update(movie)
 	if err != nil {
 		switch {
-		case errors.Is(err, data.ErrEditConflict):
+		case errors.Is(err, repository.ErrEditConflict):
 			handler.errors.EditConflictResponse(w, r)
 		default:
 			handler.errors.ServerErrorResponse(w, r, err)
@@ -199,7 +200,7 @@ func (handler *MovieHandler) UpdateHandler(w http.ResponseWriter, r *http.Reques
 
 	if input.Actors != nil {
 		err = handler.models.MovieActors.DeleteForMovie(movie.ID)
-		if err != nil {
+		if err != nil && !errors.Is(err, repository.ErrRecordNotFound) {
 			handler.errors.ServerErrorResponse(w, r, err)
 			return
 		}
@@ -213,7 +214,7 @@ func (handler *MovieHandler) UpdateHandler(w http.ResponseWriter, r *http.Reques
 			actor, err := handler.models.Actors.Get(a.ActorID)
 			if err != nil {
 				switch {
-				case errors.Is(err, data.ErrRecordNotFound):
+				case errors.Is(err, repository.ErrRecordNotFound):
 					handler.errors.NotFoundResponse(w, r)
 				default:
 					handler.errors.ServerErrorResponse(w, r, err)
@@ -221,7 +222,7 @@ func (handler *MovieHandler) UpdateHandler(w http.ResponseWriter, r *http.Reques
 				return
 			}
 
-			movieActor := data.MovieActor{
+			movieActor := model.MovieActor{
 				MovieID:   movie.ID,
 				ActorID:   a.ActorID,
 				ActorName: actor.Name,
@@ -253,7 +254,7 @@ func (handler *MovieHandler) DeleteHandler(w http.ResponseWriter, r *http.Reques
 	err = handler.models.Movies.Delete(id)
 	if err != nil {
 		switch {
-		case errors.Is(err, data.ErrRecordNotFound):
+		case errors.Is(err, repository.ErrRecordNotFound):
 			handler.errors.NotFoundResponse(w, r)
 		default:
 			handler.errors.ServerErrorResponse(w, r, err)
@@ -268,13 +269,58 @@ func (handler *MovieHandler) DeleteHandler(w http.ResponseWriter, r *http.Reques
 }
 
 func (handler *MovieHandler) ListHandler(w http.ResponseWriter, r *http.Request) {
-	movies, err := handler.models.Movies.GetAll()
+	var input struct {
+		repository.MovieFilter
+		repository.Filters
+	}
+
+	v := validator.New()
+
+	qs := r.URL.Query()
+
+	input.Title = util.ReadString(qs, "title", "")
+	input.Query = util.ReadString(qs, "q", "")
+	input.Genres = util.ReadCSV(qs, "genres", []string{})
+	input.YearFrom = int32(util.ReadInt(qs, "year_from", 0, v))
+	input.YearTo = int32(util.ReadInt(qs, "year_to", 0, v))
+
+	input.Page = util.ReadInt(qs, "page", 1, v)
+	input.PageSize = util.ReadInt(qs, "page_size", 20, v)
+	input.After = util.ReadCursor(qs, "after", v)
+	input.Before = util.ReadCursor(qs, "before", v)
+
+	// a limit or a cursor pages the list by cursor instead of page number
+	input.Cursor = qs.Has("limit") || input.After != nil || input.Before != nil
+	if input.Cursor {
+		input.PageSize = util.ReadInt(qs, "limit", 20, v)
+	}
+
+	// searched lists are sorted by relevance unless sorted otherwise
+	sort := "id"
+	if input.Query != "" && !input.Cursor {
+		sort = "relevance"
+	}
+
+	input.Sort = util.ReadString(qs, "sort", sort)
+	input.SortSafelist = []string{"id", "title", "year", "runtime", "-id", "-title", "-year", "-runtime", "relevance"}
+
+	repository.ValidateMovieFilter(v, input.MovieFilter)
+	repository.ValidateSearch(v, input.Query, input.Filters)
+
+	if repository.ValidateFilters(v, input.Filters); !v.Valid() {
+		handler.errors.FailedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	movies, metadata, err := handler.models.Movies.GetAll(input.MovieFilter, input.Filters)
 	if err != nil {
 		handler.errors.ServerErrorResponse(w, r, err)
 		return
 	}
 
-	err = util.WriteJSON(w, http.StatusOK, util.Envelope{"movies": movies}, nil)
+	metadata.Link(r.URL)
+
+	err = util.WriteJSON(w, http.StatusOK, util.Envelope{"movies": movies, "metadata": metadata}, nil)
 	if err != nil {
 		handler.errors.ServerErrorResponse(w, r, err)
 	}
@@ -0,0 +1,28 @@
+package v4_test
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"thesis.lefler.eu/internal/handler/handlertest"
+)
+
+func TestMovieUpdateAddsFirstActors(t *testing.T) {
+	h, models := handlertest.New()
+	movie := insertMovie(t, models)
+	actor := insertActor(t, models, "Guy Pearce")
+
+	// a movie without actors has none to replace
+	body := fmt.Sprintf(`{"actors": [{"actor_id": %d, "role": "Leonard"}]}`, actor.ID)
+	w := handlertest.Serve(t, h.V4.Movies.UpdateHandler, http.MethodPatch, "/v4/movies/1", body, "id", fmt.Sprint(movie.ID))
+	handlertest.Decode(t, w, http.StatusOK, nil)
+
+	actors, err := models.V4.MovieActors.GetForMovie(movie.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(actors) != 1 || actors[0].ActorID != actor.ID || actors[0].Role != "Leonard" {
+		t.Errorf("got actors %+v, want Guy Pearce as Leonard", actors)
+	}
+}
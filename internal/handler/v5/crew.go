@@ -0,0 +1,283 @@
+package v5
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	e "thesis.lefler.eu/internal/error"
+	model "thesis.lefler.eu/internal/model/v5"
+	"thesis.lefler.eu/internal/repository"
+	util "thesis.lefler.eu/internal/util"
+	"thesis.lefler.eu/internal/validator"
+)
+
+// CrewHandler serves the crew of a movie at /movies/:id/crew, its members at
+// /movies/:id/crew/:person_id/:crew_type, as a person can have several jobs in a movie
+type CrewHandler struct {
+	errors *e.Errors
+	models *repository.RepositoriesV5
+}
+
+// crewMember is a member of the crew with the version it is updated and deleted at
+type crewMember struct {
+	*model.Crew
+	Version int32 `json:"version"`
+}
+
+func (handler *CrewHandler) CreateHandler(w http.ResponseWriter, r *http.Request) {
+	movieID, ok := handler.movie(w, r)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		PersonID int64  `json:"person_id"`
+		CrewType string `json:"crew_type"`
+		Role     string `json:"role,omitempty"`
+	}
+
+	err := util.ReadJSON(w, r, &input)
+	if err != nil {
+		handler.errors.BadRequestResponse(w, r, err)
+		return
+	}
+
+	crew := &model.Crew{
+		MovieID:  movieID,
+		PersonID: input.PersonID,
+		CrewType: input.CrewType,
+		Role:     input.Role,
+	}
+
+	v := validator.New()
+
+	if model.ValidateCrew(v, crew); !v.Valid() {
+		handler.errors.FailedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	person, err := handler.models.People.Get(crew.PersonID)
+	if err != nil {
+		switch {
+		case errors.Is(err, repository.ErrRecordNotFound):
+			handler.errors.NotFoundResponse(w, r)
+		default:
+			handler.errors.ServerErrorResponse(w, r, err)
+		}
+		return
+	}
+	crew.PersonName = person.Name
+
+	_, err = handler.models.Crew.Get(crew.MovieID, crew.PersonID, crew.CrewType)
+	switch {
+	case err == nil:
+		v.AddError("crew_type", "the person already has this crew_type in the movie")
+		handler.errors.FailedValidationResponse(w, r, v.Errors)
+		return
+	case !errors.Is(err, repository.ErrRecordNotFound):
+		handler.errors.ServerErrorResponse(w, r, err)
+		return
+	}
+
+	err = handler.models.Crew.Insert(crew)
+	if err != nil {
+		handler.errors.ServerErrorResponse(w, r, err)
+		return
+	}
+
+	// the crew is served under the prefix of each strategy, the member is below the crew it was posted to
+	headers := make(http.Header)
+	headers.Set("Location", fmt.Sprintf("%s/%d/%s", r.URL.Path, crew.PersonID, crew.CrewType))
+
+	err = util.WriteJSON(w, http.StatusCreated, util.Envelope{"crew_member": crewMember{crew, crew.Version}}, headers)
+	if err != nil {
+		handler.errors.ServerErrorResponse(w, r, err)
+	}
+}
+
+func (handler *CrewHandler) GetHandler(w http.ResponseWriter, r *http.Request) {
+	crew, ok := handler.member(w, r)
+	if !ok {
+		return
+	}
+
+	err := util.WriteJSON(w, http.StatusOK, util.Envelope{"crew_member": crewMember{crew, crew.Version}}, nil)
+	if err != nil {
+		handler.errors.ServerErrorResponse(w, r, err)
+	}
+}
+
+// UpdateHandler changes the role of a member, the crew type is part of its identity. The version
+// in the body must match the current one, so changes made since the client read it are not lost.
+func (handler *CrewHandler) UpdateHandler(w http.ResponseWriter, r *http.Request) {
+	crew, ok := handler.member(w, r)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		Role    *string `json:"role"`
+		Version *int32  `json:"version"`
+	}
+
+	err := util.ReadJSON(w, r, &input)
+	if err != nil {
+		handler.errors.BadRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+
+	if v.Check(input.Version != nil, "version", "must be provided"); !v.Valid() {
+		handler.errors.FailedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	if *input.Version != crew.Version {
+		handler.errors.EditConflictResponse(w, r)
+		return
+	}
+
+	if input.Role != nil {
+		crew.Role = *input.Role
+	}
+
+	if model.ValidateCrew(v, crew); !v.Valid() {
+		handler.errors.FailedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = handler.models.Crew.Update(crew)
+	if err != nil {
+		switch {
+		case errors.Is(err, repository.ErrEditConflict):
+			handler.errors.EditConflictResponse(w, r)
+		default:
+			handler.errors.ServerErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = util.WriteJSON(w, http.StatusOK, util.Envelope{"crew_member": crewMember{crew, crew.Version}}, nil)
+	if err != nil {
+		handler.errors.ServerErrorResponse(w, r, err)
+	}
+}
+
+// DeleteHandler removes a member from the crew, at the version in the query string
+func (handler *CrewHandler) DeleteHandler(w http.ResponseWriter, r *http.Request) {
+	crew, ok := handler.member(w, r)
+	if !ok {
+		return
+	}
+
+	v := validator.New()
+
+	qs := r.URL.Query()
+
+	v.Check(qs.Has("version"), "version", "must be provided")
+	version := util.ReadInt(qs, "version", 0, v)
+
+	if !v.Valid() {
+		handler.errors.FailedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	if version != int(crew.Version) {
+		handler.errors.EditConflictResponse(w, r)
+		return
+	}
+
+	err := handler.models.Crew.Delete(crew)
+	if err != nil {
+		switch {
+		case errors.Is(err, repository.ErrEditConflict):
+			handler.errors.EditConflictResponse(w, r)
+		default:
+			handler.errors.ServerErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = util.WriteJSON(w, http.StatusOK, util.Envelope{"message": "crew member successfully deleted"}, nil)
+	if err != nil {
+		handler.errors.ServerErrorResponse(w, r, err)
+	}
+}
+
+func (handler *CrewHandler) ListHandler(w http.ResponseWriter, r *http.Request) {
+	movieID, ok := handler.movie(w, r)
+	if !ok {
+		return
+	}
+
+	crew, err := handler.models.Crew.GetForMovie(movieID)
+	if err != nil {
+		handler.errors.ServerErrorResponse(w, r, err)
+		return
+	}
+
+	members := make([]crewMember, len(crew))
+	for i, c := range crew {
+		members[i] = crewMember{c, c.Version}
+	}
+
+	err = util.WriteJSON(w, http.StatusOK, util.Envelope{"crew": members}, nil)
+	if err != nil {
+		handler.errors.ServerErrorResponse(w, r, err)
+	}
+}
+
+// movie reads the id of the movie the crew belongs to, the crew of a movie that does not exist
+// is not found rather than empty. It responds itself if it is not ok.
+func (handler *CrewHandler) movie(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	id, err := util.ReadIDParam(r)
+	if err != nil {
+		handler.errors.NotFoundResponse(w, r)
+		return 0, false
+	}
+
+	_, err = handler.models.Movies.Select(id, repository.Projection{Fields: []string{"id"}})
+	if err != nil {
+		switch {
+		case errors.Is(err, repository.ErrRecordNotFound):
+			handler.errors.NotFoundResponse(w, r)
+		default:
+			handler.errors.ServerErrorResponse(w, r, err)
+		}
+		return 0, false
+	}
+
+	return id, true
+}
+
+// member reads the crew member the route points to. It responds itself if it is not ok.
+func (handler *CrewHandler) member(w http.ResponseWriter, r *http.Request) (*model.Crew, bool) {
+	movieID, err := util.ReadIDParam(r)
+	if err != nil {
+		handler.errors.NotFoundResponse(w, r)
+		return nil, false
+	}
+
+	personID, err := util.ReadNamedIDParam(r, "person_id")
+	if err != nil {
+		handler.errors.NotFoundResponse(w, r)
+		return nil, false
+	}
+
+	crewType := util.ReadParam(r, "crew_type")
+
+	crew, err := handler.models.Crew.Get(movieID, personID, crewType)
+	if err != nil {
+		switch {
+		case errors.Is(err, repository.ErrRecordNotFound):
+			handler.errors.NotFoundResponse(w, r)
+		default:
+			handler.errors.ServerErrorResponse(w, r, err)
+		}
+		return nil, false
+	}
+
+	return crew, true
+}
@@ -0,0 +1,110 @@
+package v5_test
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"thesis.lefler.eu/internal/handler/handlertest"
+)
+
+type crewResponse struct {
+	CrewMember struct {
+		PersonID int64  `json:"person_id"`
+		CrewType string `json:"crew_type"`
+		Role     string `json:"role"`
+		Version  int32  `json:"version"`
+	} `json:"crew_member"`
+}
+
+func TestCrewCreateLocation(t *testing.T) {
+	h, models := handlertest.New()
+	id := createMovie(t, h, models)
+	actor := insertPerson(t, models, "Guy Pearce")
+
+	// the member is below the crew it was posted to, whatever prefix the strategy serves it at
+	target := fmt.Sprintf("/views/v5/movies/%d/crew", id)
+	body := fmt.Sprintf(`{"person_id": %d, "crew_type": "Actor", "role": "Leonard"}`, actor.ID)
+	w := handlertest.Serve(t, h.V5.Crew.CreateHandler, http.MethodPost, target, body, "id", fmt.Sprint(id))
+
+	var created crewResponse
+	handlertest.Decode(t, w, http.StatusCreated, &created)
+
+	want := fmt.Sprintf("%s/%d/Actor", target, actor.ID)
+	if got := w.Header().Get("Location"); got != want {
+		t.Errorf("got Location %q, want %q", got, want)
+	}
+	if created.CrewMember.Version != 1 {
+		t.Errorf("got version %d, want 1", created.CrewMember.Version)
+	}
+
+	// a person has a crew type once per movie
+	w = handlertest.Serve(t, h.V5.Crew.CreateHandler, http.MethodPost, target, body, "id", fmt.Sprint(id))
+	handlertest.Decode(t, w, http.StatusUnprocessableEntity, nil)
+}
+
+func TestCrewUpdateVersion(t *testing.T) {
+	h, models := handlertest.New()
+	id := createMovie(t, h, models)
+	director := crewOf(t, models, id)[0]
+
+	params := []string{"id", fmt.Sprint(id), "person_id", fmt.Sprint(director.PersonID), "crew_type", "Director"}
+	target := fmt.Sprintf("/v5/movies/%d/crew/%d/Director", id, director.PersonID)
+
+	tests := []struct {
+		name   string
+		body   string
+		status int
+	}{
+		{"missing version", `{"role": "writer"}`, http.StatusUnprocessableEntity},
+		{"stale version", `{"role": "writer", "version": 2}`, http.StatusConflict},
+		{"current version", `{"role": "writer", "version": 1}`, http.StatusOK},
+		{"version used up", `{"role": "producer", "version": 1}`, http.StatusConflict},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := handlertest.Serve(t, h.V5.Crew.UpdateHandler, http.MethodPatch, target, tt.body, params...)
+			handlertest.Decode(t, w, tt.status, nil)
+		})
+	}
+
+	var got crewResponse
+	handlertest.Decode(t, handlertest.Serve(t, h.V5.Crew.GetHandler, http.MethodGet, target, "", params...), http.StatusOK, &got)
+
+	if got.CrewMember.Role != "writer" || got.CrewMember.Version != 2 {
+		t.Errorf("got %+v, want the role of the one update at version 2", got.CrewMember)
+	}
+}
+
+func TestCrewDeleteVersion(t *testing.T) {
+	h, models := handlertest.New()
+	id := createMovie(t, h, models)
+	director := crewOf(t, models, id)[0]
+
+	params := []string{"id", fmt.Sprint(id), "person_id", fmt.Sprint(director.PersonID), "crew_type", "Director"}
+	target := fmt.Sprintf("/v5/movies/%d/crew/%d/Director", id, director.PersonID)
+
+	tests := []struct {
+		name   string
+		query  string
+		status int
+	}{
+		{"missing version", "", http.StatusUnprocessableEntity},
+		{"invalid version", "?version=first", http.StatusUnprocessableEntity},
+		{"stale version", "?version=2", http.StatusConflict},
+		{"current version", "?version=1", http.StatusOK},
+		{"deleted", "?version=1", http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := handlertest.Serve(t, h.V5.Crew.DeleteHandler, http.MethodDelete, target+tt.query, "", params...)
+			handlertest.Decode(t, w, tt.status, nil)
+		})
+	}
+
+	if crew := crewOf(t, models, id); len(crew) != 0 {
+		t.Errorf("got crew %+v, want it empty", crew)
+	}
+}
@@ -1,16 +1,17 @@
 package v5
 
 import (
-	data "thesis.lefler.eu/internal/data/views/v5"
 	e "thesis.lefler.eu/internal/error"
+	"thesis.lefler.eu/internal/repository"
 )
 
 type Handlers struct {
 	Movies MovieHandler
 	People PersonHandler
+	Crew   CrewHandler
 }
 
-func NewHandlers(errors *e.Errors, models *data.Models) Handlers {
+func NewHandlers(errors *e.Errors, models *repository.RepositoriesV5) Handlers {
 	return Handlers{
 		Movies: MovieHandler{
 			errors: errors,
@@ -20,5 +21,9 @@ func NewHandlers(errors *e.Errors, models *data.Models) Handlers {
 			errors: errors,
 			models: models,
 		},
+		Crew: CrewHandler{
+			errors: errors,
+			models: models,
+		},
 	}
 }
@@ -0,0 +1,477 @@
+package v5
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	e "thesis.lefler.eu/internal/error"
+	model "thesis.lefler.eu/internal/model/v5"
+	"thesis.lefler.eu/internal/repository"
+	util "thesis.lefler.eu/internal/util"
+	"thesis.lefler.eu/internal/validator"
+)
+
+type MovieHandler struct {
+	errors *e.Errors
+	models *repository.RepositoriesV5
+}
+
+func (handler *MovieHandler) CreateHandler(w http.ResponseWriter, r *http.Request) {
+	type crew struct {
+		PersonID int64  `json:"person_id"`
+		CrewType string `json:"crew_type"`
+		Role     string `json:"role,omitempty"`
+	}
+	var input struct {
+		Title    string   `json:"title"`
+		Year     int32    `json:"year"`
+		Genres   []string `json:"genres"`
+		Runtime  int32    `json:"runtime"`
+		Language string   `json:"language"`
+		Crew     []crew   `json:"crew"`
+	}
+
+	err := util.ReadJSON(w, r, &input)
+	if err != nil {
+		handler.errors.BadRequestResponse(w, r, err)
+		return
+	}
+
+	movie := &model.Movie{
+		Title:    input.Title,
+		Year:     input.Year,
+		Genres:   input.Genres,
+		Runtime:  &input.Runtime,
+		Language: &input.Language,
+	}
+
+	v := validator.New()
+
+	model.ValidateMovie(v, movie)
+
+	for _, a := range input.Crew {
+		person, err := handler.models.People.Get(a.PersonID)
+		if err != nil {
+			switch {
+			case errors.Is(err, repository.ErrRecordNotFound):
+				handler.errors.NotFoundResponse(w, r)
+			default:
+				handler.errors.ServerErrorResponse(w, r, err)
+			}
+			return
+		}
+
+		crewMember := &model.Crew{
+			PersonID:   a.PersonID,
+			PersonName: person.Name,
+			CrewType:   a.CrewType,
+			Role:       a.Role,
+		}
+		model.ValidateCrew(v, crewMember)
+		movie.Crew = append(movie.Crew, crewMember)
+	}
+
+	if !v.Valid() {
+		handler.errors.FailedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = handler.models.Movies.Insert(movie)
+	if err != nil {
+		handler.errors.ServerErrorResponse(w, r, err)
+		return
+	}
+
+	for _, crewMember := range movie.Crew {
+		crewMember.MovieID = movie.ID
+
+		err = handler.models.Crew.Insert(crewMember)
+		if err != nil {
+			handler.errors.ServerErrorResponse(w, r, err)
+			return
+		}
+	}
+
+	headers := make(http.Header)
+	headers.Set("Location", fmt.Sprintf("/v5/movie/%d", movie.ID))
+
+	err = util.WriteJSON(w, http.StatusCreated, util.Envelope{"movie": movie}, headers)
+	if err != nil {
+		handler.errors.ServerErrorResponse(w, r, err)
+	}
+}
+
+func (handler *MovieHandler) GetHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := util.ReadIDParam(r)
+	if err != nil {
+		handler.errors.NotFoundResponse(w, r)
+		return
+	}
+
+	v := validator.New()
+
+	// a movie comes with its crew unless asked for other fields or relations
+	projection := readProjection(r.URL.Query(), []string{"crew", "people"}, v)
+
+	if !v.Valid() {
+		handler.errors.FailedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	movie, err := handler.models.Movies.Select(id, projection)
+	if err != nil {
+		switch {
+		case errors.Is(err, repository.ErrRecordNotFound):
+			handler.errors.NotFoundResponse(w, r)
+		default:
+			handler.errors.ServerErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = handler.embed([]*model.Movie{movie}, projection)
+	if err != nil {
+		handler.errors.ServerErrorResponse(w, r, err)
+		return
+	}
+
+	projected, err := project(movie, projection)
+	if err != nil {
+		handler.errors.ServerErrorResponse(w, r, err)
+		return
+	}
+
+	err = util.WriteJSON(w, http.StatusOK, util.Envelope{"movie": projected}, nil)
+	if err != nil {
+		handler.errors.ServerErrorResponse(w, r, err)
+	}
+}
+
+func (handler *MovieHandler) UpdateHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := util.ReadIDParam(r)
+	if err != nil {
+		handler.errors.NotFoundResponse(w, r)
+		return
+	}
+
+	movie, err := handler.models.Movies.Get(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, repository.ErrRecordNotFound):
+			handler.errors.NotFoundResponse(w, r)
+		default:
+			handler.errors.ServerErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	type crew struct {
+		PersonID int64  `json:"person_id"`
+		CrewType string `json:"crew_type"`
+		Role     string `json:"role,omitempty"`
+	}
+	var input struct {
+		Title    *string   `json:"title"`
+		Year     *int32    `json:"year"`
+		Genres   *[]string `json:"genres"`
+		Runtime  *int32    `json:"runtime"`
+		Language *string   `json:"language"`
+		Crew     []crew    `json:"crew"`
+	}
+
+	err = util.ReadJSON(w, r, &input)
+	if err != nil {
+		handler.errors.BadRequestResponse(w, r, err)
+		return
+	}
+
+	if input.Title != nil {
+		movie.Title = *input.Title
+	}
+	if input.Year != nil {
+		movie.Year = *input.Year
+	}
+	if input.Genres != nil {
+		movie.Genres = *input.Genres
+	}
+	if input.Runtime != nil {
+		movie.Runtime = input.Runtime
+	}
+	if input.Language != nil {
+		movie.Language = input.Language
+	}
+
+	v := validator.New()
+
+	model.ValidateMovie(v, movie)
+
+	// the crew is read and validated as a whole before anything is written, the movie repository
+	// needs it for the director some strategies still store with the movie
+	var members []*model.Crew
+	if input.Crew != nil {
+		members = []*model.Crew{}
+
+		for _, a := range input.Crew {
+			if a.PersonID < 1 {
+				handler.errors.BadRequestResponse(w, r, errors.New("invalid person_id"))
+				return
+			}
+
+			person, err := handler.models.People.Get(a.PersonID)
+			if err != nil {
+				switch {
+				case errors.Is(err, repository.ErrRecordNotFound):
+					handler.errors.NotFoundResponse(w, r)
+				default:
+					handler.errors.ServerErrorResponse(w, r, err)
+				}
+				return
+			}
+
+			crewMember := &model.Crew{
+				MovieID:    movie.ID,
+				PersonID:   a.PersonID,
+				PersonName: person.Name,
+				CrewType:   a.CrewType,
+				Role:       a.Role,
+			}
+			model.ValidateCrew(v, crewMember)
+			members = append(members, crewMember)
+		}
+
+		movie.Crew = members
+	}
+
+	if !v.Valid() {
+		handler.errors.FailedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = handler.models.Movies.Update(movie)
+	if err != nil {
+		switch {
+		case errors.Is(err, repository.ErrEditConflict):
+			handler.errors.EditConflictResponse(w, r)
+		default:
+			handler.errors.ServerErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	// the crew is only replaced once the version check of the movie passed
+	if members != nil {
+		err = handler.models.Crew.DeleteForMovie(movie.ID)
+		if err != nil && !errors.Is(err, repository.ErrRecordNotFound) {
+			handler.errors.ServerErrorResponse(w, r, err)
+			return
+		}
+
+		for _, crewMember := range members {
+			err = handler.models.Crew.Insert(crewMember)
+			if err != nil {
+				handler.errors.ServerErrorResponse(w, r, err)
+				return
+			}
+		}
+	}
+
+	err = util.WriteJSON(w, http.StatusOK, util.Envelope{"movie": movie}, nil)
+	if err != nil {
+		handler.errors.ServerErrorResponse(w, r, err)
+	}
+}
+
+func (handler *MovieHandler) DeleteHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := util.ReadIDParam(r)
+	if err != nil {
+		handler.errors.NotFoundResponse(w, r)
+		return
+	}
+
+	err = handler.models.Movies.Delete(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, repository.ErrRecordNotFound):
+			handler.errors.NotFoundResponse(w, r)
+		default:
+			handler.errors.ServerErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = util.WriteJSON(w, http.StatusOK, util.Envelope{"message": "movie successfully deleted"}, nil)
+	if err != nil {
+		handler.errors.ServerErrorResponse(w, r, err)
+	}
+}
+
+func (handler *MovieHandler) ListHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		repository.MovieFilter
+		repository.Filters
+	}
+
+	v := validator.New()
+
+	qs := r.URL.Query()
+
+	input.Title = util.ReadString(qs, "title", "")
+	input.Query = util.ReadString(qs, "q", "")
+	input.Genres = util.ReadCSV(qs, "genres", []string{})
+	input.YearFrom = int32(util.ReadInt(qs, "year_from", 0, v))
+	input.YearTo = int32(util.ReadInt(qs, "year_to", 0, v))
+
+	input.Page = util.ReadInt(qs, "page", 1, v)
+	input.PageSize = util.ReadInt(qs, "page_size", 20, v)
+	input.After = util.ReadCursor(qs, "after", v)
+	input.Before = util.ReadCursor(qs, "before", v)
+
+	// a limit or a cursor pages the list by cursor instead of page number
+	input.Cursor = qs.Has("limit") || input.After != nil || input.Before != nil
+	if input.Cursor {
+		input.PageSize = util.ReadInt(qs, "limit", 20, v)
+	}
+
+	// searched lists are sorted by relevance unless sorted otherwise
+	sort := "id"
+	if input.Query != "" && !input.Cursor {
+		sort = "relevance"
+	}
+
+	input.Sort = util.ReadString(qs, "sort", sort)
+	input.SortSafelist = []string{"id", "title", "year", "runtime", "-id", "-title", "-year", "-runtime", "relevance"}
+
+	projection := readProjection(qs, nil, v)
+
+	repository.ValidateMovieFilter(v, input.MovieFilter)
+	repository.ValidateSearch(v, input.Query, input.Filters)
+
+	if repository.ValidateFilters(v, input.Filters); !v.Valid() {
+		handler.errors.FailedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	movies, metadata, err := handler.models.Movies.GetAll(input.MovieFilter, input.Filters, projection)
+	if err != nil {
+		handler.errors.ServerErrorResponse(w, r, err)
+		return
+	}
+
+	err = handler.embed(movies, projection)
+	if err != nil {
+		handler.errors.ServerErrorResponse(w, r, err)
+		return
+	}
+
+	projected := make([]any, len(movies))
+	for i, movie := range movies {
+		projected[i], err = project(movie, projection)
+		if err != nil {
+			handler.errors.ServerErrorResponse(w, r, err)
+			return
+		}
+	}
+
+	metadata.Link(r.URL)
+
+	err = util.WriteJSON(w, http.StatusOK, util.Envelope{"movies": projected, "metadata": metadata}, nil)
+	if err != nil {
+		handler.errors.ServerErrorResponse(w, r, err)
+	}
+}
+
+// movieFields are the fields of a movie clients can select
+var movieFields = []string{"id", "title", "year", "genres", "runtime", "language", "version"}
+
+// readProjection reads the fields of a movie to return and the relations to embed, crew for
+// its crew and people for the names of their people, which embeds the crew as well. The
+// relations default to include if neither parameter is given.
+func readProjection(qs url.Values, include []string, v *validator.Validator) repository.Projection {
+	if qs.Has("fields") || qs.Has("include") {
+		include = nil
+	}
+
+	projection := repository.Projection{
+		Fields:  util.ReadCSV(qs, "fields", nil),
+		Include: util.ReadCSV(qs, "include", include),
+	}
+
+	for _, field := range projection.Fields {
+		v.Check(validator.PermittedValue(field, movieFields...), "fields", "must only contain "+strings.Join(movieFields, ", "))
+	}
+	for _, relation := range projection.Include {
+		v.Check(validator.PermittedValue(relation, "crew", "people"), "include", "must only contain crew, people")
+	}
+
+	if projection.Includes("people") && !projection.Includes("crew") {
+		projection.Include = append(projection.Include, "crew")
+	}
+
+	return projection
+}
+
+// embed reads the crew of the movies if the projection includes it, in a single query
+func (handler *MovieHandler) embed(movies []*model.Movie, projection repository.Projection) error {
+	if !projection.Includes("crew") || len(movies) == 0 {
+		return nil
+	}
+
+	byID := make(map[int64]*model.Movie, len(movies))
+	ids := make([]int64, len(movies))
+	for i, movie := range movies {
+		byID[movie.ID] = movie
+		ids[i] = movie.ID
+	}
+
+	crew, err := handler.models.Crew.GetForMovies(ids, projection.Includes("people"))
+	if err != nil {
+		return err
+	}
+
+	for _, member := range crew {
+		movie := byID[member.MovieID]
+		movie.Crew = append(movie.Crew, member)
+	}
+
+	return nil
+}
+
+// crewWithoutPeople is a member of the crew embedded without people, which has no name to show
+type crewWithoutPeople struct {
+	PersonID int64  `json:"person_id"`
+	CrewType string `json:"crew_type"`
+	Role     string `json:"role,omitempty"`
+}
+
+// movieWithoutPeople is a movie with its crew embedded without people
+type movieWithoutPeople struct {
+	*model.Movie
+	Crew []crewWithoutPeople `json:"crew,omitempty"`
+}
+
+// project returns the fields of movie selected by the projection, with the relations it embeds
+func project(movie *model.Movie, projection repository.Projection) (any, error) {
+	var record any = movie
+
+	if projection.Includes("crew") && !projection.Includes("people") {
+		withoutPeople := movieWithoutPeople{Movie: movie}
+		for _, member := range movie.Crew {
+			withoutPeople.Crew = append(withoutPeople.Crew, crewWithoutPeople{member.PersonID, member.CrewType, member.Role})
+		}
+		record = withoutPeople
+	}
+
+	if len(projection.Fields) == 0 {
+		return record, nil
+	}
+
+	fields := append([]string{"id"}, projection.Fields...)
+	if projection.Includes("crew") {
+		fields = append(fields, "crew")
+	}
+
+	return util.Project(record, fields)
+}
@@ -0,0 +1,169 @@
+package v5_test
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"thesis.lefler.eu/internal/handler"
+	"thesis.lefler.eu/internal/handler/handlertest"
+	model "thesis.lefler.eu/internal/model/v5"
+	"thesis.lefler.eu/internal/repository"
+)
+
+// insertPerson seeds a person, as the crew only refers to existing people
+func insertPerson(t *testing.T, models *repository.Repositories, name string) *model.Person {
+	t.Helper()
+
+	person := &model.Person{Name: name}
+
+	err := models.V5.People.Insert(person)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return person
+}
+
+// createMovie creates a movie directed by a new person through the handler and returns its id
+func createMovie(t *testing.T, h handler.Versions, models *repository.Repositories) int64 {
+	t.Helper()
+
+	director := insertPerson(t, models, "Christopher Nolan")
+	body := fmt.Sprintf(`{"title": "Memento", "year": 2000, "genres": ["thriller"], "runtime": 113, "language": "English",
+		"crew": [{"person_id": %d, "crew_type": "Director"}]}`, director.ID)
+
+	var created struct {
+		Movie model.Movie `json:"movie"`
+	}
+	handlertest.Decode(t, handlertest.Serve(t, h.V5.Movies.CreateHandler, http.MethodPost, "/v5/movies", body), http.StatusCreated, &created)
+
+	return created.Movie.ID
+}
+
+// crewOf reads the crew of a movie from the repository
+func crewOf(t *testing.T, models *repository.Repositories, id int64) []*model.Crew {
+	t.Helper()
+
+	crew, err := models.V5.Crew.GetForMovie(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return crew
+}
+
+func TestMovieCreateAndGet(t *testing.T) {
+	h, models := handlertest.New()
+	id := createMovie(t, h, models)
+
+	var got struct {
+		Movie model.Movie `json:"movie"`
+	}
+	w := handlertest.Serve(t, h.V5.Movies.GetHandler, http.MethodGet, "/v5/movies/1", "", "id", fmt.Sprint(id))
+	handlertest.Decode(t, w, http.StatusOK, &got)
+
+	if got.Movie.Title != "Memento" || got.Movie.Version != 1 {
+		t.Errorf("got %+v, want Memento at version 1", got.Movie)
+	}
+	if len(got.Movie.Crew) != 1 || got.Movie.Crew[0].PersonName != "Christopher Nolan" || got.Movie.Crew[0].CrewType != "Director" {
+		t.Errorf("got crew %+v, want Christopher Nolan as the Director", got.Movie.Crew)
+	}
+}
+
+func TestMovieUpdateReplacesCrew(t *testing.T) {
+	h, models := handlertest.New()
+	id := createMovie(t, h, models)
+	actor := insertPerson(t, models, "Guy Pearce")
+
+	body := fmt.Sprintf(`{"crew": [{"person_id": %d, "crew_type": "Actor", "role": "Leonard"}]}`, actor.ID)
+	w := handlertest.Serve(t, h.V5.Movies.UpdateHandler, http.MethodPatch, "/v5/movies/1", body, "id", fmt.Sprint(id))
+	handlertest.Decode(t, w, http.StatusOK, nil)
+
+	crew := crewOf(t, models, id)
+	if len(crew) != 1 || crew[0].PersonID != actor.ID || crew[0].Role != "Leonard" {
+		t.Errorf("got crew %+v, want only Guy Pearce as Leonard", crew)
+	}
+}
+
+// conflictingMovies fails every update as if another client updated the movie first
+type conflictingMovies struct {
+	repository.MovieRepositoryV5
+}
+
+func (conflictingMovies) Update(movie *model.Movie) error {
+	return repository.ErrEditConflict
+}
+
+func TestMovieUpdateRejectsInvalidCrew(t *testing.T) {
+	h, models := handlertest.New()
+	id := createMovie(t, h, models)
+	actor := insertPerson(t, models, "Guy Pearce")
+
+	// an actor without a role fails validation, neither the movie nor its crew is written
+	body := fmt.Sprintf(`{"title": "Following", "crew": [{"person_id": %d, "crew_type": "Actor"}]}`, actor.ID)
+	w := handlertest.Serve(t, h.V5.Movies.UpdateHandler, http.MethodPatch, "/v5/movies/1", body, "id", fmt.Sprint(id))
+	handlertest.Decode(t, w, http.StatusUnprocessableEntity, nil)
+
+	movie, err := models.V5.Movies.Get(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if movie.Title != "Memento" || movie.Version != 1 {
+		t.Errorf("got %+v, want the movie unchanged", movie)
+	}
+	if crew := crewOf(t, models, id); len(crew) != 1 || crew[0].CrewType != "Director" {
+		t.Errorf("got crew %+v, want the crew unchanged", crew)
+	}
+}
+
+func TestMovieUpdateConflictKeepsCrew(t *testing.T) {
+	h, models := handlertest.New()
+	id := createMovie(t, h, models)
+	actor := insertPerson(t, models, "Guy Pearce")
+
+	models.V5.Movies = conflictingMovies{models.V5.Movies}
+
+	body := fmt.Sprintf(`{"crew": [{"person_id": %d, "crew_type": "Actor", "role": "Leonard"}]}`, actor.ID)
+	w := handlertest.Serve(t, h.V5.Movies.UpdateHandler, http.MethodPatch, "/v5/movies/1", body, "id", fmt.Sprint(id))
+	handlertest.Decode(t, w, http.StatusConflict, nil)
+
+	if crew := crewOf(t, models, id); len(crew) != 1 || crew[0].CrewType != "Director" {
+		t.Errorf("got crew %+v, want the crew unchanged after the conflict", crew)
+	}
+}
+
+func TestMovieGetCrewWithoutPeople(t *testing.T) {
+	h, models := handlertest.New()
+	id := createMovie(t, h, models)
+
+	tests := []struct {
+		name   string
+		query  string
+		person bool
+	}{
+		{"default", "", true},
+		{"crew with people", "?include=crew,people", true},
+		{"crew without people", "?include=crew", false},
+		{"fields and crew without people", "?fields=title&include=crew", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got struct {
+				Movie struct {
+					Crew []map[string]any `json:"crew"`
+				} `json:"movie"`
+			}
+			w := handlertest.Serve(t, h.V5.Movies.GetHandler, http.MethodGet, "/v5/movies/1"+tt.query, "", "id", fmt.Sprint(id))
+			handlertest.Decode(t, w, http.StatusOK, &got)
+
+			if len(got.Movie.Crew) != 1 {
+				t.Fatalf("got crew %+v, want the director", got.Movie.Crew)
+			}
+			if _, ok := got.Movie.Crew[0]["person_name"]; ok != tt.person {
+				t.Errorf("got crew member %+v, want person_name present %t", got.Movie.Crew[0], tt.person)
+			}
+		})
+	}
+}
@@ -6,15 +6,17 @@ import (
 	"net/http"
 
 	"cloud.google.com/go/civil"
-	data "thesis.lefler.eu/internal/data/views/v5"
+
 	e "thesis.lefler.eu/internal/error"
+	model "thesis.lefler.eu/internal/model/v5"
+	"thesis.lefler.eu/internal/repository"
 	"thesis.lefler.eu/internal/util"
 	"thesis.lefler.eu/internal/validator"
 )
 
 type PersonHandler struct {
 	errors *e.Errors
-	models *data.Models
+	models *repository.RepositoriesV5
 }
 
 func (handler *PersonHandler) CreateHandler(w http.ResponseWriter, r *http.Request) {
@@ -29,14 +31,14 @@ func (handler *PersonHandler) CreateHandler(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
-	person := &data.Person{
+	person := &model.Person{
 		Name:      input.Name,
 		Birthdate: &input.Birthdate,
 	}
 
 	v := validator.New()
 
-	if data.ValidatePerson(v, person); !v.Valid() {
+	if model.ValidatePerson(v, person); !v.Valid() {
 		handler.errors.FailedValidationResponse(w, r, v.Errors)
 		return
 	}
@@ -66,7 +68,7 @@ func (handler *PersonHandler) GetHandler(w http.ResponseWriter, r *http.Request)
 	person, err := handler.models.People.Get(id)
 	if err != nil {
 		switch {
-		case errors.Is(err, data.ErrRecordNotFound):
+		case errors.Is(err, repository.ErrRecordNotFound):
 			handler.errors.NotFoundResponse(w, r)
 		default:
 			handler.errors.ServerErrorResponse(w, r, err)
@@ -90,7 +92,7 @@ func (handler *PersonHandler) UpdateHandler(w http.ResponseWriter, r *http.Reque
 	person, err := handler.models.People.Get(id)
 	if err != nil {
 		switch {
-		case errors.Is(err, data.ErrRecordNotFound):
+		case errors.Is(err, repository.ErrRecordNotFound):
 			handler.errors.NotFoundResponse(w, r)
 		default:
 			handler.errors.ServerErrorResponse(w, r, err)
@@ -119,7 +121,7 @@ func (handler *PersonHandler) UpdateHandler(w http.ResponseWriter, r *http.Reque
 
 	v := validator.New()
 
-	if data.ValidatePerson(v, person); !v.Valid() {
+	if model.ValidatePerson(v, person); !v.Valid() {
 		handler.errors.FailedValidationResponse(w, r, v.Errors)
 		return
 	}
@@ -127,7 +129,7 @@ func (handler *PersonHandler) UpdateHandler(w http.ResponseWriter, r *http.Reque
 	err = handler.models.People.Update(person)
 	if err != nil {
 		switch {
-		case errors.Is(err, data.ErrEditConflict):
+		case errors.Is(err, repository.ErrEditConflict):
 			handler.errors.EditConflictResponse(w, r)
 		default:
 			handler.errors.ServerErrorResponse(w, r, err)
@@ -151,7 +153,7 @@ func (handler *PersonHandler) DeleteHandler(w http.ResponseWriter, r *http.Reque
 	err = handler.models.People.Delete(id)
 	if err != nil {
 		switch {
-		case errors.Is(err, data.ErrRecordNotFound):
+		case errors.Is(err, repository.ErrRecordNotFound):
 			handler.errors.NotFoundResponse(w, r)
 		default:
 			handler.errors.ServerErrorResponse(w, r, err)
@@ -166,13 +168,54 @@ func (handler *PersonHandler) DeleteHandler(w http.ResponseWriter, r *http.Reque
 }
 
 func (handler *PersonHandler) ListHandler(w http.ResponseWriter, r *http.Request) {
-	people, err := handler.models.People.GetAll()
+	var input struct {
+		repository.PersonFilter
+		repository.Filters
+	}
+
+	v := validator.New()
+
+	qs := r.URL.Query()
+
+	input.Name = util.ReadString(qs, "name", "")
+	input.Query = util.ReadString(qs, "q", "")
+
+	input.Page = util.ReadInt(qs, "page", 1, v)
+	input.PageSize = util.ReadInt(qs, "page_size", 20, v)
+	input.After = util.ReadCursor(qs, "after", v)
+	input.Before = util.ReadCursor(qs, "before", v)
+
+	// a limit or a cursor pages the list by cursor instead of page number
+	input.Cursor = qs.Has("limit") || input.After != nil || input.Before != nil
+	if input.Cursor {
+		input.PageSize = util.ReadInt(qs, "limit", 20, v)
+	}
+
+	// searched lists are sorted by relevance unless sorted otherwise
+	sort := "id"
+	if input.Query != "" && !input.Cursor {
+		sort = "relevance"
+	}
+
+	input.Sort = util.ReadString(qs, "sort", sort)
+	input.SortSafelist = []string{"id", "name", "birthdate", "-id", "-name", "-birthdate", "relevance"}
+
+	repository.ValidateSearch(v, input.Query, input.Filters)
+
+	if repository.ValidateFilters(v, input.Filters); !v.Valid() {
+		handler.errors.FailedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	people, metadata, err := handler.models.People.GetAll(input.PersonFilter, input.Filters)
 	if err != nil {
 		handler.errors.ServerErrorResponse(w, r, err)
 		return
 	}
 
-	err = util.WriteJSON(w, http.StatusOK, util.Envelope{"people": people}, nil)
+	metadata.Link(r.URL)
+
+	err = util.WriteJSON(w, http.StatusOK, util.Envelope{"people": people, "metadata": metadata}, nil)
 	if err != nil {
 		handler.errors.ServerErrorResponse(w, r, err)
 	}
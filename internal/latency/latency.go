@@ -0,0 +1,52 @@
+package latency
+
+import (
+	"sort"
+	"time"
+)
+
+// Summary of a set of request latencies, in milliseconds
+type Summary struct {
+	Mean float64 `json:"mean_ms"`
+	P50  float64 `json:"p50_ms"`
+	P90  float64 `json:"p90_ms"`
+	P95  float64 `json:"p95_ms"`
+	P99  float64 `json:"p99_ms"`
+	Max  float64 `json:"max_ms"`
+}
+
+func Summarize(durations []time.Duration) Summary {
+	if len(durations) == 0 {
+		return Summary{}
+	}
+
+	sorted := append([]time.Duration(nil), durations...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	var total time.Duration
+	for _, d := range sorted {
+		total += d
+	}
+
+	return Summary{
+		Mean: Milliseconds(total / time.Duration(len(sorted))),
+		P50:  Milliseconds(Percentile(sorted, 50)),
+		P90:  Milliseconds(Percentile(sorted, 90)),
+		P95:  Milliseconds(Percentile(sorted, 95)),
+		P99:  Milliseconds(Percentile(sorted, 99)),
+		Max:  Milliseconds(sorted[len(sorted)-1]),
+	}
+}
+
+// Percentile of sorted durations, by the nearest rank
+func Percentile(sorted []time.Duration, p int) time.Duration {
+	rank := (p*len(sorted) + 99) / 100
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+func Milliseconds(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
@@ -0,0 +1,388 @@
+package migration
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+)
+
+// CostTable stores the cost of every measured migration, next to the tracking table
+const CostTable = "migration_costs"
+
+// DefaultSampleInterval between two samples of pg_locks, locks held for a shorter time may be missed
+const DefaultSampleInterval = 10 * time.Millisecond
+
+// Cost of applying or rolling back a single migration
+type Cost struct {
+	Version      int64       `json:"version"`
+	Name         string      `json:"name"`
+	Direction    string      `json:"direction"`
+	StartedAt    time.Time   `json:"started_at"`
+	Duration     float64     `json:"duration_ms"`
+	RowsAffected int64       `json:"rows_affected"`
+	Samples      int         `json:"samples"`           // of pg_locks taken while the migration ran
+	Exclusive    float64     `json:"exclusive_lock_ms"` // an EXCLUSIVE or ACCESS EXCLUSIVE lock was held on a table
+	MaxBlocked   int         `json:"max_blocked"`       // most sessions waiting on the migration at once
+	SizeBefore   int64       `json:"size_before"`       // of all tables in bytes, including indexes and TOAST
+	SizeAfter    int64       `json:"size_after"`
+	Locks        []Lock      `json:"locks"`
+	Tables       []TableSize `json:"tables"`
+	Error        string      `json:"error,omitempty"`
+}
+
+// Lock taken by a migration on a relation, Held is estimated from the number of samples it was seen in
+type Lock struct {
+	Relation string  `json:"relation"`
+	Mode     string  `json:"mode"`
+	Samples  int     `json:"samples"`
+	Held     float64 `json:"held_ms"`
+}
+
+// TableSize of a table in bytes before and after a migration, 0 if it did not exist
+type TableSize struct {
+	Table  string `json:"table"`
+	Before int64  `json:"before"`
+	After  int64  `json:"after"`
+}
+
+// measure applies a migration while sampling the locks it holds on a second connection,
+// and records its cost in CostTable, also if the migration fails
+func (m *Migrator) measure(ctx context.Context, conn *sql.Conn, migration *Migration, statements []string, up bool) error {
+	interval := m.SampleInterval
+	if interval <= 0 {
+		interval = DefaultSampleInterval
+	}
+
+	var pid int
+	err := conn.QueryRowContext(ctx, `SELECT pg_backend_pid()`).Scan(&pid)
+	if err != nil {
+		return err
+	}
+
+	before, err := tableSizes(ctx, m.DB)
+	if err != nil {
+		return err
+	}
+
+	s := &sampler{db: m.DB, pid: pid, interval: interval, locks: make(map[lockKey]int)}
+
+	sampleCtx, stop := context.WithCancel(ctx)
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		s.run(sampleCtx)
+	}()
+
+	start := time.Now()
+	rows, applyErr := apply(ctx, conn, migration, statements, up)
+	duration := time.Since(start)
+
+	stop()
+	wg.Wait()
+
+	after, err := tableSizes(ctx, m.DB)
+	if err != nil {
+		return errors.Join(applyErr, err)
+	}
+
+	cost := Cost{
+		Version:      migration.Version,
+		Name:         migration.Name,
+		Direction:    direction(up),
+		StartedAt:    start.UTC(),
+		Duration:     milliseconds(duration),
+		RowsAffected: rows,
+		Samples:      s.samples,
+		Exclusive:    milliseconds(time.Duration(s.exclusive) * interval),
+		MaxBlocked:   s.maxBlocked,
+		Locks:        s.result(),
+		Tables:       diffSizes(before, after),
+	}
+	for _, table := range cost.Tables {
+		cost.SizeBefore += table.Before
+		cost.SizeAfter += table.After
+	}
+	if applyErr != nil {
+		cost.Error = applyErr.Error()
+	}
+
+	m.Costs = append(m.Costs, cost)
+
+	err = recordCost(ctx, conn, cost)
+	if err != nil {
+		return errors.Join(applyErr, fmt.Errorf("recording cost of %s: %w", migration.Name, err))
+	}
+
+	return applyErr
+}
+
+// LoadCosts reads all recorded costs in the order they were measured, none if
+// the database has never been migrated with measuring enabled
+func LoadCosts(ctx context.Context, db *sql.DB) ([]Cost, error) {
+	var exists bool
+
+	err := db.QueryRowContext(ctx, `SELECT to_regclass($1) IS NOT NULL`, CostTable).Scan(&exists)
+	if err != nil {
+		return nil, err
+	}
+
+	if !exists {
+		return nil, nil
+	}
+
+	query := fmt.Sprintf(`
+		SELECT version_id, name, direction, started_at, duration_ms, rows_affected, samples,
+			exclusive_lock_ms, max_blocked, size_before, size_after, locks, tables, coalesce(error, '')
+		FROM %s
+		ORDER BY id`, CostTable)
+
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var costs []Cost
+
+	for rows.Next() {
+		var (
+			cost          Cost
+			locks, tables []byte
+		)
+
+		err := rows.Scan(&cost.Version, &cost.Name, &cost.Direction, &cost.StartedAt, &cost.Duration, &cost.RowsAffected, &cost.Samples,
+			&cost.Exclusive, &cost.MaxBlocked, &cost.SizeBefore, &cost.SizeAfter, &locks, &tables, &cost.Error)
+		if err != nil {
+			return nil, err
+		}
+
+		err = json.Unmarshal(locks, &cost.Locks)
+		if err != nil {
+			return nil, err
+		}
+
+		err = json.Unmarshal(tables, &cost.Tables)
+		if err != nil {
+			return nil, err
+		}
+
+		costs = append(costs, cost)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return costs, nil
+}
+
+// recordCost stores cost in CostTable, which migration 00001_migration_costs.sql of every
+// strategy creates. The rollback of that migration drops it and is not recorded.
+func recordCost(ctx context.Context, conn *sql.Conn, cost Cost) error {
+	var exists bool
+
+	err := conn.QueryRowContext(ctx, `SELECT to_regclass($1) IS NOT NULL`, CostTable).Scan(&exists)
+	if err != nil {
+		return err
+	}
+
+	if !exists {
+		return nil
+	}
+
+	locks, err := json.Marshal(cost.Locks)
+	if err != nil {
+		return err
+	}
+
+	tables, err := json.Marshal(cost.Tables)
+	if err != nil {
+		return err
+	}
+
+	query := fmt.Sprintf(`
+		INSERT INTO %s (version_id, name, direction, started_at, duration_ms, rows_affected, samples,
+			exclusive_lock_ms, max_blocked, size_before, size_after, locks, tables, error)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, nullif($14, ''))`, CostTable)
+
+	// the json as text, lib/pq would send []byte as bytea
+	_, err = conn.ExecContext(ctx, query, cost.Version, cost.Name, cost.Direction, cost.StartedAt, cost.Duration, cost.RowsAffected, cost.Samples,
+		cost.Exclusive, cost.MaxBlocked, cost.SizeBefore, cost.SizeAfter, string(locks), string(tables), cost.Error)
+	return err
+}
+
+type lockKey struct {
+	relation string
+	mode     string
+}
+
+// sampler polls pg_locks for the relation locks held by the migrating backend
+// and pg_stat_activity for the sessions waiting on it
+type sampler struct {
+	db       *sql.DB
+	pid      int
+	interval time.Duration
+
+	samples    int
+	locks      map[lockKey]int // samples a lock was seen in
+	exclusive  int             // samples an exclusive lock was seen in
+	maxBlocked int
+}
+
+func (s *sampler) run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		// a failed sample is skipped, measuring must never fail the migration itself
+		s.sample(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// sample records the locks held at this moment. Relations created by a migration running
+// in a transaction are not visible to the sampler until it commits, which is fine as no
+// other session can access them either.
+func (s *sampler) sample(ctx context.Context) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT c.oid::regclass::text, l.mode
+		FROM pg_locks l
+		JOIN pg_class c ON c.oid = l.relation
+		JOIN pg_namespace n ON n.oid = c.relnamespace
+		WHERE l.pid = $1 AND l.locktype = 'relation' AND l.granted
+			AND n.nspname NOT IN ('pg_catalog', 'information_schema') AND n.nspname NOT LIKE 'pg_toast%'`, s.pid)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+
+	var held []lockKey
+	for rows.Next() {
+		var key lockKey
+		if rows.Scan(&key.relation, &key.mode) != nil {
+			return
+		}
+		held = append(held, key)
+	}
+	if rows.Err() != nil {
+		return
+	}
+
+	var blocked int
+	err = s.db.QueryRowContext(ctx, `SELECT count(*) FROM pg_stat_activity WHERE $1 = ANY(pg_blocking_pids(pid))`, s.pid).Scan(&blocked)
+	if err != nil {
+		return
+	}
+
+	s.samples++
+	s.maxBlocked = max(s.maxBlocked, blocked)
+
+	exclusive := false
+	for _, key := range held {
+		if key.relation == TableName || key.relation == CostTable {
+			continue
+		}
+
+		s.locks[key]++
+		if key.mode == "AccessExclusiveLock" || key.mode == "ExclusiveLock" {
+			exclusive = true
+		}
+	}
+	if exclusive {
+		s.exclusive++
+	}
+}
+
+func (s *sampler) result() []Lock {
+	locks := []Lock{}
+	for key, samples := range s.locks {
+		locks = append(locks, Lock{
+			Relation: key.relation,
+			Mode:     key.mode,
+			Samples:  samples,
+			Held:     milliseconds(time.Duration(samples) * s.interval),
+		})
+	}
+
+	slices.SortFunc(locks, func(a, b Lock) int {
+		if a.Relation != b.Relation {
+			return cmp.Compare(a.Relation, b.Relation)
+		}
+		return cmp.Compare(a.Mode, b.Mode)
+	})
+
+	return locks
+}
+
+// tableSizes returns the total size of every user table, materialized view and partitioned table
+func tableSizes(ctx context.Context, db *sql.DB) (map[string]int64, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT c.oid::regclass::text, pg_total_relation_size(c.oid)
+		FROM pg_class c
+		JOIN pg_namespace n ON n.oid = c.relnamespace
+		WHERE c.relkind IN ('r', 'm', 'p')
+			AND n.nspname NOT IN ('pg_catalog', 'information_schema') AND n.nspname NOT LIKE 'pg_toast%'`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sizes := make(map[string]int64)
+
+	for rows.Next() {
+		var (
+			table string
+			size  int64
+		)
+
+		err := rows.Scan(&table, &size)
+		if err != nil {
+			return nil, err
+		}
+
+		if table == TableName || table == CostTable {
+			continue
+		}
+
+		sizes[table] = size
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return sizes, nil
+}
+
+func diffSizes(before map[string]int64, after map[string]int64) []TableSize {
+	tables := []TableSize{}
+
+	for table, size := range before {
+		tables = append(tables, TableSize{Table: table, Before: size, After: after[table]})
+	}
+	for table, size := range after {
+		if _, ok := before[table]; !ok {
+			tables = append(tables, TableSize{Table: table, After: size})
+		}
+	}
+
+	slices.SortFunc(tables, func(a, b TableSize) int { return cmp.Compare(a.Table, b.Table) })
+
+	return tables
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
@@ -0,0 +1,217 @@
+package migration
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrNoMigrations     = errors.New("no migrations found")
+	ErrNoCurrentVersion = errors.New("no current version found")
+	ErrUnknownVersion   = errors.New("unknown migration version")
+)
+
+// Migration is a single goose annotated SQL file, split into its up and down statements
+type Migration struct {
+	Version int64    // Numeric prefix of the file name, e.g. 31 for 00031_v3_migration.sql
+	Name    string   // File name without directory
+	Up      []string // Statements of the -- +goose Up section
+	Down    []string // Statements of the -- +goose Down section
+	UseTx   bool     // False if the file is annotated with -- +goose NO TRANSACTION
+}
+
+// Load reads and parses all .sql files in dir, ordered by version
+func Load(fsys fs.FS, dir string) ([]*Migration, error) {
+	files, err := fs.Glob(fsys, path.Join(dir, "*.sql"))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(files) == 0 {
+		return nil, ErrNoMigrations
+	}
+
+	migrations := make([]*Migration, 0, len(files))
+	versions := make(map[int64]string, len(files))
+
+	for _, file := range files {
+		f, err := fsys.Open(file)
+		if err != nil {
+			return nil, err
+		}
+
+		migration, err := Parse(path.Base(file), f)
+		f.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		if other, exists := versions[migration.Version]; exists {
+			return nil, fmt.Errorf("duplicate version %d in %s and %s", migration.Version, other, migration.Name)
+		}
+		versions[migration.Version] = migration.Name
+
+		migrations = append(migrations, migration)
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	return migrations, nil
+}
+
+// Parse splits a goose annotated SQL file into statements. Statements are terminated
+// by a semicolon at the end of a line, unless they are wrapped in
+// -- +goose StatementBegin and -- +goose StatementEnd, in which case the whole block
+// is sent to the database as a single statement.
+func Parse(name string, r io.Reader) (*Migration, error) {
+	version, err := parseVersion(name)
+	if err != nil {
+		return nil, err
+	}
+
+	migration := &Migration{
+		Version: version,
+		Name:    name,
+		UseTx:   true,
+	}
+
+	const (
+		none = iota
+		up
+		down
+	)
+
+	var (
+		section    = none
+		inBlock    = false
+		buf        strings.Builder
+		lineNumber = 0
+	)
+
+	flush := func() {
+		statement := strings.TrimSpace(buf.String())
+		buf.Reset()
+
+		if statement == "" {
+			return
+		}
+
+		switch section {
+		case up:
+			migration.Up = append(migration.Up, statement)
+		case down:
+			migration.Down = append(migration.Down, statement)
+		}
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+
+	for scanner.Scan() {
+		line := scanner.Text()
+		lineNumber++
+
+		if annotation, ok := strings.CutPrefix(strings.TrimSpace(line), "-- +goose"); ok {
+			switch strings.ToLower(strings.TrimSpace(annotation)) {
+			case "up":
+				flush()
+				section = up
+			case "down":
+				flush()
+				section = down
+			case "statementbegin":
+				if inBlock {
+					return nil, fmt.Errorf("%s:%d: nested StatementBegin", name, lineNumber)
+				}
+				flush()
+				inBlock = true
+			case "statementend":
+				if !inBlock {
+					return nil, fmt.Errorf("%s:%d: StatementEnd without StatementBegin", name, lineNumber)
+				}
+				flush()
+				inBlock = false
+			case "no transaction":
+				migration.UseTx = false
+			default:
+				return nil, fmt.Errorf("%s:%d: unknown annotation %q", name, lineNumber, strings.TrimSpace(annotation))
+			}
+			continue
+		}
+
+		if section == none {
+			if strings.TrimSpace(line) != "" && !strings.HasPrefix(strings.TrimSpace(line), "--") {
+				return nil, fmt.Errorf("%s:%d: statement outside of an Up or Down section", name, lineNumber)
+			}
+			continue
+		}
+
+		buf.WriteString(line)
+		buf.WriteByte('\n')
+
+		if !inBlock && strings.HasSuffix(strings.TrimSpace(stripComment(line)), ";") {
+			flush()
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+
+	if inBlock {
+		return nil, fmt.Errorf("%s: missing StatementEnd", name)
+	}
+
+	flush()
+
+	if section == none {
+		return nil, fmt.Errorf("%s: missing -- +goose Up annotation", name)
+	}
+
+	return migration, nil
+}
+
+func parseVersion(name string) (int64, error) {
+	prefix, _, found := strings.Cut(name, "_")
+	if !found {
+		return 0, fmt.Errorf("%s: file name must start with a numeric version followed by an underscore", name)
+	}
+
+	version, err := strconv.ParseInt(prefix, 10, 64)
+	if err != nil || version < 1 {
+		return 0, fmt.Errorf("%s: invalid version %q", name, prefix)
+	}
+
+	return version, nil
+}
+
+// stripComment removes a trailing -- comment so "SELECT 1; -- note" still ends a statement,
+// a -- inside a string or a quoted identifier is kept
+func stripComment(line string) string {
+	var quote rune
+
+	for i, r := range line {
+		switch {
+		case quote != 0:
+			// a doubled quote escapes it, closing and reopening the string has the same effect
+			if r == quote {
+				quote = 0
+			}
+		case r == '\'' || r == '"':
+			quote = r
+		case strings.HasPrefix(line[i:], "--"):
+			return line[:i]
+		}
+	}
+
+	return line
+}
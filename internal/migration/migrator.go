@@ -0,0 +1,447 @@
+package migration
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// TableName is the tracking table, compatible with the one goose creates,
+// so databases migrated with the goose binary are picked up as they are
+const TableName = "goose_db_version"
+
+// lockID is the key of the session level advisory lock held while migrating
+const lockID int64 = 5887940537704921958
+
+type Status struct {
+	Migration *Migration
+	Applied   bool
+	AppliedAt *time.Time
+}
+
+type Migrator struct {
+	DB         *sql.DB
+	Migrations []*Migration
+
+	// Measure records the cost of every migration applied or rolled back in CostTable,
+	// Costs collects them in the order they were run
+	Measure        bool
+	SampleInterval time.Duration // between two samples of pg_locks, DefaultSampleInterval if 0
+	Costs          []Cost
+}
+
+func New(db *sql.DB, migrations []*Migration) *Migrator {
+	return &Migrator{DB: db, Migrations: migrations}
+}
+
+// Version returns the highest applied version, 0 if nothing has been applied yet
+func (m *Migrator) Version(ctx context.Context) (int64, error) {
+	var version int64
+
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		applied, err := appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		version = currentVersion(applied)
+		return nil
+	})
+
+	return version, err
+}
+
+// Up applies all pending migrations
+func (m *Migrator) Up(ctx context.Context) ([]*Migration, error) {
+	if len(m.Migrations) == 0 {
+		return nil, ErrNoMigrations
+	}
+
+	return m.UpTo(ctx, m.Migrations[len(m.Migrations)-1].Version)
+}
+
+// UpTo applies all pending migrations up to and including version
+func (m *Migrator) UpTo(ctx context.Context, version int64) ([]*Migration, error) {
+	if m.find(version) == nil {
+		return nil, fmt.Errorf("%w: %d", ErrUnknownVersion, version)
+	}
+
+	var done []*Migration
+
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		err := ensureTable(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		applied, err := appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for _, migration := range m.Migrations {
+			if migration.Version > version {
+				break
+			}
+			if _, ok := applied[migration.Version]; ok {
+				continue
+			}
+
+			err := m.apply(ctx, conn, migration, migration.Up, true)
+			if err != nil {
+				return err
+			}
+
+			done = append(done, migration)
+		}
+
+		return nil
+	})
+
+	return done, err
+}
+
+// Down rolls back the most recently applied migration
+func (m *Migrator) Down(ctx context.Context) (*Migration, error) {
+	var done *Migration
+
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		migration, err := m.current(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		err = m.apply(ctx, conn, migration, migration.Down, false)
+		if err != nil {
+			return err
+		}
+
+		done = migration
+		return nil
+	})
+
+	return done, err
+}
+
+// Redo rolls back the most recently applied migration and applies it again
+func (m *Migrator) Redo(ctx context.Context) (*Migration, error) {
+	var done *Migration
+
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		migration, err := m.current(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		err = m.apply(ctx, conn, migration, migration.Down, false)
+		if err != nil {
+			return err
+		}
+
+		err = m.apply(ctx, conn, migration, migration.Up, true)
+		if err != nil {
+			return err
+		}
+
+		done = migration
+		return nil
+	})
+
+	return done, err
+}
+
+// Status reports every known migration and whether it has been applied
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	var statuses []Status
+
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		applied, err := appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		statuses = make([]Status, 0, len(m.Migrations))
+
+		for _, migration := range m.Migrations {
+			status := Status{Migration: migration}
+
+			if tstamp, ok := applied[migration.Version]; ok {
+				status.Applied = true
+				status.AppliedAt = &tstamp
+			}
+
+			statuses = append(statuses, status)
+		}
+
+		return nil
+	})
+
+	return statuses, err
+}
+
+// CurrentVersion reads the highest applied version without taking the advisory lock
+// or creating the tracking table, 0 if the database has never been migrated
+func CurrentVersion(ctx context.Context, db *sql.DB) (int64, error) {
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+
+	applied, err := appliedVersions(ctx, conn)
+	if err != nil {
+		return 0, err
+	}
+
+	return currentVersion(applied), nil
+}
+
+func (m *Migrator) find(version int64) *Migration {
+	for _, migration := range m.Migrations {
+		if migration.Version == version {
+			return migration
+		}
+	}
+	return nil
+}
+
+func (m *Migrator) current(ctx context.Context, conn *sql.Conn) (*Migration, error) {
+	applied, err := appliedVersions(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+
+	version := currentVersion(applied)
+	if version == 0 {
+		return nil, ErrNoCurrentVersion
+	}
+
+	migration := m.find(version)
+	if migration == nil {
+		return nil, fmt.Errorf("%w: %d is applied but has no migration file", ErrUnknownVersion, version)
+	}
+
+	return migration, nil
+}
+
+// withLock runs fn on a dedicated connection holding the migration advisory lock,
+// so two runners can never migrate the same database concurrently
+func (m *Migrator) withLock(ctx context.Context, fn func(conn *sql.Conn) error) error {
+	if len(m.Migrations) == 0 {
+		return ErrNoMigrations
+	}
+
+	conn, err := m.DB.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	_, err = conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, lockID)
+	if err != nil {
+		return fmt.Errorf("acquiring advisory lock: %w", err)
+	}
+
+	defer func() {
+		// the lock has to be released even if ctx has been cancelled in the meantime
+		unlockCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		conn.ExecContext(unlockCtx, `SELECT pg_advisory_unlock($1)`, lockID)
+	}()
+
+	return fn(conn)
+}
+
+func tableExists(ctx context.Context, conn *sql.Conn) (bool, error) {
+	var exists bool
+
+	err := conn.QueryRowContext(ctx, `SELECT to_regclass($1) IS NOT NULL`, TableName).Scan(&exists)
+	return exists, err
+}
+
+// ensureTable creates the tracking table, only migrating writes it so reading the version of
+// a database that was never migrated leaves it as it is
+func ensureTable(ctx context.Context, conn *sql.Conn) error {
+	exists, err := tableExists(ctx, conn)
+	if err != nil {
+		return err
+	}
+
+	if exists {
+		return nil
+	}
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := fmt.Sprintf(`
+		CREATE TABLE %s (
+			id integer PRIMARY KEY GENERATED BY DEFAULT AS IDENTITY,
+			version_id bigint NOT NULL,
+			is_applied boolean NOT NULL,
+			tstamp timestamp NOT NULL DEFAULT now()
+		)`, TableName)
+
+	_, err = tx.ExecContext(ctx, query)
+	if err != nil {
+		return err
+	}
+
+	// goose marks a fresh database with version 0, keep doing the same
+	_, err = tx.ExecContext(ctx, fmt.Sprintf(`INSERT INTO %s (version_id, is_applied) VALUES (0, true)`, TableName))
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// appliedVersions returns the applied versions and when they were applied, none if the tracking
+// table does not exist yet
+func appliedVersions(ctx context.Context, conn *sql.Conn) (map[int64]time.Time, error) {
+	exists, err := tableExists(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+
+	applied := make(map[int64]time.Time)
+
+	if !exists {
+		return applied, nil
+	}
+
+	query := fmt.Sprintf(`
+		SELECT version_id, is_applied, tstamp
+		FROM %s
+		WHERE version_id > 0
+		ORDER BY id`, TableName)
+
+	rows, err := conn.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			version   int64
+			isApplied bool
+			tstamp    time.Time
+		)
+
+		err := rows.Scan(&version, &isApplied, &tstamp)
+		if err != nil {
+			return nil, err
+		}
+
+		// later rows win, older goose versions recorded rollbacks as is_applied = false
+		if isApplied {
+			applied[version] = tstamp
+		} else {
+			delete(applied, version)
+		}
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return applied, nil
+}
+
+func currentVersion(applied map[int64]time.Time) int64 {
+	var version int64
+	for v := range applied {
+		version = max(version, v)
+	}
+	return version
+}
+
+// apply runs a migration, measuring its cost if m.Measure is set
+func (m *Migrator) apply(ctx context.Context, conn *sql.Conn, migration *Migration, statements []string, up bool) error {
+	if m.Measure {
+		return m.measure(ctx, conn, migration, statements, up)
+	}
+
+	_, err := apply(ctx, conn, migration, statements, up)
+	return err
+}
+
+// apply runs the statements of one direction of a migration and records the result
+// in the tracking table, inside a single transaction unless the migration opted out.
+// It returns the rows affected by the statements.
+func apply(ctx context.Context, conn *sql.Conn, migration *Migration, statements []string, up bool) (int64, error) {
+	record := func(ctx context.Context, exec execer) error {
+		var err error
+		if up {
+			_, err = exec.ExecContext(ctx, fmt.Sprintf(`INSERT INTO %s (version_id, is_applied) VALUES ($1, true)`, TableName), migration.Version)
+		} else {
+			_, err = exec.ExecContext(ctx, fmt.Sprintf(`DELETE FROM %s WHERE version_id = $1`, TableName), migration.Version)
+		}
+		return err
+	}
+
+	var rows int64
+
+	run := func(exec execer) error {
+		for _, statement := range statements {
+			result, err := exec.ExecContext(ctx, statement)
+			if err != nil {
+				return wrap(migration, up, err)
+			}
+
+			// DDL reports no rows, which is fine as only row rewrites are of interest
+			if n, err := result.RowsAffected(); err == nil {
+				rows += n
+			}
+		}
+		return nil
+	}
+
+	if !migration.UseTx {
+		err := run(conn)
+		if err != nil {
+			return rows, err
+		}
+
+		return rows, record(ctx, conn)
+	}
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	err = run(tx)
+	if err != nil {
+		return rows, err
+	}
+
+	err = record(ctx, tx)
+	if err != nil {
+		return rows, err
+	}
+
+	return rows, tx.Commit()
+}
+
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+func wrap(migration *Migration, up bool, err error) error {
+	return fmt.Errorf("%s (%s): %w", migration.Name, direction(up), err)
+}
+
+func direction(up bool) string {
+	if up {
+		return "up"
+	}
+	return "down"
+}
@@ -0,0 +1,31 @@
+package model
+
+import (
+	"thesis.lefler.eu/internal/schema"
+
+	v1 "thesis.lefler.eu/internal/model/v1"
+	v2 "thesis.lefler.eu/internal/model/v2"
+	v3 "thesis.lefler.eu/internal/model/v3"
+	v4 "thesis.lefler.eu/internal/model/v4"
+	v5 "thesis.lefler.eu/internal/model/v5"
+)
+
+// Schemas describes the resources served by every version, with the rules they are validated by.
+// The resources are the same in every strategy.
+func Schemas() schema.Versions {
+	return schema.Versions{
+		"v1": {"movies": schema.Validated(v1.ValidateMovie)},
+		"v2": {"movies": schema.Validated(v2.ValidateMovie)},
+		"v3": {"movies": schema.Validated(v3.ValidateMovie)},
+		"v4": {
+			"movies":        schema.Validated(v4.ValidateMovie, schema.Nested("actors", v4.ValidateCrew)),
+			"actors":        schema.Validated(v4.ValidateActor),
+			"movies/actors": schema.Validated(v4.ValidateCrew, schema.Served[int32]("version")),
+		},
+		"v5": {
+			"movies":      schema.Validated(v5.ValidateMovie, schema.Nested("crew", v5.ValidateCrew)),
+			"people":      schema.Validated(v5.ValidatePerson),
+			"movies/crew": schema.Validated(v5.ValidateCrew, schema.Served[int32]("version")),
+		},
+	}
+}
@@ -0,0 +1,28 @@
+package v1
+
+import (
+	"time"
+
+	"thesis.lefler.eu/internal/validator"
+)
+
+type Movie struct {
+	ID        int64     `json:"id"`              // Unique identifier
+	CreatedAt time.Time `json:"-"`               // Timestamp of when the movie was added to the database
+	UpdatedAt time.Time `json:"-"`               // Timestamp of when the movie record was last updated
+	Title     string    `json:"title"`           // Title of the movie
+	Year      int32     `json:"year,omitempty"`  // Release year
+	Genre     string    `json:"genre,omitempty"` // Genre
+	Version   int32     `json:"version"`         // Version number, starts at 1 and increments each time the movie is updated
+}
+
+func ValidateMovie(v *validator.Validator, movie *Movie) {
+	v.Check(movie.Title != "", "title", "must be provided")
+	v.Check(len(movie.Title) <= 500, "title", "must not be more than 500 bytes long")
+
+	v.Check(movie.Year != 0, "year", "must be provided")
+	v.Check(movie.Year >= 1888, "year", "must be greater than 1888")
+	v.Check(movie.Year <= int32(time.Now().Year()), "year", "must not be in the future")
+
+	v.Check(movie.Genre != "", "genre", "must be provided")
+}
@@ -0,0 +1,49 @@
+package v2
+
+import (
+	"time"
+
+	"thesis.lefler.eu/internal/validator"
+)
+
+// New fields as pointers to allow for null values from pre-v2 records, ValidateMovie of every
+// later version only checks them when set, so movies created through v1 can be updated
+type Movie struct {
+	ID        int64     `json:"id"`                 // Unique identifier
+	CreatedAt time.Time `json:"-"`                  // Timestamp of when the movie was added to the database
+	UpdatedAt time.Time `json:"-"`                  // Timestamp of when the movie record was last updated
+	Title     string    `json:"title"`              // Title of the movie
+	Year      int32     `json:"year,omitempty"`     // Release year
+	Genre     string    `json:"genre,omitempty"`    // Genre
+	Director  *string   `json:"director,omitempty"` // Director name
+	Runtime   *int32    `json:"runtime,omitempty"`  // Runtime in minutes
+	Language  *string   `json:"language,omitempty"` // Language
+	Version   int32     `json:"version"`            // Version number, starts at 1 and increments each time the movie is updated
+}
+
+func ValidateMovie(v *validator.Validator, movie *Movie) {
+	v.Check(movie.Title != "", "title", "must be provided")
+	v.Check(len(movie.Title) <= 500, "title", "must not be more than 500 bytes long")
+
+	v.Check(movie.Year != 0, "year", "must be provided")
+	v.Check(movie.Year >= 1888, "year", "must be greater than 1888")
+	v.Check(movie.Year <= int32(time.Now().Year()), "year", "must not be in the future")
+
+	v.Check(movie.Genre != "", "genre", "must be provided")
+	v.Check(len(movie.Genre) <= 50, "genre", "must not be more than 50 bytes long")
+
+	if movie.Director != nil {
+		v.Check(*movie.Director != "", "director", "must be provided")
+		v.Check(len(*movie.Director) <= 100, "director", "must not be more than 100 bytes long")
+	}
+
+	if movie.Runtime != nil {
+		v.Check(*movie.Runtime != 0, "runtime", "must be provided")
+		v.Check(*movie.Runtime > 0, "runtime", "must be a positive integer")
+	}
+
+	if movie.Language != nil {
+		v.Check(*movie.Language != "", "language", "must be provided")
+		v.Check(len(*movie.Language) <= 50, "language", "must not be more than 50 bytes long")
+	}
+}
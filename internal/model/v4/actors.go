@@ -0,0 +1,26 @@
+package v4
+
+import (
+	"time"
+
+	"cloud.google.com/go/civil"
+
+	"thesis.lefler.eu/internal/validator"
+)
+
+type Actor struct {
+	ID        int64       `json:"id"`                  // Unique identifier
+	CreatedAt time.Time   `json:"-"`                   // Timestamp of when the movie was added to the database
+	UpdatedAt time.Time   `json:"-"`                   // Timestamp of when the movie record was last updated
+	Name      string      `json:"name"`                // Actor name
+	Birthdate *civil.Date `json:"birthdate,omitempty"` // Actor birthdate
+	Version   int32       `json:"version"`             // Version number, starts at 1 and increments each time the movie is updated
+}
+
+func ValidateActor(v *validator.Validator, actor *Actor) {
+	v.Check(actor.Name != "", "name", "must be provided")
+	v.Check(len(actor.Name) <= 500, "name", "must not be more than 500 bytes long")
+
+	v.Check(actor.Birthdate.IsValid(), "birthdate", "is not valid")
+	v.Check(actor.Birthdate.Before(civil.DateOf(time.Now())), "birthdate", "must not be in the future")
+}
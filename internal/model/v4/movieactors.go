@@ -0,0 +1,26 @@
+package v4
+
+import (
+	"time"
+
+	"thesis.lefler.eu/internal/validator"
+)
+
+type MovieActor struct {
+	MovieID   int64     `json:"-"`
+	ActorID   int64     `json:"actor_id"`
+	ActorName string    `json:"actor_name"`
+	Role      string    `json:"role"`
+	CreatedAt time.Time `json:"-"`
+	UpdatedAt time.Time `json:"-"`
+	Version   int32     `json:"-"`
+}
+
+func ValidateCrew(v *validator.Validator, movieActor *MovieActor) {
+	v.Check(movieActor.MovieID > 0, "movie_id", "must be a positive integer")
+	v.Check(movieActor.ActorID > 0, "actor_id", "must be a positive integer")
+
+	v.Check(movieActor.Role != "", "role", "must be provided")
+	v.Check(len(movieActor.Role) <= 500, "role", "must not be more than 500 bytes long")
+
+}
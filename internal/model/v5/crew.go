@@ -0,0 +1,32 @@
+package v5
+
+import (
+	"strings"
+	"time"
+
+	"thesis.lefler.eu/internal/validator"
+)
+
+type Crew struct {
+	MovieID    int64     `json:"-"`
+	PersonID   int64     `json:"person_id"`
+	PersonName string    `json:"person_name"`
+	CrewType   string    `json:"crew_type"`
+	Role       string    `json:"role,omitempty"`
+	CreatedAt  time.Time `json:"-"`
+	UpdatedAt  time.Time `json:"-"`
+	Version    int32     `json:"-"`
+}
+
+func ValidateCrew(v *validator.Validator, crew *Crew) {
+	v.Check(crew.PersonID > 0, "person_id", "must be a positive integer")
+
+	v.Check(crew.CrewType != "", "crew_type", "must be provided")
+	v.Check(len(crew.CrewType) <= 500, "crew_type", "must not be more than 500 bytes long")
+	v.Check(validator.PermittedValue(crew.CrewType, "Actor", "Director", "Producer"), "crew_type", "must be either 'Actor', 'Director', or 'Producer'")
+
+	if strings.ToLower(crew.CrewType) == "actor" {
+		v.Check(crew.Role != "", "role", "must be provided if crew_type is 'actor'")
+		v.Check(len(crew.Role) <= 500, "role", "must not be more than 500 bytes long")
+	}
+}
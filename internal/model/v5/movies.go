@@ -0,0 +1,46 @@
+package v5
+
+import (
+	"time"
+
+	"thesis.lefler.eu/internal/validator"
+)
+
+// V2 fields still as pointers to allow for null values from pre-v2 records
+// genres does not need to be a pointer as it is a slice and can be nil
+type Movie struct {
+	ID        int64     `json:"id"`                 // Unique identifier
+	CreatedAt time.Time `json:"-"`                  // Timestamp of when the movie was added to the database
+	UpdatedAt time.Time `json:"-"`                  // Timestamp of when the movie record was last updated
+	Title     string    `json:"title"`              // Title of the movie
+	Year      int32     `json:"year,omitempty"`     // Release year
+	Genres    []string  `json:"genres,omitempty"`   // Slice of genres
+	Runtime   *int32    `json:"runtime,omitempty"`  // Runtime in minutes
+	Language  *string   `json:"language,omitempty"` // Language
+	Crew      []*Crew   `json:"crew,omitempty"`     // Slice of crew
+	Version   int32     `json:"version"`            // Version number, starts at 1 and increments each time the movie is updated
+}
+
+func ValidateMovie(v *validator.Validator, movie *Movie) {
+	v.Check(movie.Title != "", "title", "must be provided")
+	v.Check(len(movie.Title) <= 500, "title", "must not be more than 500 bytes long")
+
+	v.Check(movie.Year != 0, "year", "must be provided")
+	v.Check(movie.Year >= 1888, "year", "must be greater than 1888")
+	v.Check(movie.Year <= int32(time.Now().Year()), "year", "must not be in the future")
+
+	v.Check(movie.Genres != nil, "genres", "must be provided")
+	v.Check(len(movie.Genres) >= 1, "genres", "must contain at least 1 genre")
+	v.Check(len(movie.Genres) <= 5, "genres", "must not contain more than 5 genres")
+	v.Check(validator.Unique(movie.Genres), "genres", "must not contain duplicate values")
+
+	if movie.Runtime != nil {
+		v.Check(*movie.Runtime != 0, "runtime", "must be provided")
+		v.Check(*movie.Runtime > 0, "runtime", "must be a positive integer")
+	}
+
+	if movie.Language != nil {
+		v.Check(*movie.Language != "", "language", "must be provided")
+		v.Check(len(*movie.Language) <= 50, "language", "must not be more than 50 bytes long")
+	}
+}
@@ -0,0 +1,26 @@
+package v5
+
+import (
+	"time"
+
+	"cloud.google.com/go/civil"
+
+	"thesis.lefler.eu/internal/validator"
+)
+
+type Person struct {
+	ID        int64       `json:"id"`                  // Unique identifier
+	CreatedAt time.Time   `json:"-"`                   // Timestamp of when the movie was added to the database
+	UpdatedAt time.Time   `json:"-"`                   // Timestamp of when the movie record was last updated
+	Name      string      `json:"name"`                // Person name
+	Birthdate *civil.Date `json:"birthdate,omitempty"` // Person birthdate
+	Version   int32       `json:"version"`             // Version number, starts at 1 and increments each time the movie is updated
+}
+
+func ValidatePerson(v *validator.Validator, person *Person) {
+	v.Check(person.Name != "", "name", "must be provided")
+	v.Check(len(person.Name) <= 500, "name", "must not be more than 500 bytes long")
+
+	v.Check(person.Birthdate.IsValid(), "birthdate", "is not valid")
+	v.Check(person.Birthdate.Before(civil.DateOf(time.Now())), "birthdate", "must not be in the future")
+}
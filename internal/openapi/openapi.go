@@ -0,0 +1,512 @@
+package openapi
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"thesis.lefler.eu/internal/schema"
+)
+
+// Version is the OpenAPI version of the generated documents
+const Version = "3.1.0"
+
+// readOnly are fields set by the server, clients do not send them on create and update
+var readOnly = map[string]bool{
+	"id":          true,
+	"version":     true,
+	"person_name": true,
+	"actor_name":  true,
+}
+
+type Document struct {
+	OpenAPI    string              `json:"openapi"`
+	Info       Info                `json:"info"`
+	Paths      map[string]PathItem `json:"paths"`
+	Components Components          `json:"components"`
+}
+
+type Info struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+type Components struct {
+	Schemas map[string]Schema `json:"schemas"`
+}
+
+// PathItem maps lower case HTTP methods to operations
+type PathItem map[string]Operation
+
+type Operation struct {
+	OperationID string              `json:"operationId"`
+	Summary     string              `json:"summary"`
+	Tags        []string            `json:"tags"`
+	Deprecated  bool                `json:"deprecated,omitempty"`
+	Parameters  []Parameter         `json:"parameters,omitempty"`
+	RequestBody *RequestBody        `json:"requestBody,omitempty"`
+	Responses   map[string]Response `json:"responses"`
+}
+
+type Parameter struct {
+	Name        string `json:"name"`
+	In          string `json:"in"`
+	Description string `json:"description,omitempty"`
+	Required    bool   `json:"required"`
+	Schema      Schema `json:"schema"`
+}
+
+type RequestBody struct {
+	Required bool                 `json:"required"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+type Response struct {
+	Description string               `json:"description"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+type MediaType struct {
+	Schema Schema `json:"schema"`
+}
+
+// Schema is a JSON Schema (2020-12) object, as used by OpenAPI 3.1
+type Schema map[string]any
+
+// Resource is a single resource of a version, as registered in the API
+type Resource struct {
+	Strategy   string
+	Version    string
+	Name       string   // path segment, e.g. movies
+	Parent     string   // resource the items are nested in, e.g. movies for the crew of a movie
+	Item       []string // path parameters addressing an item, id if empty, e.g. person_id and crew_type
+	Envelope   string   // key of an item in replies, the lower case model name if empty
+	Deprecated bool
+	Model      schema.Type
+}
+
+// New returns the document describing resources. Component names are prefixed with the
+// strategy and version of their resource if resources span more than one of them.
+func New(title string, resources ...Resource) Document {
+	doc := Document{
+		OpenAPI: Version,
+		Info:    Info{Title: title, Version: versionOf(resources)},
+		Paths:   make(map[string]PathItem),
+		Components: Components{
+			Schemas: map[string]Schema{
+				"Metadata": {
+					"type":        "object",
+					"description": "the page of a list, empty if nothing matched, lists paged by cursor only have page_size and the links",
+					"properties": map[string]Schema{
+						"current_page":  {"type": "integer"},
+						"page_size":     {"type": "integer"},
+						"first_page":    {"type": "integer"},
+						"last_page":     {"type": "integer"},
+						"total_records": {"type": "integer"},
+						"next":          {"type": "string", "description": "link to the next page of a list paged by cursor"},
+						"previous":      {"type": "string", "description": "link to the previous page of a list paged by cursor"},
+					},
+				},
+				"Error": {
+					"type":     "object",
+					"required": []string{"error"},
+					"properties": map[string]Schema{
+						"error": {"description": "a message, or the message of every invalid field on failed validation"},
+					},
+				},
+			},
+		},
+	}
+
+	qualify := len(groups(resources)) > 1
+
+	for _, resource := range resources {
+		doc.add(resource, qualify)
+	}
+
+	return doc
+}
+
+func (doc *Document) add(resource Resource, qualify bool) {
+	name := resource.Model.Name
+	if qualify {
+		name = fmt.Sprintf("%s_%s_%s", resource.Strategy, resource.Version, name)
+	}
+
+	doc.Components.Schemas[name] = objectSchema(resource.Model, served)
+	doc.Components.Schemas[name+"Create"] = objectSchema(resource.Model, create)
+	doc.Components.Schemas[name+"Update"] = objectSchema(resource.Model, update)
+
+	var (
+		single     = strings.ToLower(resource.Model.Name)
+		tag        = fmt.Sprintf("%s %s", resource.Strategy, resource.Version)
+		operation  = fmt.Sprintf("%s_%s_%s", resource.Strategy, resource.Version, resource.Name)
+		collection = fmt.Sprintf("/%s/%s/%s", resource.Strategy, resource.Version, resource.Name)
+		ref        = Schema{"$ref": "#/components/schemas/" + name}
+	)
+
+	if resource.Envelope != "" {
+		single = resource.Envelope
+	}
+
+	// a nested resource is below an item of its parent, e.g. /movies/{id}/crew
+	var parent []Parameter
+	if resource.Parent != "" {
+		operation = fmt.Sprintf("%s_%s_%s_%s", resource.Strategy, resource.Version, resource.Parent, resource.Name)
+		collection = fmt.Sprintf("/%s/%s/%s/{id}/%s", resource.Strategy, resource.Version, resource.Parent, resource.Name)
+		parent = pathParameters([]string{"id"})
+	}
+
+	params := pathParameters(resource.Item)
+	if len(params) == 0 {
+		params = pathParameters([]string{"id"})
+	}
+	params = append(slices.Clip(parent), params...)
+
+	var segments []string
+	for _, param := range params[len(parent):] {
+		segments = append(segments, "{"+param.Name+"}")
+	}
+	path := collection + "/" + strings.Join(segments, "/")
+
+	item := envelope(single, ref)
+	list := Schema{
+		"type":     "object",
+		"required": []string{resource.Name, "metadata"},
+		"properties": map[string]Schema{
+			resource.Name: {"type": "array", "items": ref},
+			"metadata":    {"$ref": "#/components/schemas/Metadata"},
+		},
+	}
+	listParams := listParameters(resource.Model)
+	projection := projectionParameters(resource.Model)
+	var deleteParams []Parameter
+
+	listErrors := []int{http.StatusUnprocessableEntity, http.StatusInternalServerError}
+	createErrors := []int{http.StatusBadRequest, http.StatusUnprocessableEntity, http.StatusInternalServerError}
+	deleteErrors := []int{http.StatusNotFound, http.StatusInternalServerError}
+
+	// nested resources are listed whole and their items are changed at the version they were read at
+	if resource.Parent != "" {
+		list = envelope(resource.Name, Schema{"type": "array", "items": ref})
+		listParams = nil
+		// the params addressing an item are its identity, they are not updated
+		update := doc.Components.Schemas[name+"Update"]
+		properties := update["properties"].(map[string]Schema)
+		for _, param := range resource.Item {
+			delete(properties, param)
+		}
+		properties["version"] = Schema{"type": "integer", "format": "int32", "description": "the version the item was read at"}
+		update["required"] = append(update["required"].([]string), "version")
+		deleteParams = []Parameter{{Name: "version", In: "query", Description: "the version the item was read at", Required: true, Schema: Schema{"type": "integer", "format": "int32"}}}
+
+		// the item of the parent may not exist
+		listErrors = append([]int{http.StatusNotFound}, listErrors...)
+		createErrors = []int{http.StatusBadRequest, http.StatusNotFound, http.StatusUnprocessableEntity, http.StatusInternalServerError}
+		deleteErrors = []int{http.StatusNotFound, http.StatusConflict, http.StatusUnprocessableEntity, http.StatusInternalServerError}
+	}
+
+	doc.Paths[collection] = PathItem{
+		"get": {
+			OperationID: "list_" + operation,
+			Summary:     fmt.Sprintf("List %s", resource.Name),
+			Parameters:  append(append(slices.Clip(parent), listParams...), projection...),
+			Responses:   responses(http.StatusOK, list, listErrors...),
+		},
+		"post": {
+			OperationID: "create_" + operation,
+			Summary:     fmt.Sprintf("Create a %s", strings.ReplaceAll(single, "_", " ")),
+			Parameters:  parent,
+			RequestBody: body(Schema{"$ref": "#/components/schemas/" + name + "Create"}),
+			Responses:   responses(http.StatusCreated, item, createErrors...),
+		},
+	}
+
+	doc.Paths[path] = PathItem{
+		"get": {
+			OperationID: "get_" + operation,
+			Summary:     fmt.Sprintf("Get a %s", strings.ReplaceAll(single, "_", " ")),
+			Parameters:  append(slices.Clip(params), projection...),
+			Responses:   responses(http.StatusOK, item, http.StatusNotFound, http.StatusUnprocessableEntity, http.StatusInternalServerError),
+		},
+		"patch": {
+			OperationID: "update_" + operation,
+			Summary:     fmt.Sprintf("Update a %s, fields not sent are left unchanged", strings.ReplaceAll(single, "_", " ")),
+			Parameters:  params,
+			RequestBody: body(Schema{"$ref": "#/components/schemas/" + name + "Update"}),
+			Responses:   responses(http.StatusOK, item, http.StatusBadRequest, http.StatusNotFound, http.StatusConflict, http.StatusUnprocessableEntity, http.StatusInternalServerError),
+		},
+		"delete": {
+			OperationID: "delete_" + operation,
+			Summary:     fmt.Sprintf("Delete a %s", strings.ReplaceAll(single, "_", " ")),
+			Parameters:  append(slices.Clip(params), deleteParams...),
+			Responses:   responses(http.StatusOK, envelope("message", Schema{"type": "string"}), deleteErrors...),
+		},
+	}
+
+	for _, path := range []string{collection, path} {
+		for method, op := range doc.Paths[path] {
+			op.Tags = []string{tag}
+			op.Deprecated = resource.Deprecated
+			doc.Paths[path][method] = op
+		}
+	}
+}
+
+// sortable are the fields a list can be sorted by, if its resource has them
+var sortable = []string{"id", "title", "year", "genre", "runtime", "name", "birthdate"}
+
+// listParameters are the pagination, sorting and filter parameters of the list of a resource
+func listParameters(t schema.Type) []Parameter {
+	has := make(map[string]bool)
+	for _, field := range t.Fields {
+		has[field.Name] = true
+	}
+
+	var sort []string
+	for _, field := range sortable {
+		if has[field] {
+			sort = append(sort, field, "-"+field)
+		}
+	}
+	sort = append(sort, "relevance")
+
+	var filters []Parameter
+
+	if has["title"] {
+		filters = append(filters,
+			query("title", "part of the title, case insensitive", Schema{"type": "string"}),
+			query("q", "words of the title, matched in full text by their prefixes", Schema{"type": "string", "maxLength": 500}),
+		)
+	}
+	if has["genre"] {
+		filters = append(filters, query("genre", "the genre of the movie", Schema{"type": "string"}))
+	}
+	if has["genres"] {
+		filters = append(filters, query("genres", "comma separated genres the movie has all of", Schema{"type": "string"}))
+	}
+	if has["year"] {
+		filters = append(filters,
+			query("year_from", "earliest release year", Schema{"type": "integer", "minimum": 0}),
+			query("year_to", "latest release year", Schema{"type": "integer", "minimum": 0}),
+		)
+	}
+	if has["name"] {
+		filters = append(filters,
+			query("name", "part of the name, case insensitive", Schema{"type": "string"}),
+			query("q", "words of the name, matched in full text by their prefixes", Schema{"type": "string", "maxLength": 500}),
+		)
+	}
+
+	return append(filters,
+		query("page", "", Schema{"type": "integer", "minimum": 1, "maximum": 10_000_000, "default": 1}),
+		query("page_size", "", Schema{"type": "integer", "minimum": 1, "maximum": 100, "default": 20}),
+		query("sort", "field to sort by, a leading - sorts descending, relevance ranks searched lists and is their default unless paged by cursor", Schema{"type": "string", "enum": sort, "default": "id"}),
+		query("limit", "page size of a list paged by cursor, given instead of page and page_size", Schema{"type": "integer", "minimum": 1, "maximum": 100, "default": 20}),
+		query("after", "cursor of the record the page starts after, from the next link", Schema{"type": "string"}),
+		query("before", "cursor of the record the page ends before, from the previous link", Schema{"type": "string"}),
+	)
+}
+
+// projectionParameters are the sparse fieldset and embedded relation parameters of resources
+// with a crew, the movies of v5
+func projectionParameters(t schema.Type) []Parameter {
+	var fields []string
+	embeds := false
+
+	for _, field := range t.Fields {
+		switch {
+		case field.Name == "crew":
+			embeds = true
+		case field.Kind != "object" && !(field.Kind == "array" && field.Items.Kind == "object"):
+			fields = append(fields, field.Name)
+		}
+	}
+
+	if !embeds {
+		return nil
+	}
+
+	return []Parameter{
+		query("fields", "comma separated fields to return, the id is always returned: "+strings.Join(fields, ", "), Schema{"type": "string"}),
+		query("include", "comma separated relations to embed: crew, and people for the names of the crew, defaults to both on single movies unless fields or include is given", Schema{"type": "string"}),
+	}
+}
+
+// pathParameters are the parameters addressing an item, ids are positive integers, e.g. id and
+// person_id, the others strings, e.g. the crew_type of a crew member
+func pathParameters(names []string) []Parameter {
+	var params []Parameter
+
+	for _, name := range names {
+		s := Schema{"type": "string"}
+		if name == "id" || strings.HasSuffix(name, "_id") {
+			s = Schema{"type": "integer", "format": "int64", "minimum": 1}
+		}
+
+		params = append(params, Parameter{Name: name, In: "path", Required: true, Schema: s})
+	}
+
+	return params
+}
+
+func query(name string, description string, s Schema) Parameter {
+	return Parameter{Name: name, In: "query", Description: description, Schema: s}
+}
+
+// mode selects which side of an exchange a schema describes
+type mode int
+
+const (
+	served mode = iota // response bodies
+	create             // POST bodies, readOnly fields are left out and provided fields are required
+	update             // PATCH bodies, like create but every field is optional
+)
+
+func objectSchema(t schema.Type, m mode) Schema {
+	properties := make(map[string]Schema)
+	required := []string{}
+
+	for _, field := range t.Fields {
+		if m != served && readOnly[field.Name] {
+			continue
+		}
+
+		property := typeSchema(field.Type, m)
+		if readOnly[field.Name] {
+			property["readOnly"] = true
+		}
+
+		provided := apply(property, field)
+
+		switch {
+		case m == served && !field.Optional && !field.Nullable:
+			required = append(required, field.Name)
+		case m == create && provided:
+			required = append(required, field.Name)
+		}
+
+		properties[field.Name] = property
+	}
+
+	s := Schema{
+		"type":       "object",
+		"properties": properties,
+		"required":   required,
+	}
+	if m != served {
+		s["additionalProperties"] = false
+	}
+
+	return s
+}
+
+func typeSchema(t schema.Type, m mode) Schema {
+	var s Schema
+
+	switch t.Kind {
+	case "array":
+		s = Schema{"type": t.Kind, "items": typeSchema(*t.Items, m)}
+	case "object":
+		if len(t.Fields) > 0 {
+			s = objectSchema(t, m)
+		} else {
+			s = Schema{"type": t.Kind}
+		}
+	default:
+		s = Schema{"type": t.Kind}
+		if t.Format != "" {
+			s["format"] = t.Format
+		}
+	}
+
+	// only records written by older versions hold null, clients never need to send it
+	if t.Nullable && m == served {
+		s["type"] = []string{t.Kind, "null"}
+	}
+
+	return s
+}
+
+// apply translates the validation rules of field into JSON Schema keywords and reports
+// whether the field must be provided. Rules without an equivalent, such as "must not be
+// in the future", are kept in the description.
+func apply(s Schema, field schema.Field) (provided bool) {
+	c := field.Constraints()
+
+	if c.Unique {
+		s["uniqueItems"] = true
+	}
+	if c.Enum != nil {
+		s["enum"] = c.Enum
+	}
+	if c.MaxLength != nil {
+		s["maxLength"] = *c.MaxLength
+	}
+	if c.Minimum != nil {
+		s["minimum"] = *c.Minimum
+	}
+	if c.MinItems != nil {
+		s["minItems"] = *c.MinItems
+	}
+	if c.MaxItems != nil {
+		s["maxItems"] = *c.MaxItems
+	}
+	if len(c.Other) > 0 {
+		s["description"] = strings.Join(c.Other, ", ")
+	}
+
+	return c.Required
+}
+
+func envelope(key string, value Schema) Schema {
+	return Schema{
+		"type":       "object",
+		"required":   []string{key},
+		"properties": map[string]Schema{key: value},
+	}
+}
+
+func body(s Schema) *RequestBody {
+	return &RequestBody{
+		Required: true,
+		Content:  map[string]MediaType{"application/json": {Schema: s}},
+	}
+}
+
+func responses(status int, success Schema, errors ...int) map[string]Response {
+	r := map[string]Response{
+		strconv.Itoa(status): {
+			Description: http.StatusText(status),
+			Content:     map[string]MediaType{"application/json": {Schema: success}},
+		},
+	}
+
+	for _, status := range errors {
+		r[strconv.Itoa(status)] = Response{
+			Description: http.StatusText(status),
+			Content:     map[string]MediaType{"application/json": {Schema: Schema{"$ref": "#/components/schemas/Error"}}},
+		}
+	}
+
+	return r
+}
+
+// groups returns the distinct strategy and version pairs of resources
+func groups(resources []Resource) map[string]bool {
+	g := make(map[string]bool)
+	for _, resource := range resources {
+		g[resource.Strategy+"/"+resource.Version] = true
+	}
+	return g
+}
+
+func versionOf(resources []Resource) string {
+	if len(groups(resources)) == 1 {
+		return resources[0].Version
+	}
+	return "all"
+}
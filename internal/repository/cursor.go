@@ -0,0 +1,240 @@
+package repository
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Cursor is the position of a record in a list: the value of the field the list is sorted by
+// and the id of the record. It names fields of the API rather than columns, so it reveals
+// nothing of the tables of a strategy and stays valid when records are inserted before it.
+type Cursor struct {
+	Sort  string          `json:"s"`
+	Value json.RawMessage `json:"v"`
+	ID    int64           `json:"id"`
+}
+
+// String encodes the cursor for a query parameter
+func (c Cursor) String() string {
+	js, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(js)
+}
+
+func ParseCursor(s string) (*Cursor, error) {
+	js, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	var c Cursor
+	if json.Unmarshal(js, &c) != nil || c.ID < 1 || c.Sort == "" || len(c.Value) == 0 {
+		return nil, ErrInvalidCursor
+	}
+
+	return &c, nil
+}
+
+// decoded is the sort value as decoded from JSON: nil, a string or a json.Number
+func (c Cursor) decoded() any {
+	dec := json.NewDecoder(bytes.NewReader(c.Value))
+	dec.UseNumber()
+
+	var v any
+	if dec.Decode(&v) != nil {
+		return nil
+	}
+	return v
+}
+
+// value is the sort value as a query argument, numbers in their decimal form
+func (c Cursor) value() any {
+	if n, ok := c.decoded().(json.Number); ok {
+		return n.String()
+	}
+	return c.decoded()
+}
+
+// CursorOf returns the cursor of a record in a list sorted by sort. The record is a model
+// struct, its fields are found by their JSON names, as the sort fields are.
+func CursorOf(record any, sort string) Cursor {
+	c := Cursor{Sort: sort, Value: json.RawMessage("null")}
+	field := strings.TrimPrefix(sort, "-")
+
+	v := reflect.Indirect(reflect.ValueOf(record))
+	t := v.Type()
+
+	for i := range t.NumField() {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+
+		if name == "id" {
+			c.ID = v.Field(i).Int()
+		}
+		if name == field {
+			c.Value, _ = json.Marshal(v.Field(i).Interface())
+		}
+	}
+
+	return c
+}
+
+// CompareCursors orders a before b in a list sorted by their sort field, NULLs last in
+// ascending and first in descending order, then by id in the same direction
+func CompareCursors(a Cursor, b Cursor) int {
+	c := compareValues(a.decoded(), b.decoded())
+	if c == 0 {
+		c = cmp.Compare(a.ID, b.ID)
+	}
+
+	if strings.HasPrefix(a.Sort, "-") {
+		return -c
+	}
+	return c
+}
+
+func compareValues(a any, b any) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return 1
+	case b == nil:
+		return -1
+	}
+
+	an, aNumber := a.(json.Number)
+	bn, bNumber := b.(json.Number)
+	if aNumber && bNumber {
+		af, _ := an.Float64()
+		bf, _ := bn.Float64()
+		return cmp.Compare(af, bf)
+	}
+
+	return strings.Compare(fmt.Sprint(a), fmt.Sprint(b))
+}
+
+// Keyset returns the clauses of a list query after its filters: the condition selecting the
+// records after or before the cursor, which is TRUE without one, the ORDER BY and the LIMIT
+// and OFFSET. Parameters are numbered from n, args are their values. Lists read by cursor
+// are sorted by id in the direction of the sort, and read backwards before a cursor.
+func (f Filters) Keyset(columns map[string]string, id string, n int) (where string, orderBy string, limit string, args []any) {
+	column := f.SortColumn(columns)
+	direction := f.SortDirection()
+
+	if !f.Cursor {
+		return "TRUE", fmt.Sprintf("%s %s, %s ASC", column, direction, id), fmt.Sprintf("LIMIT $%d OFFSET $%d", n, n+1), []any{f.Limit(), f.Offset()}
+	}
+
+	cursor := f.After
+	if f.Before != nil {
+		cursor = f.Before
+		direction = map[string]string{"ASC": "DESC", "DESC": "ASC"}[direction]
+	}
+
+	// parameters are only numbered when used, PostgreSQL cannot type unused ones
+	param := func(arg any) string {
+		args = append(args, arg)
+		return fmt.Sprintf("$%d", n+len(args)-1)
+	}
+
+	where = "TRUE"
+
+	if cursor != nil {
+		value := cursor.value()
+
+		switch {
+		case f.SortField() == "id" && direction == "ASC":
+			where = fmt.Sprintf("%s > %s", id, param(cursor.ID))
+		case f.SortField() == "id":
+			where = fmt.Sprintf("%s < %s", id, param(cursor.ID))
+		case value == nil && direction == "ASC":
+			where = fmt.Sprintf("(%s IS NULL AND %s > %s)", column, id, param(cursor.ID))
+		case value == nil:
+			where = fmt.Sprintf("(%s IS NOT NULL OR %s < %s)", column, id, param(cursor.ID))
+		case direction == "ASC":
+			v, c := param(value), param(cursor.ID)
+			where = fmt.Sprintf("(%s > %s OR (%s = %s AND %s > %s) OR %s IS NULL)", column, v, column, v, id, c, column)
+		default:
+			v, c := param(value), param(cursor.ID)
+			where = fmt.Sprintf("(%s < %s OR (%s = %s AND %s < %s))", column, v, column, v, id, c)
+		}
+	}
+
+	orderBy = fmt.Sprintf("%s %s, %s %s", column, direction, id, direction)
+
+	// one more record than asked for tells whether there is another page
+	limit = "LIMIT " + param(f.Limit()+1)
+
+	return where, orderBy, limit, args
+}
+
+// Paginate returns the page and its metadata from the records read by a list query built
+// with Keyset, totalRecords is the count of records matching the filters of the list
+func Paginate[T any](records []T, totalRecords int, f Filters) ([]T, Metadata) {
+	if !f.Cursor {
+		return records, CalculateMetadata(totalRecords, f.Page, f.PageSize)
+	}
+
+	more := len(records) > f.Limit()
+	if more {
+		records = records[:f.Limit()]
+	}
+
+	if f.Before != nil {
+		slices.Reverse(records)
+	}
+
+	m := Metadata{PageSize: f.PageSize}
+
+	if len(records) == 0 {
+		return records, m
+	}
+
+	first := CursorOf(records[0], f.Sort)
+	last := CursorOf(records[len(records)-1], f.Sort)
+
+	switch {
+	case f.Before != nil:
+		m.next = &last
+		if more {
+			m.previous = &first
+		}
+	default:
+		if more {
+			m.next = &last
+		}
+		if f.After != nil {
+			m.previous = &first
+		}
+	}
+
+	return records, m
+}
+
+// Link sets the links to the next and previous page of a list read by cursor at u
+func (m *Metadata) Link(u *url.URL) {
+	link := func(key string, c *Cursor) string {
+		qs := u.Query()
+		qs.Del("after")
+		qs.Del("before")
+		qs.Set(key, c.String())
+
+		return (&url.URL{Path: u.Path, RawQuery: qs.Encode()}).String()
+	}
+
+	if m.next != nil {
+		m.Next = link("after", m.next)
+	}
+	if m.previous != nil {
+		m.Previous = link("before", m.previous)
+	}
+}
@@ -0,0 +1,124 @@
+package repository
+
+import (
+	"math"
+	"slices"
+	"strings"
+
+	"thesis.lefler.eu/internal/validator"
+)
+
+// Filters selects the page of a list and the field it is sorted by, a leading - sorts descending.
+// Lists are paged by page number, or by cursor if Cursor is set, PageSize is then the limit.
+type Filters struct {
+	Page         int
+	PageSize     int
+	Sort         string
+	SortSafelist []string
+	Cursor       bool
+	After        *Cursor // the records after this one, the first page if both are nil
+	Before       *Cursor // the records before this one
+}
+
+// MaxPageSize is the largest page a list returns
+const MaxPageSize = 100
+
+func ValidateFilters(v *validator.Validator, f Filters) {
+	v.Check(f.Page > 0, "page", "must be greater than zero")
+	v.Check(f.Page <= 10_000_000, "page", "must be a maximum of 10 million")
+
+	size := "page_size"
+	if f.Cursor {
+		size = "limit"
+	}
+	v.Check(f.PageSize > 0, size, "must be greater than zero")
+	v.Check(f.PageSize <= MaxPageSize, size, "must be a maximum of 100")
+
+	v.Check(validator.PermittedValue(f.Sort, f.SortSafelist...), "sort", "invalid sort value")
+
+	v.Check(f.After == nil || f.Before == nil, "before", "must not be combined with after")
+	v.Check(f.After == nil || f.After.Sort == f.Sort, "after", "must be a cursor of a list with the same sort")
+	v.Check(f.Before == nil || f.Before.Sort == f.Sort, "before", "must be a cursor of a list with the same sort")
+}
+
+// SortField is the field sorted by, without the direction
+func (f Filters) SortField() string {
+	return strings.TrimPrefix(f.Sort, "-")
+}
+
+// SortColumn maps the field sorted by to its column in the query of a strategy. The sort
+// value must be in the safelist, as the column is written into the query.
+func (f Filters) SortColumn(columns map[string]string) string {
+	column, ok := columns[f.SortField()]
+	if !ok || !slices.Contains(f.SortSafelist, f.Sort) {
+		panic("unsafe sort parameter: " + f.Sort)
+	}
+
+	return column
+}
+
+func (f Filters) SortDirection() string {
+	if strings.HasPrefix(f.Sort, "-") {
+		return "DESC"
+	}
+
+	return "ASC"
+}
+
+func (f Filters) Limit() int {
+	return f.PageSize
+}
+
+func (f Filters) Offset() int {
+	return (f.Page - 1) * f.PageSize
+}
+
+// Metadata describes the page of a list, it is empty if nothing matched. Lists read by cursor
+// have no page numbers or total, but links to the next and previous page if there are any.
+type Metadata struct {
+	CurrentPage  int    `json:"current_page,omitempty"`
+	PageSize     int    `json:"page_size,omitempty"`
+	FirstPage    int    `json:"first_page,omitempty"`
+	LastPage     int    `json:"last_page,omitempty"`
+	TotalRecords int    `json:"total_records,omitempty"`
+	Next         string `json:"next,omitempty"`
+	Previous     string `json:"previous,omitempty"`
+
+	next     *Cursor
+	previous *Cursor
+}
+
+func CalculateMetadata(totalRecords, page, pageSize int) Metadata {
+	if totalRecords == 0 {
+		return Metadata{}
+	}
+
+	return Metadata{
+		CurrentPage:  page,
+		PageSize:     pageSize,
+		FirstPage:    1,
+		LastPage:     int(math.Ceil(float64(totalRecords) / float64(pageSize))),
+		TotalRecords: totalRecords,
+	}
+}
+
+// MovieFilter narrows down a list of movies, zero values match every movie
+type MovieFilter struct {
+	Title    string   // part of the title, case insensitive
+	Genres   []string // genres the movie has all of, up to v2 its single genre must be all of them
+	YearFrom int32
+	YearTo   int32
+	Query    string // words of the title, searched in full text by their prefixes
+}
+
+func ValidateMovieFilter(v *validator.Validator, f MovieFilter) {
+	v.Check(f.YearFrom >= 0, "year_from", "must not be negative")
+	v.Check(f.YearTo >= 0, "year_to", "must not be negative")
+	v.Check(f.YearTo == 0 || f.YearFrom <= f.YearTo, "year_to", "must not be before year_from")
+}
+
+// PersonFilter narrows down a list of people or actors, zero values match everyone
+type PersonFilter struct {
+	Name  string // part of the name, case insensitive
+	Query string // words of the name, searched in full text by their prefixes
+}
@@ -0,0 +1,79 @@
+package repository
+
+import (
+	"slices"
+	"strings"
+)
+
+// Projection selects the fields of a record a query reads and the relations read with it
+type Projection struct {
+	Fields  []string // JSON names of the fields, every field if empty, the id is always read
+	Include []string // names of the relations embedded in the record
+}
+
+// Selects reports whether the field is read
+func (p Projection) Selects(field string) bool {
+	return field == "id" || len(p.Fields) == 0 || slices.Contains(p.Fields, field)
+}
+
+// Includes reports whether the relation is read with the record
+func (p Projection) Includes(relation string) bool {
+	return slices.Contains(p.Include, relation)
+}
+
+// Sorted adds the field a list is sorted by to the fields read, its cursors need it
+func (p Projection) Sorted(f Filters) Projection {
+	if len(p.Fields) > 0 {
+		p.Fields = append(slices.Clip(p.Fields), f.SortField())
+	}
+	return p
+}
+
+// Column is a field of a model T as the queries of a strategy read it
+type Column[T any] struct {
+	Field string       // JSON name of the field, fields without one, like created_at, are read with every field
+	Expr  string       // expression selecting the field
+	Join  string       // table the expression needs joined, if any
+	Dest  func(*T) any // scan destination of the field
+}
+
+// Columns is the set of columns a query reads
+type Columns[T any] []Column[T]
+
+// Select returns the columns of the fields selected by p
+func Select[T any](columns Columns[T], p Projection) Columns[T] {
+	if len(p.Fields) == 0 {
+		return columns
+	}
+
+	var selected Columns[T]
+	for _, c := range columns {
+		if c.Field != "" && p.Selects(c.Field) {
+			selected = append(selected, c)
+		}
+	}
+	return selected
+}
+
+// List returns the select list of the columns
+func (columns Columns[T]) List() string {
+	exprs := make([]string, len(columns))
+	for i, c := range columns {
+		exprs[i] = c.Expr
+	}
+	return strings.Join(exprs, ", ")
+}
+
+// Dest returns the scan destinations of the columns in record
+func (columns Columns[T]) Dest(record *T) []any {
+	dest := make([]any, len(columns))
+	for i, c := range columns {
+		dest[i] = c.Dest(record)
+	}
+	return dest
+}
+
+// Joins reports whether any of the columns needs table joined
+func (columns Columns[T]) Joins(table string) bool {
+	return slices.ContainsFunc(columns, func(c Column[T]) bool { return c.Join == table })
+}
@@ -0,0 +1,136 @@
+package repository
+
+import (
+	"errors"
+
+	v1 "thesis.lefler.eu/internal/model/v1"
+	v2 "thesis.lefler.eu/internal/model/v2"
+	v3 "thesis.lefler.eu/internal/model/v3"
+	v4 "thesis.lefler.eu/internal/model/v4"
+	v5 "thesis.lefler.eu/internal/model/v5"
+)
+
+// Errors returned by every implementation, so handlers do not depend on the strategy
+var (
+	ErrRecordNotFound = errors.New("record not found")
+	ErrEditConflict   = errors.New("edit conflict")
+)
+
+type MovieRepositoryV1 interface {
+	Insert(movie *v1.Movie) error
+	Get(id int64) (*v1.Movie, error)
+	GetAll(filter MovieFilter, filters Filters) ([]*v1.Movie, Metadata, error)
+	Update(movie *v1.Movie) error
+	Delete(id int64) error
+}
+
+type MovieRepositoryV2 interface {
+	Insert(movie *v2.Movie) error
+	Get(id int64) (*v2.Movie, error)
+	GetAll(filter MovieFilter, filters Filters) ([]*v2.Movie, Metadata, error)
+	Update(movie *v2.Movie) error
+	Delete(id int64) error
+}
+
+type MovieRepositoryV3 interface {
+	Insert(movie *v3.Movie) error
+	Get(id int64) (*v3.Movie, error)
+	GetAll(filter MovieFilter, filters Filters) ([]*v3.Movie, Metadata, error)
+	Update(movie *v3.Movie) error
+	Delete(id int64) error
+}
+
+type MovieRepositoryV4 interface {
+	Insert(movie *v4.Movie) error
+	Get(id int64) (*v4.Movie, error)
+	GetAll(filter MovieFilter, filters Filters) ([]*v4.Movie, Metadata, error)
+	Update(movie *v4.Movie) error
+	Delete(id int64) error
+}
+
+type ActorRepositoryV4 interface {
+	Insert(actor *v4.Actor) error
+	Get(id int64) (*v4.Actor, error)
+	GetAll(filter PersonFilter, filters Filters) ([]*v4.Actor, Metadata, error)
+	Update(actor *v4.Actor) error
+	Delete(id int64) error
+}
+
+// MovieActorRepositoryV4 stores the actors of a movie, replaced as a whole with the movie or one
+// by one. Update changes the role, it and Delete return ErrEditConflict unless the row is still
+// at its version.
+type MovieActorRepositoryV4 interface {
+	Insert(movieActor *v4.MovieActor) error
+	Get(movieID int64, actorID int64) (*v4.MovieActor, error)
+	GetForMovie(movieID int64) ([]*v4.MovieActor, error)
+	Update(movieActor *v4.MovieActor) error
+	Delete(movieActor *v4.MovieActor) error
+	DeleteForMovie(movieID int64) error
+}
+
+// MovieRepositoryV5 stores movies, Insert and Update expect the crew of the movie to be set
+// with person names, as some strategies still write the director of v2 to v4. Update leaves the
+// director alone when the crew is nil, as it is when a client does not replace it.
+type MovieRepositoryV5 interface {
+	Insert(movie *v5.Movie) error
+	Get(id int64) (*v5.Movie, error)
+	Select(id int64, projection Projection) (*v5.Movie, error) // reads only the fields of projection
+	GetAll(filter MovieFilter, filters Filters, projection Projection) ([]*v5.Movie, Metadata, error)
+	Update(movie *v5.Movie) error
+	Delete(id int64) error
+}
+
+type PersonRepositoryV5 interface {
+	Insert(person *v5.Person) error
+	Get(id int64) (*v5.Person, error)
+	GetAll(filter PersonFilter, filters Filters) ([]*v5.Person, Metadata, error)
+	Update(person *v5.Person) error
+	Delete(id int64) error
+}
+
+// CrewRepositoryV5 stores the crew of a movie, replaced as a whole with the movie or one member
+// at a time. A member is identified by the movie, the person and the crew type, as a person can
+// have several jobs in a movie. Update changes the role, it and Delete return ErrEditConflict
+// unless the member is still at its version.
+type CrewRepositoryV5 interface {
+	Insert(crew *v5.Crew) error
+	Get(movieID int64, personID int64, crewType string) (*v5.Crew, error)
+	GetForMovie(movieID int64) ([]*v5.Crew, error)
+	GetForMovies(movieIDs []int64, names bool) ([]*v5.Crew, error) // names reads the names of the people
+	Update(crew *v5.Crew) error
+	Delete(crew *v5.Crew) error
+	DeleteForMovie(movieID int64) error
+}
+
+type RepositoriesV1 struct {
+	Movies MovieRepositoryV1
+}
+
+type RepositoriesV2 struct {
+	Movies MovieRepositoryV2
+}
+
+type RepositoriesV3 struct {
+	Movies MovieRepositoryV3
+}
+
+type RepositoriesV4 struct {
+	Movies      MovieRepositoryV4
+	Actors      ActorRepositoryV4
+	MovieActors MovieActorRepositoryV4
+}
+
+type RepositoriesV5 struct {
+	Movies MovieRepositoryV5
+	People PersonRepositoryV5
+	Crew   CrewRepositoryV5
+}
+
+// Repositories of every version, as implemented by a strategy
+type Repositories struct {
+	V1 RepositoriesV1
+	V2 RepositoriesV2
+	V3 RepositoriesV3
+	V4 RepositoriesV4
+	V5 RepositoriesV5
+}
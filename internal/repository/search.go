@@ -0,0 +1,40 @@
+package repository
+
+import (
+	"strings"
+	"unicode"
+
+	"thesis.lefler.eu/internal/validator"
+)
+
+// SearchTerms splits a search into its lowercase words, anything but letters and digits
+// separates them
+func SearchTerms(q string) []string {
+	return strings.FieldsFunc(strings.ToLower(q), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// SearchQuery returns the search as a tsquery for the 'simple' configuration, matching
+// records with every word as the prefix of a word, so partial words match. It is empty
+// if the search has no words.
+func SearchQuery(q string) string {
+	terms := SearchTerms(q)
+	for i, term := range terms {
+		terms[i] = term + ":*"
+	}
+
+	return strings.Join(terms, " & ")
+}
+
+// ValidateSearch checks the search of a list and its sort. Only searched lists can be sorted
+// by relevance, and they cannot be paged by cursor then, as the rank is no field of a record.
+func ValidateSearch(v *validator.Validator, q string, f Filters) {
+	v.Check(q == "" || SearchQuery(q) != "", "q", "must contain a letter or digit")
+	v.Check(len(q) <= 500, "q", "must not be more than 500 bytes long")
+
+	if f.Sort == "relevance" {
+		v.Check(q != "", "sort", "must be combined with q to sort by relevance")
+		v.Check(!f.Cursor, "sort", "relevance cannot be paged by cursor")
+	}
+}
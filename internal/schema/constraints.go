@@ -0,0 +1,72 @@
+package schema
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Constraints are the validation rules of a field in a structured form
+type Constraints struct {
+	Required  bool     // the field must be sent on create
+	Unique    bool     // array elements must not repeat
+	Enum      []string // permitted values
+	MaxLength *int
+	Minimum   *int
+	MinItems  *int
+	MaxItems  *int
+	Other     []string // rules without a structured form, e.g. "must not be in the future"
+}
+
+var (
+	maxLengthRX = regexp.MustCompile(`^must not be more than (\d+) bytes long$`)
+	minimumRX   = regexp.MustCompile(`^must be greater than (\d+)$`)
+	minItemsRX  = regexp.MustCompile(`^must contain at least (\d+)`)
+	maxItemsRX  = regexp.MustCompile(`^must not contain more than (\d+)`)
+	enumRX      = regexp.MustCompile(`'([^']*)'`)
+)
+
+// Constraints parses the rules of f, the messages passed to validator.Check
+func (f Field) Constraints() Constraints {
+	var c Constraints
+
+	for _, rule := range f.Rules {
+		switch {
+		case rule == "must be provided":
+			c.Required = true
+		case rule == "must be a positive integer":
+			// zero, the value of a missing field, fails the check as well
+			c.Required = true
+			c.Minimum = number(1)
+		case rule == "is not valid":
+			// covered by the format of the field
+		case rule == "must not contain duplicate values":
+			c.Unique = true
+		case strings.HasPrefix(rule, "must be either"):
+			for _, match := range enumRX.FindAllStringSubmatch(rule, -1) {
+				c.Enum = append(c.Enum, match[1])
+			}
+		case maxLengthRX.MatchString(rule):
+			c.MaxLength = parse(maxLengthRX, rule)
+		case minimumRX.MatchString(rule):
+			c.Minimum = parse(minimumRX, rule)
+		case minItemsRX.MatchString(rule):
+			c.MinItems = parse(minItemsRX, rule)
+		case maxItemsRX.MatchString(rule):
+			c.MaxItems = parse(maxItemsRX, rule)
+		default:
+			c.Other = append(c.Other, rule)
+		}
+	}
+
+	return c
+}
+
+func parse(rx *regexp.Regexp, rule string) *int {
+	n, _ := strconv.Atoi(rx.FindStringSubmatch(rule)[1])
+	return &n
+}
+
+func number(n int) *int {
+	return &n
+}
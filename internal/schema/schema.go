@@ -0,0 +1,198 @@
+package schema
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/civil"
+
+	"thesis.lefler.eu/internal/validator"
+)
+
+// Type describes the JSON representation of a Go value
+type Type struct {
+	Name     string  `json:"-"`                  // Go type name of structs, e.g. Movie
+	Kind     string  `json:"type"`               // JSON type: string, integer, number, boolean, array or object
+	Format   string  `json:"format,omitempty"`   // int32, int64, date or date-time
+	Nullable bool    `json:"nullable,omitempty"` // true for pointers, which are null for records written by older versions
+	Items    *Type   `json:"items,omitempty"`    // element type of arrays
+	Fields   []Field `json:"fields,omitempty"`   // properties of objects, in struct order
+}
+
+// Field is a single JSON property of a struct
+type Field struct {
+	Name     string   `json:"name"`
+	Optional bool     `json:"optional,omitempty"` // omitted from responses when empty
+	Rules    []string `json:"rules,omitempty"`    // validation messages enforced on writes
+	Type
+}
+
+var (
+	timeType  = reflect.TypeOf(time.Time{})
+	civilDate = reflect.TypeOf(civil.Date{})
+)
+
+// Of returns the JSON representation of v, usually a data struct such as v5.Movie
+func Of(v any) Type {
+	return typeOf(reflect.TypeOf(v))
+}
+
+// Validated returns the JSON representation of T with the rules enforced by validate
+// attached to its fields. Nested applies the rules of another Validate function to the
+// elements of an array field, e.g. the crew of a movie.
+func Validated[T any](validate func(*validator.Validator, *T), options ...Option) Type {
+	var zero T
+
+	typ := Of(zero)
+	typ.attach(rulesOf(validate))
+
+	for _, option := range options {
+		option(&typ)
+	}
+
+	return typ
+}
+
+type Option func(*Type)
+
+// Nested attaches the rules of validate to the elements of the array field name
+func Nested[T any](name string, validate func(*validator.Validator, *T)) Option {
+	return func(typ *Type) {
+		for i := range typ.Fields {
+			if typ.Fields[i].Name == name && typ.Fields[i].Items != nil {
+				typ.Fields[i].Items.attach(rulesOf(validate))
+			}
+		}
+	}
+}
+
+// Served appends a field that replies carry next to the value of T without T holding it, e.g.
+// the version of a crew member, which is kept out of its JSON
+func Served[T any](name string) Option {
+	return func(typ *Type) {
+		var zero T
+		typ.Fields = append(typ.Fields, Field{Name: name, Type: Of(zero)})
+	}
+}
+
+func (t *Type) attach(rules map[string][]string) {
+	for i := range t.Fields {
+		t.Fields[i].Rules = append(t.Fields[i].Rules, rules[t.Fields[i].Name]...)
+	}
+}
+
+// rulesOf runs validate once on a probe value with every pointer field set, so validate
+// can dereference them, and returns the message of every check it performed
+func rulesOf[T any](validate func(*validator.Validator, *T)) (rules map[string][]string) {
+	v := validator.NewRecorder()
+
+	probe := new(T)
+	value := reflect.ValueOf(probe).Elem()
+
+	if value.Kind() == reflect.Struct {
+		for i := 0; i < value.NumField(); i++ {
+			field := value.Field(i)
+			if field.Kind() == reflect.Pointer && field.CanSet() {
+				field.Set(reflect.New(field.Type().Elem()))
+			}
+		}
+	}
+
+	defer func() {
+		// rules checked before a panic are still worth reporting
+		if err := recover(); err != nil {
+			v.Rules["_"] = append(v.Rules["_"], fmt.Sprintf("validation could not be fully inspected: %v", err))
+		}
+		rules = v.Rules
+	}()
+
+	validate(v, probe)
+
+	return v.Rules
+}
+
+// Find returns the field with the given JSON name
+func (t Type) Find(name string) (Field, bool) {
+	for _, field := range t.Fields {
+		if field.Name == name {
+			return field, true
+		}
+	}
+	return Field{}, false
+}
+
+func typeOf(t reflect.Type) Type {
+	nullable := false
+	for t.Kind() == reflect.Pointer {
+		nullable = true
+		t = t.Elem()
+	}
+
+	typ := Type{Nullable: nullable}
+
+	switch {
+	case t == timeType:
+		typ.Kind, typ.Format = "string", "date-time"
+		return typ
+	case t == civilDate:
+		typ.Kind, typ.Format = "string", "date"
+		return typ
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		typ.Kind = "string"
+	case reflect.Bool:
+		typ.Kind = "boolean"
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8, reflect.Uint16:
+		typ.Kind, typ.Format = "integer", "int32"
+	case reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint32, reflect.Uint64:
+		typ.Kind, typ.Format = "integer", "int64"
+	case reflect.Float32, reflect.Float64:
+		typ.Kind = "number"
+	case reflect.Slice, reflect.Array:
+		items := typeOf(t.Elem())
+		items.Nullable = false // slices of pointers never hold null
+		typ.Kind, typ.Items = "array", &items
+	case reflect.Struct:
+		typ.Kind, typ.Name, typ.Fields = "object", t.Name(), fieldsOf(t)
+	default:
+		typ.Kind = "object"
+	}
+
+	return typ
+}
+
+func fieldsOf(t reflect.Type) []Field {
+	fields := []Field{}
+
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+
+		tag := sf.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+
+		name, options, _ := strings.Cut(tag, ",")
+		if name == "" {
+			name = sf.Name
+		}
+
+		fields = append(fields, Field{
+			Name:     name,
+			Optional: strings.Contains(options, "omitempty"),
+			Type:     typeOf(sf.Type),
+		})
+	}
+
+	return fields
+}
+
+// Versions describes the resources of every version of a strategy, by version and resource name
+type Versions map[string]map[string]Type
@@ -0,0 +1,180 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+)
+
+// Redundancy is data a strategy stores twice, e.g. the genre of a movie next to its genres
+type Redundancy struct {
+	Check       string `json:"check"`
+	Description string `json:"description"`
+	Rows        int64  `json:"rows"`      // compared by the check
+	Redundant   int64  `json:"redundant"` // rows whose data is duplicated elsewhere
+	Bytes       int64  `json:"bytes"`     // taken by the duplicated values
+}
+
+// check finds duplicated data, it only runs on databases that have all the tables and
+// columns it requires. Its query returns the rows compared, the redundant ones and their size.
+type check struct {
+	name        string
+	description string
+	requires    []string // table or table.column
+	query       string
+}
+
+var checks = []check{
+	{
+		name:        "genre_genres",
+		description: "movies.genre duplicates movies.genres[1]",
+		requires:    []string{"movies.genre", "movies.genres"},
+		query: `
+			SELECT count(*), count(*) FILTER (WHERE genre = genres[1]),
+				coalesce(sum(pg_column_size(genre)) FILTER (WHERE genre = genres[1]), 0)
+			FROM movies
+			WHERE genre IS NOT NULL`,
+	},
+	{
+		name:        "genre_branch_v3",
+		description: "movies_branch_v3.genres[1] duplicates movies.genre",
+		requires:    []string{"movies.genre", "movies_branch_v3.genres"},
+		query: `
+			SELECT count(*), count(*) FILTER (WHERE m.genre = b.genres[1]),
+				coalesce(sum(pg_column_size(b.genres)) FILTER (WHERE m.genre = b.genres[1]), 0)
+			FROM movies m
+			JOIN movies_branch_v3 b ON b.id = m.id
+			WHERE m.genre IS NOT NULL`,
+	},
+	{
+		name:        "director_crew",
+		description: "movies.director duplicates the name of the Director in crew",
+		requires:    []string{"movies.director", "crew", "people"},
+		query: `
+			SELECT count(*), count(*) FILTER (WHERE d.duplicate),
+				coalesce(sum(pg_column_size(m.director)) FILTER (WHERE d.duplicate), 0)
+			FROM movies m
+			CROSS JOIN LATERAL (
+				SELECT EXISTS (
+					SELECT 1 FROM crew c JOIN people p ON p.id = c.person_id
+					WHERE c.movie_id = m.id AND c.crew_type = 'Director' AND p.name = m.director
+				) AS duplicate
+			) d
+			WHERE m.director IS NOT NULL`,
+	},
+	{
+		name:        "director_branch_v2",
+		description: "movies_branch_v2.director duplicates the name of the Director in crew",
+		requires:    []string{"movies_branch_v2.director", "crew", "people"},
+		query: `
+			SELECT count(*), count(*) FILTER (WHERE d.duplicate),
+				coalesce(sum(pg_column_size(b.director)) FILTER (WHERE d.duplicate), 0)
+			FROM movies_branch_v2 b
+			CROSS JOIN LATERAL (
+				SELECT EXISTS (
+					SELECT 1 FROM crew c JOIN people p ON p.id = c.person_id
+					WHERE c.movie_id = b.id AND c.crew_type = 'Director' AND p.name = b.director
+				) AS duplicate
+			) d
+			WHERE b.director IS NOT NULL`,
+	},
+	{
+		name:        "actors_people",
+		description: "actors duplicates people with the same name and birthdate",
+		requires:    []string{"actors", "people"},
+		query: `
+			SELECT count(*), count(*) FILTER (WHERE d.duplicate),
+				coalesce(sum(pg_column_size(a.*)) FILTER (WHERE d.duplicate), 0)
+			FROM actors a
+			CROSS JOIN LATERAL (
+				SELECT EXISTS (
+					SELECT 1 FROM people p
+					WHERE p.name = a.name AND p.birthdate IS NOT DISTINCT FROM a.birthdate
+				) AS duplicate
+			) d`,
+	},
+	{
+		name:        "movie_actors_crew",
+		description: "movie_actors duplicates the Actor rows of crew",
+		requires:    []string{"movie_actors", "actors", "crew", "people"},
+		query: `
+			SELECT count(*), count(*) FILTER (WHERE d.duplicate),
+				coalesce(sum(pg_column_size(ma.*)) FILTER (WHERE d.duplicate), 0)
+			FROM movie_actors ma
+			JOIN actors a ON a.id = ma.actor_id
+			CROSS JOIN LATERAL (
+				SELECT EXISTS (
+					SELECT 1 FROM crew c JOIN people p ON p.id = c.person_id
+					WHERE c.movie_id = ma.movie_id AND c.crew_type = 'Actor' AND p.name = a.name
+				) AS duplicate
+			) d`,
+	},
+}
+
+// redundancy runs every check that applies to the schema of db
+func redundancy(ctx context.Context, db *sql.DB) ([]Redundancy, error) {
+	schema, err := columns(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+
+	results := []Redundancy{}
+
+	for _, c := range checks {
+		applies := true
+		for _, required := range c.requires {
+			if !schema[required] {
+				applies = false
+				break
+			}
+		}
+		if !applies {
+			continue
+		}
+
+		r := Redundancy{Check: c.name, Description: c.description}
+
+		err := db.QueryRowContext(ctx, c.query).Scan(&r.Rows, &r.Redundant, &r.Bytes)
+		if err != nil {
+			return nil, err
+		}
+
+		results = append(results, r)
+	}
+
+	return results, nil
+}
+
+// columns returns the tables and table.columns of the public schema, views excluded
+func columns(ctx context.Context, db *sql.DB) (map[string]bool, error) {
+	query := `
+		SELECT c.table_name, c.column_name
+		FROM information_schema.columns c
+		JOIN information_schema.tables t ON t.table_schema = c.table_schema AND t.table_name = c.table_name
+		WHERE c.table_schema = 'public' AND t.table_type = 'BASE TABLE'`
+
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	schema := make(map[string]bool)
+
+	for rows.Next() {
+		var table, column string
+
+		err := rows.Scan(&table, &column)
+		if err != nil {
+			return nil, err
+		}
+
+		schema[table] = true
+		schema[table+"."+column] = true
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return schema, nil
+}
@@ -0,0 +1,215 @@
+package storage
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"fmt"
+	"slices"
+
+	"github.com/lib/pq"
+
+	"thesis.lefler.eu/internal/migration"
+)
+
+// excluded tables are bookkeeping of the tooling, not data of a strategy
+var excluded = []string{migration.TableName, migration.CostTable, "api_usage", "api_usage_clients"}
+
+// Report compares the storage of the strategy databases
+type Report struct {
+	Strategies []Strategy        `json:"strategies"`
+	Tables     []TableComparison `json:"tables"`
+}
+
+// Strategy is the storage of one strategy database
+type Strategy struct {
+	Strategy       string       `json:"strategy"`
+	TotalBytes     int64        `json:"total_bytes"`
+	TableBytes     int64        `json:"table_bytes"`
+	IndexBytes     int64        `json:"index_bytes"`
+	ToastBytes     int64        `json:"toast_bytes"`
+	Rows           int64        `json:"rows"`
+	DeadTuples     int64        `json:"dead_tuples"`
+	RedundantBytes int64        `json:"redundant_bytes"`
+	Tables         []Table      `json:"tables"`
+	Redundancy     []Redundancy `json:"redundancy"`
+}
+
+// Table is the storage of a single table, TotalBytes includes its indexes and TOAST
+type Table struct {
+	Name       string  `json:"name"`
+	TotalBytes int64   `json:"total_bytes"`
+	TableBytes int64   `json:"table_bytes"`
+	IndexBytes int64   `json:"index_bytes"`
+	ToastBytes int64   `json:"toast_bytes"`
+	Rows       int64   `json:"rows"`
+	DeadTuples int64   `json:"dead_tuples"` // as last counted by the statistics collector
+	Indexes    []Index `json:"indexes"`
+}
+
+type Index struct {
+	Name  string `json:"name"`
+	Bytes int64  `json:"bytes"`
+}
+
+// TableComparison is the total size of a table in every strategy database that has it
+type TableComparison struct {
+	Table      string           `json:"table"`
+	TotalBytes map[string]int64 `json:"total_bytes"`
+	Rows       map[string]int64 `json:"rows"`
+}
+
+// Collect measures the storage of every strategy database, in the order of strategies
+func Collect(ctx context.Context, dbs map[string]*sql.DB, strategies []string) (Report, error) {
+	report := Report{Strategies: []Strategy{}, Tables: []TableComparison{}}
+
+	comparisons := make(map[string]*TableComparison)
+
+	for _, strategy := range strategies {
+		s, err := Measure(ctx, dbs[strategy], strategy)
+		if err != nil {
+			return Report{}, fmt.Errorf("measuring %s storage: %w", strategy, err)
+		}
+
+		report.Strategies = append(report.Strategies, s)
+
+		for _, table := range s.Tables {
+			c, ok := comparisons[table.Name]
+			if !ok {
+				c = &TableComparison{Table: table.Name, TotalBytes: make(map[string]int64), Rows: make(map[string]int64)}
+				comparisons[table.Name] = c
+			}
+			c.TotalBytes[strategy] = table.TotalBytes
+			c.Rows[strategy] = table.Rows
+		}
+	}
+
+	for _, c := range comparisons {
+		report.Tables = append(report.Tables, *c)
+	}
+	slices.SortFunc(report.Tables, func(a, b TableComparison) int { return cmp.Compare(a.Table, b.Table) })
+
+	return report, nil
+}
+
+// Measure reads the size, row count and dead tuples of every table of a strategy database
+// and how much of its data is redundant
+func Measure(ctx context.Context, db *sql.DB, strategy string) (Strategy, error) {
+	s := Strategy{Strategy: strategy}
+
+	tables, err := tables(ctx, db)
+	if err != nil {
+		return Strategy{}, err
+	}
+
+	for _, table := range tables {
+		s.TotalBytes += table.TotalBytes
+		s.TableBytes += table.TableBytes
+		s.IndexBytes += table.IndexBytes
+		s.ToastBytes += table.ToastBytes
+		s.Rows += table.Rows
+		s.DeadTuples += table.DeadTuples
+	}
+	s.Tables = tables
+
+	s.Redundancy, err = redundancy(ctx, db)
+	if err != nil {
+		return Strategy{}, err
+	}
+
+	for _, r := range s.Redundancy {
+		s.RedundantBytes += r.Bytes
+	}
+
+	return s, nil
+}
+
+func tables(ctx context.Context, db *sql.DB) ([]Table, error) {
+	query := `
+		SELECT c.relname, pg_total_relation_size(c.oid), pg_relation_size(c.oid), pg_indexes_size(c.oid),
+			coalesce(s.n_dead_tup, 0)
+		FROM pg_class c
+		JOIN pg_namespace n ON n.oid = c.relnamespace
+		LEFT JOIN pg_stat_user_tables s ON s.relid = c.oid
+		WHERE c.relkind IN ('r', 'm', 'p') AND n.nspname = 'public' AND NOT c.relname = ANY($1)
+		ORDER BY c.relname`
+
+	rows, err := db.QueryContext(ctx, query, pq.Array(excluded))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tables := []Table{}
+
+	for rows.Next() {
+		var table Table
+
+		err := rows.Scan(&table.Name, &table.TotalBytes, &table.TableBytes, &table.IndexBytes, &table.DeadTuples)
+		if err != nil {
+			return nil, err
+		}
+
+		table.ToastBytes = table.TotalBytes - table.TableBytes - table.IndexBytes
+		table.Indexes = []Index{}
+
+		tables = append(tables, table)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := range tables {
+		// exact counts, the estimates of the planner lag behind after bulk migrations
+		query := fmt.Sprintf(`SELECT count(*) FROM %s`, pq.QuoteIdentifier(tables[i].Name))
+
+		err := db.QueryRowContext(ctx, query).Scan(&tables[i].Rows)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = indexes(ctx, db, tables)
+	if err != nil {
+		return nil, err
+	}
+
+	return tables, nil
+}
+
+func indexes(ctx context.Context, db *sql.DB, tables []Table) error {
+	query := `
+		SELECT t.relname, ic.relname, pg_relation_size(ic.oid)
+		FROM pg_index i
+		JOIN pg_class ic ON ic.oid = i.indexrelid
+		JOIN pg_class t ON t.oid = i.indrelid
+		JOIN pg_namespace n ON n.oid = t.relnamespace
+		WHERE n.nspname = 'public'
+		ORDER BY t.relname, ic.relname`
+
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			table string
+			index Index
+		)
+
+		err := rows.Scan(&table, &index.Name, &index.Bytes)
+		if err != nil {
+			return err
+		}
+
+		i := slices.IndexFunc(tables, func(t Table) bool { return t.Name == table })
+		if i >= 0 {
+			tables[i].Indexes = append(tables[i].Indexes, index)
+		}
+	}
+
+	return rows.Err()
+}
@@ -0,0 +1,181 @@
+package telemetry
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// Store persists the usage of every strategy into a table of that strategy's own database
+type Store struct {
+	DBs map[string]*sql.DB
+}
+
+// Load restores the persisted usage into rec. The usage tables are created by migration
+// 00002_api_usage.sql of every strategy.
+func (s Store) Load(rec *Recorder) error {
+	for strategy, db := range s.DBs {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		err := s.load(ctx, db, strategy, rec)
+		cancel()
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s Store) load(ctx context.Context, db *sql.DB, strategy string, rec *Recorder) error {
+	var exists bool
+
+	err := db.QueryRowContext(ctx, `SELECT to_regclass('api_usage') IS NOT NULL`).Scan(&exists)
+	if err != nil {
+		return err
+	}
+
+	if !exists {
+		return fmt.Errorf("%s database has no api_usage table, apply its migrations first", strategy)
+	}
+
+	query := `
+		SELECT version, resource, method, requests
+		FROM api_usage
+		WHERE strategy = $1`
+
+	rows, err := db.QueryContext(ctx, query, strategy)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+
+	for rows.Next() {
+		key := Key{Strategy: strategy}
+		var requests int64
+
+		err := rows.Scan(&key.Version, &key.Resource, &key.Method, &requests)
+		if err != nil {
+			return err
+		}
+
+		rec.requests[key] += requests
+	}
+
+	if err = rows.Err(); err != nil {
+		return err
+	}
+
+	query = `
+		SELECT version, client_id, last_seen
+		FROM api_usage_clients
+		WHERE strategy = $1`
+
+	clientRows, err := db.QueryContext(ctx, query, strategy)
+	if err != nil {
+		return err
+	}
+	defer clientRows.Close()
+
+	for clientRows.Next() {
+		var (
+			version  string
+			client   string
+			lastSeen time.Time
+		)
+
+		err := clientRows.Scan(&version, &client, &lastSeen)
+		if err != nil {
+			return err
+		}
+
+		rec.seen(versionKey{strategy, version}, client, lastSeen)
+	}
+
+	return clientRows.Err()
+}
+
+// Flush writes the usage recorded since the previous flush
+func (s Store) Flush(rec *Recorder) error {
+	rec.mu.Lock()
+	requests := rec.pendingRequests
+	clients := rec.pendingClients
+	rec.pendingRequests = make(map[Key]int64)
+	rec.pendingClients = make(map[versionKey]map[string]time.Time)
+	rec.mu.Unlock()
+
+	for key, count := range requests {
+		db, ok := s.DBs[key.Strategy]
+		if !ok {
+			continue
+		}
+
+		query := `
+			INSERT INTO api_usage (strategy, version, resource, method, requests)
+			VALUES ($1, $2, $3, $4, $5)
+			ON CONFLICT (strategy, version, resource, method) DO UPDATE
+				SET requests = api_usage.requests + EXCLUDED.requests`
+
+		ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+		_, err := db.ExecContext(ctx, query, key.Strategy, key.Version, key.Resource, key.Method, count)
+		cancel()
+
+		if err != nil {
+			rec.restore(requests, clients)
+			return err
+		}
+		delete(requests, key)
+	}
+
+	for vk, seen := range clients {
+		db, ok := s.DBs[vk.strategy]
+		if !ok {
+			continue
+		}
+
+		for client, at := range seen {
+			query := `
+				INSERT INTO api_usage_clients (strategy, version, client_id, last_seen)
+				VALUES ($1, $2, $3, $4)
+				ON CONFLICT (strategy, version, client_id) DO UPDATE
+					SET last_seen = GREATEST(api_usage_clients.last_seen, EXCLUDED.last_seen)`
+
+			ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+			_, err := db.ExecContext(ctx, query, vk.strategy, vk.version, client, at)
+			cancel()
+
+			if err != nil {
+				rec.restore(requests, clients)
+				return err
+			}
+			delete(seen, client)
+		}
+	}
+
+	return nil
+}
+
+// restore puts usage that could not be flushed back, so it is retried on the next flush
+func (rec *Recorder) restore(requests map[Key]int64, clients map[versionKey]map[string]time.Time) {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+
+	for key, count := range requests {
+		rec.pendingRequests[key] += count
+	}
+
+	for vk, seen := range clients {
+		if rec.pendingClients[vk] == nil {
+			rec.pendingClients[vk] = make(map[string]time.Time)
+		}
+		for client, at := range seen {
+			if at.After(rec.pendingClients[vk][client]) {
+				rec.pendingClients[vk][client] = at
+			}
+		}
+	}
+}
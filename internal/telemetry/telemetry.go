@@ -0,0 +1,202 @@
+package telemetry
+
+import (
+	"net"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ClientHeader identifies a client, requests without it are attributed to their remote address
+const ClientHeader = "X-Client-ID"
+
+// MaxClients bounds the distinct clients tracked per strategy and version, as clients choose
+// their own ID. Requests of further clients are still counted, but not the clients themselves.
+const MaxClients = 10000
+
+type Key struct {
+	Strategy string
+	Version  string
+	Resource string
+	Method   string
+}
+
+type versionKey struct {
+	strategy string
+	version  string
+}
+
+type versionUsage struct {
+	lastSeen time.Time
+	clients  map[string]time.Time
+	capped   bool // a client was not tracked as MaxClients were
+}
+
+// Recorder counts requests per strategy, version, resource and method, and the distinct
+// clients and last request time per strategy and version
+type Recorder struct {
+	mu       sync.Mutex
+	requests map[Key]int64
+	versions map[versionKey]*versionUsage
+
+	// changes since the last flush, so several API instances can share one table
+	pendingRequests map[Key]int64
+	pendingClients  map[versionKey]map[string]time.Time
+}
+
+func NewRecorder() *Recorder {
+	return &Recorder{
+		requests:        make(map[Key]int64),
+		versions:        make(map[versionKey]*versionUsage),
+		pendingRequests: make(map[Key]int64),
+		pendingClients:  make(map[versionKey]map[string]time.Time),
+	}
+}
+
+func (rec *Recorder) Record(key Key, client string, at time.Time) {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+
+	rec.requests[key]++
+	rec.pendingRequests[key]++
+
+	vk := versionKey{key.Strategy, key.Version}
+	if !rec.seen(vk, client, at) {
+		return
+	}
+
+	if rec.pendingClients[vk] == nil {
+		rec.pendingClients[vk] = make(map[string]time.Time)
+	}
+	rec.pendingClients[vk][client] = at
+}
+
+// seen updates the last request time of the version and of the client, it returns false if the
+// client is not tracked as the version has MaxClients already
+func (rec *Recorder) seen(vk versionKey, client string, at time.Time) bool {
+	usage, ok := rec.versions[vk]
+	if !ok {
+		usage = &versionUsage{clients: make(map[string]time.Time)}
+		rec.versions[vk] = usage
+	}
+
+	if at.After(usage.lastSeen) {
+		usage.lastSeen = at
+	}
+
+	last, ok := usage.clients[client]
+	if !ok && len(usage.clients) >= MaxClients {
+		usage.capped = true
+		return false
+	}
+	if at.After(last) {
+		usage.clients[client] = at
+	}
+
+	return true
+}
+
+// Middleware records every request passing through next under the given strategy, version and resource
+func (rec *Recorder) Middleware(strategy string, version string, resource string) func(http.HandlerFunc) http.HandlerFunc {
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			rec.Record(Key{
+				Strategy: strategy,
+				Version:  version,
+				Resource: resource,
+				Method:   r.Method,
+			}, ClientID(r), time.Now())
+
+			next(w, r)
+		}
+	}
+}
+
+// ClientID returns the X-Client-ID header, or the remote host if it is not set
+func ClientID(r *http.Request) string {
+	if id := r.Header.Get(ClientHeader); id != "" {
+		return id
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	return host
+}
+
+type ResourceUsage struct {
+	Resource string `json:"resource"`
+	Method   string `json:"method"`
+	Requests int64  `json:"requests"`
+}
+
+type VersionUsage struct {
+	Strategy        string          `json:"strategy"`
+	Version         string          `json:"version"`
+	Requests        int64           `json:"requests"`
+	DistinctClients int             `json:"distinct_clients"`
+	ClientsCapped   bool            `json:"clients_capped,omitempty"` // more clients than MaxClients were seen
+	LastSeen        time.Time       `json:"last_seen"`
+	Resources       []ResourceUsage `json:"resources"`
+}
+
+// Snapshot returns the usage of every version seen so far, ordered by strategy and version
+func (rec *Recorder) Snapshot() []VersionUsage {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+
+	byVersion := make(map[versionKey]*VersionUsage, len(rec.versions))
+
+	for vk, usage := range rec.versions {
+		byVersion[vk] = &VersionUsage{
+			Strategy:        vk.strategy,
+			Version:         vk.version,
+			DistinctClients: len(usage.clients),
+			ClientsCapped:   usage.capped,
+			LastSeen:        usage.lastSeen,
+			Resources:       []ResourceUsage{},
+		}
+	}
+
+	for key, count := range rec.requests {
+		vk := versionKey{key.Strategy, key.Version}
+
+		usage, ok := byVersion[vk]
+		if !ok {
+			usage = &VersionUsage{Strategy: key.Strategy, Version: key.Version, Resources: []ResourceUsage{}}
+			byVersion[vk] = usage
+		}
+
+		usage.Requests += count
+		usage.Resources = append(usage.Resources, ResourceUsage{
+			Resource: key.Resource,
+			Method:   key.Method,
+			Requests: count,
+		})
+	}
+
+	snapshot := make([]VersionUsage, 0, len(byVersion))
+
+	for _, usage := range byVersion {
+		sort.Slice(usage.Resources, func(i, j int) bool {
+			if usage.Resources[i].Resource != usage.Resources[j].Resource {
+				return usage.Resources[i].Resource < usage.Resources[j].Resource
+			}
+			return usage.Resources[i].Method < usage.Resources[j].Method
+		})
+
+		snapshot = append(snapshot, *usage)
+	}
+
+	sort.Slice(snapshot, func(i, j int) bool {
+		if snapshot[i].Strategy != snapshot[j].Strategy {
+			return snapshot[i].Strategy < snapshot[j].Strategy
+		}
+		return snapshot[i].Version < snapshot[j].Version
+	})
+
+	return snapshot
+}
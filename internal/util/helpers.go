@@ -10,6 +10,7 @@ import (
 	"strconv"
 	"strings"
 
+	"thesis.lefler.eu/internal/repository"
 	"thesis.lefler.eu/internal/validator"
 
 	"github.com/julienschmidt/httprouter"
@@ -18,16 +19,24 @@ import (
 type Envelope map[string]any
 
 func ReadIDParam(r *http.Request) (int64, error) {
-	params := httprouter.ParamsFromContext(r.Context())
+	return ReadNamedIDParam(r, "id")
+}
 
-	id, err := strconv.ParseInt(params.ByName("id"), 10, 64)
+// ReadNamedIDParam reads the id in the route parameter name, like the ids of nested resources
+func ReadNamedIDParam(r *http.Request, name string) (int64, error) {
+	id, err := strconv.ParseInt(ReadParam(r, name), 10, 64)
 	if err != nil || id < 1 {
-		return 0, errors.New("invalid id parameter")
+		return 0, fmt.Errorf("invalid %s parameter", name)
 	}
 
 	return id, nil
 }
 
+// ReadParam reads the route parameter name, it is empty if the route has none
+func ReadParam(r *http.Request, name string) string {
+	return httprouter.ParamsFromContext(r.Context()).ByName(name)
+}
+
 func ReadJSON(w http.ResponseWriter, r *http.Request, dst any) error {
 	maxBytes := 1_048_576
 	r.Body = http.MaxBytesReader(w, r.Body, int64(maxBytes))
@@ -118,6 +127,54 @@ func ReadInt(qs url.Values, key string, defaultValue int, v *validator.Validator
 	return i
 }
 
+func ReadCSV(qs url.Values, key string, defaultValue []string) []string {
+	csv := qs.Get(key)
+
+	if csv == "" {
+		return defaultValue
+	}
+
+	return strings.Split(csv, ",")
+}
+
+func ReadCursor(qs url.Values, key string, v *validator.Validator) *repository.Cursor {
+	s := qs.Get(key)
+
+	if s == "" {
+		return nil
+	}
+
+	c, err := repository.ParseCursor(s)
+	if err != nil {
+		v.AddError(key, "must be a valid cursor")
+		return nil
+	}
+	return c
+}
+
+// Project returns the JSON properties of record named in fields, for sparse fieldsets
+func Project(record any, fields []string) (map[string]json.RawMessage, error) {
+	js, err := json.Marshal(record)
+	if err != nil {
+		return nil, err
+	}
+
+	var properties map[string]json.RawMessage
+	err = json.Unmarshal(js, &properties)
+	if err != nil {
+		return nil, err
+	}
+
+	projected := make(map[string]json.RawMessage, len(fields))
+	for _, field := range fields {
+		if value, ok := properties[field]; ok {
+			projected[field] = value
+		}
+	}
+
+	return projected, nil
+}
+
 func MergeGenres(genre string, genres []string) []string {
 	for _, g := range genres {
 		if g == genre {
@@ -11,12 +11,19 @@ var (
 
 type Validator struct {
 	Errors map[string]string
+	Rules  map[string][]string // message of every check by key, passing or not, only collected by NewRecorder
 }
 
 func New() *Validator {
 	return &Validator{Errors: make(map[string]string)}
 }
 
+// NewRecorder returns a validator that also records the message of every check it performs,
+// so running a Validate function once reveals all rules it enforces
+func NewRecorder() *Validator {
+	return &Validator{Errors: make(map[string]string), Rules: make(map[string][]string)}
+}
+
 func (v *Validator) Valid() bool {
 	return len(v.Errors) == 0
 }
@@ -28,6 +35,10 @@ func (v *Validator) AddError(key, message string) {
 }
 
 func (v *Validator) Check(ok bool, key, message string) {
+	if v.Rules != nil && !slices.Contains(v.Rules[key], message) {
+		v.Rules[key] = append(v.Rules[key], message)
+	}
+
 	if !ok {
 		v.AddError(key, message)
 	}
@@ -0,0 +1,88 @@
+package workload
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"slices"
+	"time"
+)
+
+// Resources served by each version
+var Resources = map[string][]string{
+	"v1": {"movies"},
+	"v2": {"movies"},
+	"v3": {"movies"},
+	"v4": {"movies", "actors"},
+	"v5": {"movies", "people"},
+}
+
+var (
+	titleWords = []string{"Silent", "Red", "Last", "Night", "River", "Glass", "Iron", "Summer", "Echo", "Hidden", "Northern", "Paper", "Lost", "Golden", "City"}
+	names      = []string{"Ada", "Boris", "Chiara", "Daniel", "Elif", "Farid", "Greta", "Hiro", "Ines", "Jonas", "Kaja", "Luis", "Mona", "Nils", "Oona", "Pavel"}
+	surnames   = []string{"Lefler", "Novak", "Rossi", "Berg", "Okafor", "Tanaka", "Silva", "Kowalski", "Haddad", "Lund", "Moreau", "Ivanova"}
+	genres     = []string{"drama", "comedy", "thriller", "horror", "romance", "documentary", "animation", "western", "sci-fi", "crime"}
+	languages  = []string{"English", "German", "French", "Japanese", "Spanish", "Czech", "Korean"}
+	roles      = []string{"Lead", "Support", "Villain", "Narrator", "Cameo"}
+)
+
+// Fields returns the fields of a valid create body for the input struct of the resource in
+// version, without the cast of a movie, which references other records
+func Fields(r *rand.Rand, version string, resource string) map[string]any {
+	if resource == "actors" || resource == "people" {
+		return map[string]any{
+			"name":      Name(r),
+			"birthdate": fmt.Sprintf("%d-%02d-%02d", 1930+r.Intn(70), 1+r.Intn(12), 1+r.Intn(28)),
+		}
+	}
+
+	fields := map[string]any{
+		"title": titleWords[r.Intn(len(titleWords))] + " " + titleWords[r.Intn(len(titleWords))],
+		"year":  1900 + r.Intn(time.Now().Year()-1900),
+	}
+
+	if version == "v1" || version == "v2" {
+		fields["genre"] = genres[r.Intn(len(genres))]
+	} else {
+		shuffled := slices.Clone(genres)
+		r.Shuffle(len(shuffled), func(i, j int) { shuffled[i], shuffled[j] = shuffled[j], shuffled[i] })
+		fields["genres"] = shuffled[:1+r.Intn(3)]
+	}
+
+	if version != "v1" {
+		fields["runtime"] = 70 + r.Intn(120)
+		fields["language"] = languages[r.Intn(len(languages))]
+	}
+	if version == "v2" || version == "v3" || version == "v4" {
+		fields["director"] = Name(r)
+	}
+
+	return fields
+}
+
+func Name(r *rand.Rand) string {
+	return names[r.Intn(len(names))] + " " + surnames[r.Intn(len(surnames))]
+}
+
+func Role(r *rand.Rand) string {
+	return roles[r.Intn(len(roles))]
+}
+
+// CreatedID returns the id of the record in a response envelope, e.g. {"movie": {"id": 1, ...}}
+func CreatedID(body []byte) string {
+	var envelope map[string]json.RawMessage
+	if json.Unmarshal(body, &envelope) != nil {
+		return ""
+	}
+
+	for _, value := range envelope {
+		var record struct {
+			ID json.Number `json:"id"`
+		}
+		if json.Unmarshal(value, &record) == nil && record.ID != "" {
+			return record.ID.String()
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,112 @@
+package workload
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// Entry is a single request of a workload, path is relative to the strategy prefix,
+// e.g. /v1/movies
+type Entry struct {
+	Method   string          `json:"method"`
+	Path     string          `json:"path"`
+	Body     json.RawMessage `json:"body,omitempty"`
+	Status   int             `json:"status,omitempty"`   // expected status, not checked if 0
+	Strategy string          `json:"strategy,omitempty"` // only replayed against this strategy if set
+	Capture  string          `json:"capture,omitempty"`  // name the id of the created record is captured as
+}
+
+var (
+	// Reference matches {{name}} in the path of an entry, and QuotedReference "{{name}}" in
+	// its body, which is replaced by the bare id so the body stays valid JSON
+	Reference       = regexp.MustCompile(`\{\{(\w+)\}\}`)
+	QuotedReference = regexp.MustCompile(`"\{\{(\w+)\}\}"`)
+	captureName     = regexp.MustCompile(`^\w+$`)
+)
+
+// Load reads a JSONL workload, blank lines are skipped
+func Load(path string) ([]Entry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var entries []Entry
+	captured := make(map[string]bool)
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+
+		var e Entry
+		err = json.Unmarshal([]byte(text), &e)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+
+		e.Method = strings.ToUpper(e.Method)
+
+		switch {
+		case e.Method == "":
+			return nil, fmt.Errorf("line %d: missing method", line)
+		case e.Path == "":
+			return nil, fmt.Errorf("line %d: missing path", line)
+		case !strings.HasPrefix(e.Path, "/"):
+			return nil, fmt.Errorf("line %d: path must start with /", line)
+		case e.Status != 0 && http.StatusText(e.Status) == "":
+			return nil, fmt.Errorf("line %d: invalid status %d", line, e.Status)
+		case e.Capture != "" && !captureName.MatchString(e.Capture):
+			return nil, fmt.Errorf("line %d: invalid capture name %q", line, e.Capture)
+		}
+
+		// references must be captured by an earlier entry, or replaying them would wait forever
+		references := append(Reference.FindAllStringSubmatch(e.Path, -1), QuotedReference.FindAllStringSubmatch(string(e.Body), -1)...)
+		for _, match := range references {
+			if !captured[match[1]] {
+				return nil, fmt.Errorf("line %d: {{%s}} is not captured by an earlier entry", line, match[1])
+			}
+		}
+		if e.Capture != "" {
+			captured[e.Capture] = true
+		}
+
+		entries = append(entries, e)
+	}
+
+	err = scanner.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(entries) == 0 {
+		return nil, errors.New("workload is empty")
+	}
+
+	return entries, nil
+}
+
+// Endpoint groups requests by method and path with ids and references replaced, e.g. GET /v1/movies/:id
+func (e Entry) Endpoint() string {
+	path, _, _ := strings.Cut(e.Path, "?")
+
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		if segment != "" && (strings.Trim(segment, "0123456789") == "" || Reference.MatchString(segment)) {
+			segments[i] = ":id"
+		}
+	}
+
+	return e.Method + " " + strings.Join(segments, "/")
+}
@@ -0,0 +1,26 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Per version usage the API persists with -telemetry-persist, not part of any API version
+CREATE TABLE IF NOT EXISTS api_usage (
+    strategy text NOT NULL,             -- Strategy the requests were served by
+    version text NOT NULL,              -- API version, e.g. v4
+    resource text NOT NULL,             -- Resource of the route, e.g. movies
+    method text NOT NULL,               -- HTTP method
+    requests bigint NOT NULL DEFAULT 0, -- Requests served
+    PRIMARY KEY (strategy, version, resource, method)
+);
+
+CREATE TABLE IF NOT EXISTS api_usage_clients (
+    strategy text NOT NULL,
+    version text NOT NULL,
+    client_id text NOT NULL,                               -- X-Client-ID header or remote host
+    last_seen timestamp(0) with time zone NOT NULL,        -- Last request of the client through the version
+    PRIMARY KEY (strategy, version, client_id)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS api_usage_clients;
+DROP TABLE IF EXISTS api_usage;
+-- +goose StatementEnd
@@ -0,0 +1,11 @@
+-- +goose NO TRANSACTION
+-- +goose Up
+-- Full-text search indexes, the expressions must match the search conditions of the list queries
+CREATE INDEX CONCURRENTLY IF NOT EXISTS movies_title_search_idx ON movies USING GIN (to_tsvector('simple', title));
+CREATE INDEX CONCURRENTLY IF NOT EXISTS actors_name_search_idx ON actors USING GIN (to_tsvector('simple', name));
+CREATE INDEX CONCURRENTLY IF NOT EXISTS people_name_search_idx ON people USING GIN (to_tsvector('simple', name));
+
+-- +goose Down
+DROP INDEX CONCURRENTLY IF EXISTS people_name_search_idx;
+DROP INDEX CONCURRENTLY IF EXISTS actors_name_search_idx;
+DROP INDEX CONCURRENTLY IF EXISTS movies_title_search_idx;
@@ -0,0 +1,18 @@
+package migrations
+
+import (
+	"embed"
+)
+
+//go:embed views/*.sql expand_and_deprecate/*.sql branches/*.sql
+var FS embed.FS
+
+// Dirs maps each strategy, named as in the API route prefixes, to its migration directory
+var Dirs = map[string]string{
+	"views":            "views",
+	"expand_deprecate": "expand_and_deprecate",
+	"branches":         "branches",
+}
+
+// Strategies lists the strategies in the order they are migrated
+var Strategies = []string{"views", "expand_deprecate", "branches"}
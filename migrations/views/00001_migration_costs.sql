@@ -0,0 +1,27 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Cost of every migration applied or rolled back with measuring enabled, next to goose_db_version.
+-- It is the first migration, so every later one can be measured.
+CREATE TABLE IF NOT EXISTS migration_costs (
+    id integer PRIMARY KEY GENERATED BY DEFAULT AS IDENTITY,
+    version_id bigint NOT NULL,                  -- Migration measured
+    name text NOT NULL,                          -- File name of the migration
+    direction text NOT NULL,                     -- up or down
+    started_at timestamptz NOT NULL,
+    duration_ms double precision NOT NULL,
+    rows_affected bigint NOT NULL,
+    samples integer NOT NULL,                    -- Samples of pg_locks taken while it ran
+    exclusive_lock_ms double precision NOT NULL, -- Time an EXCLUSIVE or ACCESS EXCLUSIVE lock was held on a table
+    max_blocked integer NOT NULL,                -- Most sessions waiting on the migration at once
+    size_before bigint NOT NULL,                 -- Size of all tables in bytes
+    size_after bigint NOT NULL,
+    locks jsonb NOT NULL,
+    tables jsonb NOT NULL,
+    error text                                   -- Error of a failed migration
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS migration_costs;
+-- +goose StatementEnd
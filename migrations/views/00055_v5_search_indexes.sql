@@ -0,0 +1,11 @@
+-- +goose NO TRANSACTION
+-- +goose Up
+-- Full-text search indexes on the base tables, queries through the versioned views use them
+-- as the views select title and name unchanged. The expressions must match the search
+-- conditions of the list queries.
+CREATE INDEX CONCURRENTLY IF NOT EXISTS movies_title_search_idx ON movies USING GIN (to_tsvector('simple', title));
+CREATE INDEX CONCURRENTLY IF NOT EXISTS people_name_search_idx ON people USING GIN (to_tsvector('simple', name));
+
+-- +goose Down
+DROP INDEX CONCURRENTLY IF EXISTS people_name_search_idx;
+DROP INDEX CONCURRENTLY IF EXISTS movies_title_search_idx;